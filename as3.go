@@ -11,3 +11,59 @@ func (f AbcFile) GetClassByName(name string) (Class, bool) {
 	}
 	return Class{}, false
 }
+
+// GetClassByQName finds a class by its qualified name, the kind and the URI
+// of its namespace included
+func (f AbcFile) GetClassByQName(q QName) (Class, bool) {
+	for _, c := range f.Classes {
+		if c.QName() == q {
+			return c, true
+		}
+	}
+	return Class{}, false
+}
+
+// traits returns all the traits of the object
+func (o TraitsObject) traits() []Trait {
+	var traits []Trait
+	traits = append(traits, o.Slots...)
+	traits = append(traits, o.Classes...)
+	traits = append(traits, o.Functions...)
+	traits = append(traits, o.Methods...)
+	return traits
+}
+
+// QualifiedName returns the name of the trait prefixed by its namespace and
+// a dot, or its name alone when its namespace is empty
+func (t Trait) QualifiedName() string {
+	if t.Namespace.URI == "" {
+		return t.Name
+	}
+	return t.Namespace.URI + "." + t.Name
+}
+
+// GetDefinitionByName finds a top-level definition, a trait of a script, by
+// its fully qualified name. The namespace may be separated from the name by a
+// dot or by "::", as in flash.display.Sprite or flash.display::Sprite.
+func (f AbcFile) GetDefinitionByName(name string) (Trait, Script, bool) {
+	for _, s := range f.Scripts {
+		for _, t := range s.Traits.traits() {
+			if t.QualifiedName() == name || t.QName().String() == name {
+				return t, s, true
+			}
+		}
+	}
+	return Trait{}, Script{}, false
+}
+
+// GetDefinitionByQName finds a top-level definition by its qualified name
+func (f AbcFile) GetDefinitionByQName(q QName) (Trait, Script, bool) {
+	for _, s := range f.Scripts {
+		for _, t := range s.Traits.traits() {
+			if t.QName() == q {
+				return t, s, true
+			}
+		}
+	}
+	return Trait{}, Script{}, false
+}
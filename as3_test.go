@@ -53,3 +53,42 @@ func TestAbcFile_GetClassByName(t *testing.T) {
 		})
 	}
 }
+
+func TestAbcFile_GetDefinitionByName(t *testing.T) {
+	var abc bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&abc.ConstantPool)
+	pkg := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString("flash.display"))
+	global := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	abc.Methods = []bytecode.MethodInfo{{}}
+	abc.Scripts = []bytecode.ScriptInfo{{Traits: []bytecode.TraitsInfo{
+		{Name: b.AddQName(pkg, "Sprite"), Kind: bytecode.TraitsInfoConst},
+		{Name: b.AddQName(global, "trace"), Kind: bytecode.TraitsInfoMethod},
+	}}}
+	l, err := Link(&abc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		want  string
+		found bool
+	}{
+		{"flash.display.Sprite", "Sprite", true},
+		{"flash.display::Sprite", "Sprite", true},
+		{"trace", "trace", true},
+		{"Sprite", "", false},
+		{"flash.display.trace", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, s, found := l.GetDefinitionByName(tt.name)
+			if found != tt.found || got.Name != tt.want {
+				t.Errorf("AbcFile.GetDefinitionByName() = %v, %v, want %v, %v", got.Name, found, tt.want, tt.found)
+			}
+			if found && s.Init != &l.Methods[0] {
+				t.Errorf("AbcFile.GetDefinitionByName() script = %v", s)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+// Package asm provides a textual assembly language for ActionScript 3
+// bytecode files, in the spirit of RABCDasm.
+//
+// Print writes a whole AbcFile with every constant pool reference inlined
+// as a literal or a name, branch targets as labels and exception blocks
+// referring to those labels. Parse reads that text back into an AbcFile
+// ready to be serialized with bytecode.Extract. The constant pool is rebuilt
+// from the references found in the text: unused entries are dropped and
+// equal entries are merged.
+//
+// A short sample of the format:
+//
+//	method 0
+//	  name "foo"
+//	  returns QName(PackageNamespace(""), "void")
+//	  param QName(PackageNamespace(""), "int")
+//	end
+//	body 0
+//	  maxstack 1
+//	  localcount 2
+//	  initscopedepth 0
+//	  maxscopedepth 1
+//	  code
+//	    getlocal_0
+//	    pushscope
+//	    iffalse L1
+//	    returnvoid
+//	  L1:
+//	    returnvoid
+//	  end
+//	end
+//
+// Private namespaces are identified by their name and, when several of them
+// share a name, by an ordinal: PrivateNamespace("x", 1) is the second
+// distinct private namespace named "x" found in the file.
+package asm
@@ -0,0 +1,121 @@
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type tokenKind uint8
+
+const (
+	tokenIdent = tokenKind(iota)
+	tokenNumber
+	tokenString
+	tokenPunct
+)
+
+type token struct {
+	Kind tokenKind
+	Text string
+}
+
+// line is a non-empty line of the source split into tokens
+type line struct {
+	Number int
+	Tokens []token
+}
+
+// SyntaxError represents an error found while parsing the textual assembly
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %v: %v", e.Line, e.Msg)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isNumberChar reports whether c may appear in a number after its first
+// character: hexadecimal digits, exponents and the Inf of doubles
+func isNumberChar(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '.' || c == '+' || c == '-'
+}
+
+func tokenizeLine(text string, number int) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == ';':
+			// comment until the end of the line
+			return tokens, nil
+		case c == '"':
+			j := i + 1
+			for j < len(text) && text[j] != '"' {
+				if text[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(text) {
+				return nil, &SyntaxError{number, "unterminated string"}
+			}
+			s, err := strconv.Unquote(text[i : j+1])
+			if err != nil {
+				return nil, &SyntaxError{number, fmt.Sprintf("invalid string %v", text[i:j+1])}
+			}
+			tokens = append(tokens, token{tokenString, s})
+			i = j + 1
+		case isDigit(c) || (c == '-' || c == '+') && i+1 < len(text) && (isDigit(text[i+1]) || text[i+1] == 'I'):
+			j := i + 1
+			for j < len(text) && isNumberChar(text[j]) {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumber, text[i:j]})
+			i = j
+		case isLetter(c):
+			j := i + 1
+			for j < len(text) && (isLetter(text[j]) || isDigit(text[j])) {
+				j++
+			}
+			tokens = append(tokens, token{tokenIdent, text[i:j]})
+			i = j
+		case c == '(' || c == ')' || c == '[' || c == ']' || c == '<' || c == '>' || c == ',' || c == ':':
+			tokens = append(tokens, token{tokenPunct, text[i : i+1]})
+			i++
+		default:
+			return nil, &SyntaxError{number, fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return tokens, nil
+}
+
+// tokenize splits the source into lines of tokens, dropping empty lines and
+// comments
+func tokenize(r io.Reader) ([]line, error) {
+	var lines []line
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<30)
+	for number := 1; scanner.Scan(); number++ {
+		tokens, err := tokenizeLine(scanner.Text(), number)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) > 0 {
+			lines = append(lines, line{number, tokens})
+		}
+	}
+	return lines, scanner.Err()
+}
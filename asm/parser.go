@@ -0,0 +1,730 @@
+package asm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+const opLookupswitch = 0x1b
+
+// instructionsByName maps an instruction name to its model
+var instructionsByName = func() map[string]bytecode.InstrModel {
+	models := make(map[string]bytecode.InstrModel, len(bytecode.Instructions))
+	for _, model := range bytecode.Instructions {
+		models[model.Name] = model
+	}
+	return models
+}()
+
+func reverse(names map[uint8]string) map[string]uint8 {
+	kinds := make(map[string]uint8, len(names))
+	for kind, name := range names {
+		kinds[name] = kind
+	}
+	return kinds
+}
+
+var (
+	namespaceKindsByName = reverse(bytecode.NamespaceKindNames)
+	multinameKindsByName = reverse(bytecode.MultinameKindNames)
+	traitKindsByName     = reverse(bytecode.TraitKindNames)
+)
+
+type parser struct {
+	lines  []line
+	pos    int
+	line   line
+	tokens []token
+	abc    bytecode.AbcFile
+	pool   *pool
+}
+
+// Parse reads the textual assembly of an AbcFile, as written by Print.
+// Method bodies are assembled, and the constant pool is rebuilt from the
+// constants referenced by the text.
+func Parse(r io.Reader) (abc bytecode.AbcFile, err error) {
+	lines, err := tokenize(r)
+	if err != nil {
+		return bytecode.AbcFile{}, err
+	}
+	p := parser{lines: lines, pool: newPool()}
+	defer func() {
+		if v := recover(); v != nil {
+			e, ok := v.(*SyntaxError)
+			if !ok {
+				panic(v)
+			}
+			abc, err = bytecode.AbcFile{}, e
+		}
+	}()
+	p.Parse()
+	p.abc.ConstantPool = p.pool.cpool
+	return p.abc, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) {
+	panic(&SyntaxError{p.line.Number, fmt.Sprintf(format, args...)})
+}
+
+// nextLine moves to the next line and returns its first word
+func (p *parser) nextLine() string {
+	if p.pos >= len(p.lines) {
+		p.errorf("unexpected end of file")
+	}
+	p.line = p.lines[p.pos]
+	p.tokens = p.line.Tokens
+	p.pos++
+	return p.word()
+}
+
+func (p *parser) more() bool {
+	return len(p.tokens) > 0
+}
+
+func (p *parser) next() token {
+	if len(p.tokens) == 0 {
+		p.errorf("unexpected end of line")
+	}
+	t := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return t
+}
+
+// peek reports whether the next token of the line is text
+func (p *parser) peek(text string) bool {
+	return len(p.tokens) > 0 && p.tokens[0].Kind != tokenString && p.tokens[0].Text == text
+}
+
+func (p *parser) expect(text string) {
+	if t := p.next(); t.Kind == tokenString || t.Text != text {
+		p.errorf("expected %q, got %q", text, t.Text)
+	}
+}
+
+func (p *parser) endLine() {
+	if len(p.tokens) > 0 {
+		p.errorf("unexpected %q", p.tokens[0].Text)
+	}
+}
+
+func (p *parser) word() string {
+	t := p.next()
+	if t.Kind != tokenIdent {
+		p.errorf("expected a word, got %q", t.Text)
+	}
+	return t.Text
+}
+
+func (p *parser) number() string {
+	t := p.next()
+	if t.Kind != tokenNumber {
+		p.errorf("expected a number, got %q", t.Text)
+	}
+	return t.Text
+}
+
+func (p *parser) uint(bits int) uint32 {
+	text := p.number()
+	v, err := strconv.ParseUint(text, 0, bits)
+	if err != nil {
+		p.errorf("invalid number %v", text)
+	}
+	return uint32(v)
+}
+
+func (p *parser) int(bits int) int32 {
+	text := p.number()
+	v, err := strconv.ParseInt(text, 0, bits)
+	if err != nil {
+		p.errorf("invalid number %v", text)
+	}
+	return int32(v)
+}
+
+// short reads a pushshort operand. Negative values are stored on 16 bits,
+// like the compilers do, while larger values keep the raw U30 they were
+// printed from.
+func (p *parser) short() uint32 {
+	text := p.number()
+	v, err := strconv.ParseInt(text, 0, 64)
+	if err != nil || v < math.MinInt16 || v > math.MaxUint32 {
+		p.errorf("invalid number %v", text)
+	}
+	if v < 0 {
+		return uint32(uint16(v))
+	}
+	return uint32(v)
+}
+
+// index reads a decimal reference to a table entry
+func (p *parser) index() uint32 {
+	return p.uint(32)
+}
+
+// null reads a null reference, if any
+func (p *parser) null() bool {
+	if p.peek("null") {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) str() uint32 {
+	if p.null() {
+		return 0
+	}
+	t := p.next()
+	if t.Kind != tokenString {
+		p.errorf("expected a string, got %q", t.Text)
+	}
+	return p.pool.builder.AddString(t.Text)
+}
+
+func (p *parser) integer() uint32 {
+	if p.null() {
+		return 0
+	}
+	return p.pool.builder.AddInt(p.int(32))
+}
+
+func (p *parser) uinteger() uint32 {
+	if p.null() {
+		return 0
+	}
+	return p.pool.builder.AddUInt(p.uint(32))
+}
+
+func (p *parser) double() uint32 {
+	if p.null() {
+		return 0
+	}
+	t := p.next()
+	v, err := strconv.ParseFloat(t.Text, 64)
+	if t.Kind == tokenString || err != nil {
+		p.errorf("invalid double %v", t.Text)
+	}
+	return p.pool.builder.AddDouble(v)
+}
+
+func (p *parser) namespaceOfKind(kind uint8) uint32 {
+	p.expect("(")
+	name := p.str()
+	ordinal := 0
+	if kind == bytecode.NamespaceKindPrivateNs && p.peek(",") {
+		p.next()
+		ordinal = int(p.int(32))
+	}
+	p.expect(")")
+	return p.pool.namespace(kind, name, ordinal)
+}
+
+func (p *parser) namespace() uint32 {
+	if p.null() {
+		return 0
+	}
+	word := p.word()
+	kind, ok := namespaceKindsByName[word]
+	if !ok {
+		p.errorf("unknown namespace kind %v", word)
+	}
+	return p.namespaceOfKind(kind)
+}
+
+func (p *parser) nsSet() uint32 {
+	if p.null() {
+		return 0
+	}
+	p.expect("[")
+	namespaces := []uint32{}
+	for !p.peek("]") {
+		if len(namespaces) > 0 {
+			p.expect(",")
+		}
+		namespaces = append(namespaces, p.namespace())
+	}
+	p.expect("]")
+	return p.pool.builder.AddNsSet(namespaces...)
+}
+
+func (p *parser) multiname() uint32 {
+	if p.null() {
+		return 0
+	}
+	word := p.word()
+	kind, ok := multinameKindsByName[word]
+	if !ok {
+		p.errorf("unknown multiname kind %v", word)
+	}
+	info := bytecode.MultinameInfo{Kind: kind}
+	p.expect("(")
+	switch kind {
+	case bytecode.MultinameKindQName, bytecode.MultinameKindQNameA:
+		info.Namespace = p.namespace()
+		p.expect(",")
+		info.Name = p.str()
+	case bytecode.MultinameKindRTQName, bytecode.MultinameKindRTQNameA:
+		info.Name = p.str()
+	case bytecode.MultinameKindRTQNameL, bytecode.MultinameKindRTQNameLA:
+	case bytecode.MultinameKindMultiname, bytecode.MultinameKindMultinameA:
+		info.Name = p.str()
+		p.expect(",")
+		info.NsSet = p.nsSet()
+	case bytecode.MultinameKindMultinameL, bytecode.MultinameKindMultinameLA:
+		info.NsSet = p.nsSet()
+	case bytecode.MultinameKindTypename:
+		info.Name = p.multiname()
+		p.expect("<")
+		info.Params = []uint32{}
+		for !p.peek(">") {
+			if len(info.Params) > 0 {
+				p.expect(",")
+			}
+			info.Params = append(info.Params, p.multiname())
+		}
+		p.expect(">")
+	}
+	p.expect(")")
+	return p.pool.builder.AddMultiname(info)
+}
+
+// value reads the constant of an optional parameter or of a slot
+func (p *parser) value() (uint8, uint32) {
+	word := p.word()
+	switch word {
+	case "True", "False", "Null", "Undefined":
+		// the index is ignored by the runtime but must not be zero
+		kinds := map[string]uint8{
+			"True":      bytecode.SlotKindTrue,
+			"False":     bytecode.SlotKindFalse,
+			"Null":      bytecode.SlotKindNull,
+			"Undefined": bytecode.SlotKindUndefined,
+		}
+		return kinds[word], 1
+	}
+	if kind, ok := namespaceKindsByName[word]; ok {
+		return kind, p.namespaceOfKind(kind)
+	}
+	var kind uint8
+	var index uint32
+	p.expect("(")
+	switch word {
+	case "Integer":
+		kind, index = bytecode.SlotKindInt, p.integer()
+	case "UInteger":
+		kind, index = bytecode.SlotKindUInt, p.uinteger()
+	case "Double":
+		kind, index = bytecode.SlotKindDouble, p.double()
+	case "Utf8":
+		kind, index = bytecode.SlotKindUtf8, p.str()
+	default:
+		p.errorf("unknown value kind %v", word)
+	}
+	p.expect(")")
+	return kind, index
+}
+
+func (p *parser) flags(names []bytecode.Flag) uint8 {
+	var flags uint8
+	for p.more() {
+		if p.tokens[0].Kind == tokenNumber {
+			flags |= uint8(p.uint(8))
+			continue
+		}
+		word := p.word()
+		found := false
+		for _, f := range names {
+			if f.Name == word {
+				flags |= f.Bit
+				found = true
+			}
+		}
+		if !found {
+			p.errorf("unknown flag %v", word)
+		}
+	}
+	return flags
+}
+
+// header checks the index of a new table entry
+func (p *parser) header(kind string, length int) {
+	if index := p.index(); int(index) != length {
+		p.errorf("expected %v %v, got %v", kind, length, index)
+	}
+}
+
+func (p *parser) Parse() {
+	for p.pos < len(p.lines) {
+		switch word := p.nextLine(); word {
+		case "minorversion":
+			p.abc.MinorVersion = uint16(p.uint(16))
+		case "majorversion":
+			p.abc.MajorVersion = uint16(p.uint(16))
+		case "metadata":
+			p.parseMetadata()
+		case "method":
+			p.parseMethod()
+		case "class":
+			p.parseClass()
+		case "script":
+			p.parseScript()
+		case "body":
+			p.parseBody()
+		default:
+			p.errorf("unexpected %v", word)
+		}
+		p.endLine()
+	}
+}
+
+func (p *parser) parseMetadata() {
+	p.header("metadata", len(p.abc.Metadatas))
+	m := bytecode.MetadataInfo{Names: p.str()}
+	p.endLine()
+	for word := p.nextLine(); word != "end"; word = p.nextLine() {
+		if word != "item" {
+			p.errorf("unexpected %v", word)
+		}
+		m.Items = append(m.Items, bytecode.ItemInfo{Key: p.str(), Value: p.str()})
+		p.endLine()
+	}
+	p.abc.Metadatas = append(p.abc.Metadatas, m)
+}
+
+func (p *parser) parseMethod() {
+	p.header("method", len(p.abc.Methods))
+	var m bytecode.MethodInfo
+	p.endLine()
+	for word := p.nextLine(); word != "end"; word = p.nextLine() {
+		switch word {
+		case "name":
+			m.Name = p.str()
+		case "flags":
+			m.Flags = p.flags(bytecode.MethodFlagNames)
+		case "returns":
+			m.ReturnType = p.multiname()
+		case "param":
+			m.ParamTypes = append(m.ParamTypes, p.multiname())
+		case "optional":
+			kind, index := p.value()
+			m.OptionInfo.Options = append(m.OptionInfo.Options, bytecode.OptionDetail{Value: index, Kind: kind})
+		case "paramname":
+			m.ParamInfo.ParamNames = append(m.ParamInfo.ParamNames, p.str())
+		default:
+			p.errorf("unexpected %v", word)
+		}
+		p.endLine()
+	}
+	m.ParamCount = uint32(len(m.ParamTypes))
+	p.abc.Methods = append(p.abc.Methods, m)
+}
+
+func (p *parser) parseClass() {
+	p.header("class", len(p.abc.Instances))
+	p.endLine()
+	var instance bytecode.InstanceInfo
+	var class bytecode.ClassInfo
+	if word := p.nextLine(); word != "instance" {
+		p.errorf("expected instance, got %v", word)
+	}
+	instance.Name = p.multiname()
+	p.endLine()
+	for word := p.nextLine(); word != "end"; word = p.nextLine() {
+		switch word {
+		case "extends":
+			instance.SuperName = p.multiname()
+		case "flags":
+			instance.Flags = p.flags(bytecode.InstanceFlagNames)
+		case "protectedns":
+			instance.ProtectedNs = p.namespace()
+		case "implements":
+			instance.Interfaces = append(instance.Interfaces, p.multiname())
+		case "iinit":
+			instance.IInit = p.index()
+		case "trait":
+			instance.Traits = append(instance.Traits, p.parseTrait())
+		default:
+			p.errorf("unexpected %v", word)
+		}
+		p.endLine()
+	}
+	p.endLine()
+	if word := p.nextLine(); word != "cinit" {
+		p.errorf("expected cinit, got %v", word)
+	}
+	class.CInit = p.index()
+	p.endLine()
+	class.Traits = p.parseTraits()
+	p.endLine()
+	if word := p.nextLine(); word != "end" {
+		p.errorf("expected end, got %v", word)
+	}
+	p.abc.Instances = append(p.abc.Instances, instance)
+	p.abc.Classes = append(p.abc.Classes, class)
+}
+
+func (p *parser) parseScript() {
+	p.header("script", len(p.abc.Scripts))
+	p.endLine()
+	var s bytecode.ScriptInfo
+	if word := p.nextLine(); word != "init" {
+		p.errorf("expected init, got %v", word)
+	}
+	s.Init = p.index()
+	p.endLine()
+	s.Traits = p.parseTraits()
+	p.abc.Scripts = append(p.abc.Scripts, s)
+}
+
+// parseTraits reads trait lines up to an end line
+func (p *parser) parseTraits() []bytecode.TraitsInfo {
+	var traits []bytecode.TraitsInfo
+	for word := p.nextLine(); word != "end"; word = p.nextLine() {
+		if word != "trait" {
+			p.errorf("unexpected %v", word)
+		}
+		traits = append(traits, p.parseTrait())
+		p.endLine()
+	}
+	return traits
+}
+
+func (p *parser) parseTrait() bytecode.TraitsInfo {
+	word := p.word()
+	kind, ok := traitKindsByName[word]
+	if !ok {
+		p.errorf("unknown trait kind %v", word)
+	}
+	t := bytecode.TraitsInfo{Name: p.multiname(), Kind: kind}
+	for p.more() {
+		switch word := p.word(); word {
+		case "final":
+			t.Kind |= bytecode.TraitsInfoAttributeFinal
+		case "override":
+			t.Kind |= bytecode.TraitsInfoAttributeOverride
+		case "slotid":
+			t.SlotID = p.index()
+		case "type":
+			t.Typename = p.multiname()
+		case "value":
+			t.VKind, t.VIndex = p.value()
+		case "class":
+			t.ClassI = p.index()
+		case "method":
+			if kind == bytecode.TraitsInfoFunction {
+				t.Function = p.index()
+			} else {
+				t.Method = p.index()
+			}
+		case "dispid":
+			t.DispID = p.index()
+		case "metadata":
+			t.Kind |= bytecode.TraitsInfoAttributeMetadata
+			t.Metadatas = []uint32{}
+			for p.more() {
+				t.Metadatas = append(t.Metadatas, p.index())
+			}
+		default:
+			p.errorf("unexpected %v", word)
+		}
+	}
+	return t
+}
+
+// bodyParser holds the labels of a method body until they can be resolved
+type bodyParser struct {
+	labels  map[string]bytecode.Label
+	targets [][]string
+	froms   []string
+	tos     []string
+	handler []string
+}
+
+// position reads an exception offset: a label name or a raw offset
+func (p *parser) position() (string, uint32) {
+	if p.more() && p.tokens[0].Kind == tokenIdent {
+		return p.word(), 0
+	}
+	return "", p.index()
+}
+
+func (p *parser) parseBody() {
+	p.header("body", len(p.abc.MethodBodies))
+	p.endLine()
+	var body bytecode.MethodBodyInfo
+	b := bodyParser{labels: map[string]bytecode.Label{}}
+	start := p.line
+	for word := p.nextLine(); word != "end"; word = p.nextLine() {
+		switch word {
+		case "method":
+			body.Method = p.index()
+		case "maxstack":
+			body.MaxStack = p.index()
+		case "localcount":
+			body.LocalCount = p.index()
+		case "initscopedepth":
+			body.InitScopeLength = p.index()
+		case "maxscopedepth":
+			body.MaxScopeLength = p.index()
+		case "code":
+			p.endLine()
+			p.parseCode(&body, &b)
+		case "rawcode":
+			var code []byte
+			if p.more() {
+				text := p.next().Text
+				var err error
+				if code, err = hex.DecodeString(text); err != nil {
+					p.errorf("invalid code %v", text)
+				}
+			}
+			body.Code = code
+		case "try":
+			var e bytecode.ExceptionInfo
+			var from, to, target string
+			p.expect("from")
+			from, e.From = p.position()
+			p.expect("to")
+			to, e.To = p.position()
+			p.expect("target")
+			target, e.Target = p.position()
+			p.expect("type")
+			e.ExcType = p.multiname()
+			p.expect("name")
+			e.VarName = p.multiname()
+			body.Exceptions = append(body.Exceptions, e)
+			b.froms = append(b.froms, from)
+			b.tos = append(b.tos, to)
+			b.handler = append(b.handler, target)
+		case "trait":
+			body.Traits = append(body.Traits, p.parseTrait())
+		default:
+			p.errorf("unexpected %v", word)
+		}
+		p.endLine()
+	}
+	end := p.line
+	p.line = start
+	p.resolveLabels(&body, &b)
+	if body.Instructions != nil {
+		if err := body.Assemble(); err != nil {
+			p.errorf("body %v: %v", len(p.abc.MethodBodies), err)
+		}
+	}
+	p.line = end
+	p.abc.MethodBodies = append(p.abc.MethodBodies, body)
+}
+
+func (p *parser) parseCode(body *bytecode.MethodBodyInfo, b *bodyParser) {
+	var pending []string
+	define := func() bytecode.Label {
+		if len(pending) == 0 {
+			return 0
+		}
+		l := bytecode.Label(len(b.labels) + 1)
+		for _, name := range pending {
+			if _, ok := b.labels[name]; ok {
+				p.errorf("label %v defined twice", name)
+			}
+			b.labels[name] = l
+		}
+		pending = nil
+		return l
+	}
+	body.Instructions = []bytecode.Instr{}
+	for word := p.nextLine(); word != "end"; word = p.nextLine() {
+		if p.peek(":") {
+			p.next()
+			p.endLine()
+			pending = append(pending, word)
+			continue
+		}
+		model, ok := instructionsByName[word]
+		if !ok {
+			p.errorf("unknown instruction %v", word)
+		}
+		instr := bytecode.Instr{Model: model, Label: define()}
+		types := instr.OperandTypes()
+		var targets []string
+		for i := 0; i < len(types) || model.Code == opLookupswitch && p.more(); i++ {
+			t := bytecode.OperandTypeBranch
+			if i < len(types) {
+				t = types[i]
+			}
+			if t == bytecode.OperandTypeBranch && p.more() && p.tokens[0].Kind == tokenIdent {
+				targets = append(targets, p.word())
+				instr.Operands = append(instr.Operands, 0)
+				continue
+			}
+			instr.Operands = append(instr.Operands, p.operand(t))
+		}
+		if targets != nil && len(targets) != len(instr.Operands) && model.Code == opLookupswitch {
+			p.errorf("lookupswitch mixes labels and offsets")
+		}
+		p.endLine()
+		body.Instructions = append(body.Instructions, instr)
+		b.targets = append(b.targets, targets)
+	}
+	body.EndLabel = define()
+}
+
+func (p *parser) operand(t bytecode.OperandType) uint32 {
+	switch t {
+	case bytecode.OperandTypeByte:
+		return uint32(uint8(p.int(8)))
+	case bytecode.OperandTypeShort:
+		return p.short()
+	case bytecode.OperandTypeBranch:
+		return uint32(p.int(24))
+	case bytecode.OperandTypeInt:
+		return p.integer()
+	case bytecode.OperandTypeUInt:
+		return p.uinteger()
+	case bytecode.OperandTypeDouble:
+		return p.double()
+	case bytecode.OperandTypeString:
+		return p.str()
+	case bytecode.OperandTypeNamespace:
+		return p.namespace()
+	case bytecode.OperandTypeMultiname:
+		return p.multiname()
+	}
+	return p.index()
+}
+
+func (p *parser) label(b *bodyParser, name string) bytecode.Label {
+	l, ok := b.labels[name]
+	if !ok {
+		p.errorf("unknown label %v", name)
+	}
+	return l
+}
+
+func (p *parser) resolveLabels(body *bytecode.MethodBodyInfo, b *bodyParser) {
+	for i, names := range b.targets {
+		if names == nil {
+			continue
+		}
+		body.Instructions[i].Targets = make([]bytecode.Label, len(names))
+		for j, name := range names {
+			body.Instructions[i].Targets[j] = p.label(b, name)
+		}
+	}
+	for i := range body.Exceptions {
+		e := &body.Exceptions[i]
+		if b.froms[i] != "" {
+			e.FromLabel = p.label(b, b.froms[i])
+		}
+		if b.tos[i] != "" {
+			e.ToLabel = p.label(b, b.tos[i])
+		}
+		if b.handler[i] != "" {
+			e.TargetLabel = p.label(b, b.handler[i])
+		}
+	}
+}
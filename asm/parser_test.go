@@ -0,0 +1,201 @@
+package asm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+func openFixture(t *testing.T, name string) *os.File {
+	file, err := os.Open(fmt.Sprintf("../bytecode/fixtures/%v.abc", name))
+	if err != nil {
+		t.Fatalf("openFixture: %v", err)
+	}
+	return file
+}
+
+func parseFixture(t *testing.T, name string) bytecode.AbcFile {
+	file := openFixture(t, name)
+	defer file.Close()
+	a, err := bytecode.Parse(bytecode.NewReader(file))
+	if err != nil {
+		t.Fatalf("%v: expected nil, got %v", name, err)
+	}
+	return a
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	for _, name := range []string{"obf1", "obf2"} {
+		a := parseFixture(t, name)
+		text := &bytes.Buffer{}
+		if err := Print(text, a); err != nil {
+			t.Fatalf("%v: Print: %v", name, err)
+		}
+
+		parsed, err := Parse(bytes.NewReader(text.Bytes()))
+		if err != nil {
+			t.Fatalf("%v: Parse: %v", name, err)
+		}
+		buf := &bytes.Buffer{}
+		if err = bytecode.Extract(buf, parsed); err != nil {
+			t.Fatalf("%v: Extract: %v", name, err)
+		}
+		extracted, err := bytecode.Parse(bytecode.NewReader(buf))
+		if err != nil {
+			t.Fatalf("%v: bytecode.Parse: %v", name, err)
+		}
+		if len(extracted.MethodBodies) != len(a.MethodBodies) {
+			t.Errorf("%v: expected %v bodies, got %v", name, len(a.MethodBodies), len(extracted.MethodBodies))
+		}
+
+		again := &bytes.Buffer{}
+		if err = Print(again, extracted); err != nil {
+			t.Fatalf("%v: Print: %v", name, err)
+		}
+		if again.String() != text.String() {
+			want := strings.Split(text.String(), "\n")
+			got := strings.Split(again.String(), "\n")
+			for i := range want {
+				if i >= len(got) || got[i] != want[i] {
+					t.Errorf("%v: line %v: expected %q, got %q", name, i+1, want[i], got[i])
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	src := `
+minorversion 16
+majorversion 46
+
+method 0
+  flags HAS_OPTIONAL
+  returns QName(PackageNamespace(""), "void")
+  param QName(PackageNamespace(""), "int")
+  optional Integer(-3)
+end
+
+body 0
+  method 0
+  maxstack 1
+  localcount 2
+  initscopedepth 0
+  maxscopedepth 1
+  code
+    getlocal_0
+    pushscope
+  loop:
+    getlocal_1
+    iftrue loop ; jumps backward
+    pushstring "a\tb"
+  done:
+    returnvoid
+  end
+  try from loop to done target done type null name null
+end
+`
+	a, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if len(a.Methods) != 1 || a.Methods[0].ParamCount != 1 {
+		t.Fatalf("expected one method with one parameter, got %v", a.Methods)
+	}
+	option := a.Methods[0].OptionInfo.Options[0]
+	if option.Kind != bytecode.SlotKindInt || a.ConstantPool.Integers[option.Value] != -3 {
+		t.Errorf("expected Integer(-3), got %v", option)
+	}
+	body := a.MethodBodies[0]
+	wantCode := []byte{0xd0, 0x30, 0xd1, 0x11, 0xfb, 0xff, 0xff, 0x2c, 0x04, 0x47}
+	if !bytes.Equal(body.Code, wantCode) {
+		t.Errorf("expected code %x, got %x", wantCode, body.Code)
+	}
+	if e := body.Exceptions[0]; e.From != 2 || e.To != 9 || e.Target != 9 {
+		t.Errorf("expected exception [2, 9) -> 9, got %v", e)
+	}
+	if s := a.ConstantPool.Strings[4]; s != "a\tb" {
+		t.Errorf("expected %q, got %q", "a\tb", s)
+	}
+}
+
+func TestParse_PrivateNamespaces(t *testing.T) {
+	src := `
+method 0
+  returns QName(PrivateNamespace("x"), "a")
+  param QName(PrivateNamespace("x", 1), "a")
+  param QName(PrivateNamespace("x"), "a")
+end
+`
+	a, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	m := a.Methods[0]
+	if m.ReturnType != m.ParamTypes[1] || m.ReturnType == m.ParamTypes[0] {
+		t.Errorf("expected distinct private namespaces, got %v", a.ConstantPool.Multinames)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		line int
+	}{
+		{"unknown directive", "foo", 1},
+		{"unterminated string", "\nmetadata 0 \"foo", 2},
+		{"wrong index", "method 1\nend", 1},
+		{"missing end", "method 0\n  returns null", 2},
+		{"unknown instruction", "body 0\n  code\n    foo\n  end\nend", 3},
+		{"unknown label", "body 0\n  code\n    jump L1\n  end\nend", 1},
+		{"trailing token", "script 0\n  init 0 1\nend", 2},
+		{"unknown multiname kind", "method 0\n  returns Foo()\nend", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.src))
+			e, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("expected a SyntaxError, got %v", err)
+			}
+			if e.Line != tt.line {
+				t.Errorf("expected line %v, got %v", tt.line, e.Line)
+			}
+		})
+	}
+}
+
+func TestParse_PushShort(t *testing.T) {
+	// pushshort 0x3fffffff; pop; pushshort -1; pop; pushshort 5; pop; returnvoid
+	code := []byte{0x25, 0xff, 0xff, 0xff, 0xff, 0x03, 0x29, 0x25, 0xff, 0xff, 0x03, 0x29, 0x25, 0x05, 0x29, 0x47}
+	a := bytecode.AbcFile{
+		Methods:      []bytecode.MethodInfo{{}},
+		Scripts:      []bytecode.ScriptInfo{{Init: 0}},
+		MethodBodies: []bytecode.MethodBodyInfo{{Method: 0, MaxStack: 1, LocalCount: 1, Code: code}},
+	}
+	text := &bytes.Buffer{}
+	if err := Print(text, a); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	parsed, err := Parse(bytes.NewReader(text.Bytes()))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err = bytecode.Extract(buf, parsed); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	extracted, err := bytecode.Parse(bytecode.NewReader(buf))
+	if err != nil {
+		t.Fatalf("bytecode.Parse: %v", err)
+	}
+	if got := extracted.MethodBodies[0].Code; !bytes.Equal(got, code) {
+		t.Errorf("expected %x, got %x", code, got)
+	}
+}
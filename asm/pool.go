@@ -0,0 +1,35 @@
+package asm
+
+import "github.com/kelvyne/as3/bytecode"
+
+type privateKey struct {
+	Name    uint32
+	Ordinal int
+}
+
+// pool builds a constant pool, returning the same index for equal entries.
+// Private namespaces are told apart by their ordinal.
+type pool struct {
+	cpool    bytecode.CpoolInfo
+	builder  *bytecode.CpoolBuilder
+	privates map[privateKey]uint32
+}
+
+func newPool() *pool {
+	p := &pool{privates: map[privateKey]uint32{}}
+	p.builder = bytecode.NewCpoolBuilder(&p.cpool)
+	return p
+}
+
+func (p *pool) namespace(kind uint8, name uint32, ordinal int) uint32 {
+	if kind != bytecode.NamespaceKindPrivateNs {
+		return p.builder.AddNamespace(kind, name)
+	}
+	key := privateKey{name, ordinal}
+	i, ok := p.privates[key]
+	if !ok {
+		i = p.builder.NewNamespace(kind, name)
+		p.privates[key] = i
+	}
+	return i
+}
@@ -0,0 +1,466 @@
+package asm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+// ErrIndexOutOfRange means that a reference indexes past the end of the
+// constant pool table it refers to
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+// ErrUnknownKind means that a namespace, a multiname or a constant value has
+// a kind with no textual form
+var ErrUnknownKind = errors.New("unknown kind")
+
+// ErrRecursiveTypeName means that a TypeName multiname refers to itself
+var ErrRecursiveTypeName = errors.New("recursive TypeName")
+
+// maxTypeNameDepth bounds the nesting of TypeName parameters
+const maxTypeNameDepth = 16
+
+type printer struct {
+	w     *bufio.Writer
+	abc   *bytecode.AbcFile
+	cpool *bytecode.CpoolInfo
+	err   error
+
+	// ordinals of the private namespaces already printed, and number of
+	// distinct private namespaces printed for each name
+	privates map[uint32]int
+	counts   map[string]int
+	depth    int
+}
+
+// Print writes the textual assembly of an AbcFile.
+// Method bodies are printed from their Code.
+func Print(w io.Writer, abc bytecode.AbcFile) error {
+	p := printer{
+		w:        bufio.NewWriter(w),
+		abc:      &abc,
+		cpool:    &abc.ConstantPool,
+		privates: map[uint32]int{},
+		counts:   map[string]int{},
+	}
+	p.Print()
+	if p.err != nil {
+		return p.err
+	}
+	return p.w.Flush()
+}
+
+func (p *printer) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+func (p *printer) line(indent int, format string, args ...interface{}) {
+	p.w.WriteString(strings.Repeat("  ", indent))
+	fmt.Fprintf(p.w, format, args...)
+	p.w.WriteByte('\n')
+}
+
+func (p *printer) Print() {
+	p.line(0, "minorversion %v", p.abc.MinorVersion)
+	p.line(0, "majorversion %v", p.abc.MajorVersion)
+	for i, m := range p.abc.Metadatas {
+		p.printMetadata(i, m)
+	}
+	for i, m := range p.abc.Methods {
+		p.printMethod(i, m)
+	}
+	for i, instance := range p.abc.Instances {
+		if i >= len(p.abc.Classes) {
+			p.fail(ErrIndexOutOfRange)
+			return
+		}
+		p.printClass(i, instance, p.abc.Classes[i])
+	}
+	for i, s := range p.abc.Scripts {
+		p.line(0, "")
+		p.line(0, "script %v", i)
+		p.line(1, "init %v", s.Init)
+		p.printTraits(1, s.Traits)
+		p.line(0, "end")
+	}
+	for i, body := range p.abc.MethodBodies {
+		p.printBody(i, body)
+	}
+}
+
+func (p *printer) str(i uint32) string {
+	if i == 0 {
+		return "null"
+	}
+	if int(i) >= len(p.cpool.Strings) {
+		p.fail(ErrIndexOutOfRange)
+		return "null"
+	}
+	return strconv.Quote(p.cpool.Strings[i])
+}
+
+func (p *printer) integer(i uint32) string {
+	if i == 0 {
+		return "null"
+	}
+	if int(i) >= len(p.cpool.Integers) {
+		p.fail(ErrIndexOutOfRange)
+		return "null"
+	}
+	return strconv.FormatInt(int64(p.cpool.Integers[i]), 10)
+}
+
+func (p *printer) uinteger(i uint32) string {
+	if i == 0 {
+		return "null"
+	}
+	if int(i) >= len(p.cpool.UIntegers) {
+		p.fail(ErrIndexOutOfRange)
+		return "null"
+	}
+	return strconv.FormatUint(uint64(p.cpool.UIntegers[i]), 10)
+}
+
+func (p *printer) double(i uint32) string {
+	if i == 0 {
+		return "null"
+	}
+	if int(i) >= len(p.cpool.Doubles) {
+		p.fail(ErrIndexOutOfRange)
+		return "null"
+	}
+	return strconv.FormatFloat(p.cpool.Doubles[i], 'g', -1, 64)
+}
+
+func (p *printer) namespace(i uint32) string {
+	if i == 0 {
+		return "null"
+	}
+	if int(i) >= len(p.cpool.Namespaces) {
+		p.fail(ErrIndexOutOfRange)
+		return "null"
+	}
+	info := p.cpool.Namespaces[i]
+	kind, ok := bytecode.NamespaceKindNames[info.Kind]
+	if !ok {
+		p.fail(ErrUnknownKind)
+		return "null"
+	}
+	name := p.str(info.Name)
+	if info.Kind != bytecode.NamespaceKindPrivateNs {
+		return fmt.Sprintf("%v(%v)", kind, name)
+	}
+	ordinal, ok := p.privates[i]
+	if !ok {
+		ordinal = p.counts[name]
+		p.counts[name]++
+		p.privates[i] = ordinal
+	}
+	if ordinal == 0 {
+		return fmt.Sprintf("%v(%v)", kind, name)
+	}
+	return fmt.Sprintf("%v(%v, %v)", kind, name, ordinal)
+}
+
+func (p *printer) nsSet(i uint32) string {
+	if i == 0 {
+		return "null"
+	}
+	if int(i) >= len(p.cpool.NsSets) {
+		p.fail(ErrIndexOutOfRange)
+		return "null"
+	}
+	var namespaces []string
+	for _, ns := range p.cpool.NsSets[i].Namespaces {
+		namespaces = append(namespaces, p.namespace(ns))
+	}
+	return "[" + strings.Join(namespaces, ", ") + "]"
+}
+
+func (p *printer) multiname(i uint32) string {
+	if i == 0 {
+		return "null"
+	}
+	if int(i) >= len(p.cpool.Multinames) {
+		p.fail(ErrIndexOutOfRange)
+		return "null"
+	}
+	info := p.cpool.Multinames[i]
+	kind, ok := bytecode.MultinameKindNames[info.Kind]
+	if !ok {
+		p.fail(ErrUnknownKind)
+		return "null"
+	}
+	switch info.Kind {
+	case bytecode.MultinameKindQName, bytecode.MultinameKindQNameA:
+		return fmt.Sprintf("%v(%v, %v)", kind, p.namespace(info.Namespace), p.str(info.Name))
+	case bytecode.MultinameKindRTQName, bytecode.MultinameKindRTQNameA:
+		return fmt.Sprintf("%v(%v)", kind, p.str(info.Name))
+	case bytecode.MultinameKindRTQNameL, bytecode.MultinameKindRTQNameLA:
+		return kind + "()"
+	case bytecode.MultinameKindMultiname, bytecode.MultinameKindMultinameA:
+		return fmt.Sprintf("%v(%v, %v)", kind, p.str(info.Name), p.nsSet(info.NsSet))
+	case bytecode.MultinameKindMultinameL, bytecode.MultinameKindMultinameLA:
+		return fmt.Sprintf("%v(%v)", kind, p.nsSet(info.NsSet))
+	}
+	if p.depth >= maxTypeNameDepth {
+		p.fail(ErrRecursiveTypeName)
+		return "null"
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+	var params []string
+	for _, param := range info.Params {
+		params = append(params, p.multiname(param))
+	}
+	return fmt.Sprintf("%v(%v<%v>)", kind, p.multiname(info.Name), strings.Join(params, ", "))
+}
+
+// value formats the constant of an optional parameter or of a slot
+func (p *printer) value(kind uint8, index uint32) string {
+	switch kind {
+	case bytecode.SlotKindInt:
+		return fmt.Sprintf("Integer(%v)", p.integer(index))
+	case bytecode.SlotKindUInt:
+		return fmt.Sprintf("UInteger(%v)", p.uinteger(index))
+	case bytecode.SlotKindDouble:
+		return fmt.Sprintf("Double(%v)", p.double(index))
+	case bytecode.SlotKindUtf8:
+		return fmt.Sprintf("Utf8(%v)", p.str(index))
+	case bytecode.SlotKindTrue:
+		return "True"
+	case bytecode.SlotKindFalse:
+		return "False"
+	case bytecode.SlotKindNull:
+		return "Null"
+	case bytecode.SlotKindUndefined:
+		return "Undefined"
+	}
+	if _, ok := bytecode.NamespaceKindNames[kind]; ok {
+		return p.namespace(index)
+	}
+	p.fail(ErrUnknownKind)
+	return "Undefined"
+}
+
+func flagsString(flags uint8, names []bytecode.Flag) string {
+	return strings.Join(bytecode.FlagNames(flags, names), " ")
+}
+
+func (p *printer) printMetadata(index int, m bytecode.MetadataInfo) {
+	p.line(0, "")
+	p.line(0, "metadata %v %v", index, p.str(m.Names))
+	for _, item := range m.Items {
+		p.line(1, "item %v %v", p.str(item.Key), p.str(item.Value))
+	}
+	p.line(0, "end")
+}
+
+func (p *printer) printMethod(index int, m bytecode.MethodInfo) {
+	p.line(0, "")
+	p.line(0, "method %v", index)
+	if m.Name != 0 {
+		p.line(1, "name %v", p.str(m.Name))
+	}
+	if m.Flags != 0 {
+		p.line(1, "flags %v", flagsString(m.Flags, bytecode.MethodFlagNames))
+	}
+	p.line(1, "returns %v", p.multiname(m.ReturnType))
+	for _, param := range m.ParamTypes {
+		p.line(1, "param %v", p.multiname(param))
+	}
+	for _, option := range m.OptionInfo.Options {
+		p.line(1, "optional %v", p.value(option.Kind, option.Value))
+	}
+	for _, name := range m.ParamInfo.ParamNames {
+		p.line(1, "paramname %v", p.str(name))
+	}
+	p.line(0, "end")
+}
+
+func (p *printer) printClass(index int, instance bytecode.InstanceInfo, class bytecode.ClassInfo) {
+	p.line(0, "")
+	p.line(0, "class %v", index)
+	p.line(1, "instance %v", p.multiname(instance.Name))
+	if instance.SuperName != 0 {
+		p.line(2, "extends %v", p.multiname(instance.SuperName))
+	}
+	if instance.Flags != 0 {
+		p.line(2, "flags %v", flagsString(instance.Flags, bytecode.InstanceFlagNames))
+	}
+	if instance.Flags&bytecode.InstanceInfoClassProtectedNs != 0 {
+		p.line(2, "protectedns %v", p.namespace(instance.ProtectedNs))
+	}
+	for _, intf := range instance.Interfaces {
+		p.line(2, "implements %v", p.multiname(intf))
+	}
+	p.line(2, "iinit %v", instance.IInit)
+	p.printTraits(2, instance.Traits)
+	p.line(1, "end")
+	p.line(1, "cinit %v", class.CInit)
+	p.printTraits(2, class.Traits)
+	p.line(1, "end")
+	p.line(0, "end")
+}
+
+func (p *printer) printTraits(indent int, traits []bytecode.TraitsInfo) {
+	for _, t := range traits {
+		p.printTrait(indent, t)
+	}
+}
+
+func (p *printer) printTrait(indent int, t bytecode.TraitsInfo) {
+	kind, ok := bytecode.TraitKindNames[t.GetType()]
+	if !ok {
+		p.fail(bytecode.ErrUnknownTraitsInfoKind)
+		return
+	}
+	words := []string{"trait", kind, p.multiname(t.Name)}
+	if t.Kind&bytecode.TraitsInfoAttributeFinal != 0 {
+		words = append(words, "final")
+	}
+	if t.Kind&bytecode.TraitsInfoAttributeOverride != 0 {
+		words = append(words, "override")
+	}
+	switch t.GetType() {
+	case bytecode.TraitsInfoSlot, bytecode.TraitsInfoConst:
+		words = append(words, "slotid", fmt.Sprint(t.SlotID), "type", p.multiname(t.Typename))
+		if t.VIndex != 0 {
+			words = append(words, "value", p.value(t.VKind, t.VIndex))
+		}
+	case bytecode.TraitsInfoClass:
+		words = append(words, "slotid", fmt.Sprint(t.SlotID), "class", fmt.Sprint(t.ClassI))
+	case bytecode.TraitsInfoFunction:
+		words = append(words, "slotid", fmt.Sprint(t.SlotID), "method", fmt.Sprint(t.Function))
+	default:
+		words = append(words, "dispid", fmt.Sprint(t.DispID), "method", fmt.Sprint(t.Method))
+	}
+	if t.Kind&bytecode.TraitsInfoAttributeMetadata != 0 {
+		words = append(words, "metadata")
+		for _, m := range t.Metadatas {
+			words = append(words, fmt.Sprint(m))
+		}
+	}
+	p.line(indent, "%v", strings.Join(words, " "))
+}
+
+// disassemble returns the instructions of a method body with labels for
+// their branch targets and exception ranges. It reports false when the code
+// cannot be printed as instructions: unknown instructions or an encoding that
+// would not assemble back to the same code. Labels are left unresolved when
+// some branch or exception offset does not point to an instruction.
+func disassemble(body bytecode.MethodBodyInfo) (bytecode.MethodBodyInfo, bool) {
+	body.Instructions = nil
+	body.EndLabel = 0
+	if err := body.Disassemble(); err != nil {
+		return body, false
+	}
+	code := body.Code
+	if err := body.Assemble(); err != nil || !bytes.Equal(code, body.Code) {
+		return body, false
+	}
+	resolved := body
+	resolved.Instructions = append([]bytecode.Instr(nil), body.Instructions...)
+	if err := resolved.ResolveLabels(); err != nil {
+		return body, true
+	}
+	return resolved, true
+}
+
+func labelName(l bytecode.Label) string {
+	return fmt.Sprintf("L%v", l)
+}
+
+// position formats an exception offset as its label, if any
+func position(l bytecode.Label, offset uint32) string {
+	if l != 0 {
+		return labelName(l)
+	}
+	return fmt.Sprint(offset)
+}
+
+func (p *printer) operand(instr bytecode.Instr, o bytecode.Operand, branch int) string {
+	switch o.Type {
+	case bytecode.OperandTypeByte:
+		return fmt.Sprint(o.Signed())
+	case bytecode.OperandTypeShort:
+		// values past 16 bits are kept raw to be reassembled unchanged
+		if o.Value > math.MaxUint16 {
+			return fmt.Sprintf("%#x", o.Value)
+		}
+		return fmt.Sprint(o.Signed())
+	case bytecode.OperandTypeBranch:
+		if instr.Targets != nil {
+			return labelName(instr.Targets[branch])
+		}
+		return fmt.Sprint(o.Signed())
+	case bytecode.OperandTypeInt:
+		return p.integer(o.Value)
+	case bytecode.OperandTypeUInt:
+		return p.uinteger(o.Value)
+	case bytecode.OperandTypeDouble:
+		return p.double(o.Value)
+	case bytecode.OperandTypeString:
+		return p.str(o.Value)
+	case bytecode.OperandTypeNamespace:
+		return p.namespace(o.Value)
+	case bytecode.OperandTypeMultiname:
+		return p.multiname(o.Value)
+	}
+	return fmt.Sprint(o.Value)
+}
+
+func (p *printer) printInstr(instr bytecode.Instr) {
+	words := []string{instr.Model.Name}
+	branch := 0
+	for _, o := range instr.TypedOperands() {
+		words = append(words, p.operand(instr, o, branch))
+		if o.Type == bytecode.OperandTypeBranch {
+			branch++
+		}
+	}
+	p.line(2, "%v", strings.Join(words, " "))
+}
+
+func (p *printer) printBody(index int, body bytecode.MethodBodyInfo) {
+	p.line(0, "")
+	p.line(0, "body %v", index)
+	p.line(1, "method %v", body.Method)
+	p.line(1, "maxstack %v", body.MaxStack)
+	p.line(1, "localcount %v", body.LocalCount)
+	p.line(1, "initscopedepth %v", body.InitScopeLength)
+	p.line(1, "maxscopedepth %v", body.MaxScopeLength)
+	body, ok := disassemble(body)
+	if ok {
+		p.line(1, "code")
+		for _, instr := range body.Instructions {
+			if instr.Label != 0 {
+				p.line(1, "%v:", labelName(instr.Label))
+			}
+			p.printInstr(instr)
+		}
+		if body.EndLabel != 0 {
+			p.line(1, "%v:", labelName(body.EndLabel))
+		}
+		p.line(1, "end")
+	} else {
+		p.line(1, "rawcode %v", hex.EncodeToString(body.Code))
+	}
+	for _, e := range body.Exceptions {
+		p.line(1, "try from %v to %v target %v type %v name %v",
+			position(e.FromLabel, e.From), position(e.ToLabel, e.To), position(e.TargetLabel, e.Target),
+			p.multiname(e.ExcType), p.multiname(e.VarName))
+	}
+	p.printTraits(1, body.Traits)
+	p.line(0, "end")
+}
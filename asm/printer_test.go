@@ -0,0 +1,128 @@
+package asm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+func TestPrint(t *testing.T) {
+	abc := bytecode.AbcFile{
+		MinorVersion: 16,
+		MajorVersion: 46,
+		ConstantPool: bytecode.CpoolInfo{
+			Doubles: []float64{0, 0.5},
+			Strings: []string{"", "", "Foo", "x"},
+			Namespaces: []bytecode.NamespaceInfo{
+				{},
+				{Kind: bytecode.NamespaceKindPackageNamespace, Name: 1},
+				{Kind: bytecode.NamespaceKindPrivateNs, Name: 3},
+				{Kind: bytecode.NamespaceKindPrivateNs, Name: 3},
+			},
+			NsSets: []bytecode.NsSetInfo{{}, {Count: 2, Namespaces: []uint32{1, 3}}},
+			Multinames: []bytecode.MultinameInfo{
+				{},
+				{Kind: bytecode.MultinameKindQName, Namespace: 1, Name: 2},
+				{Kind: bytecode.MultinameKindMultiname, Name: 3, NsSet: 1},
+				{Kind: bytecode.MultinameKindQName, Namespace: 2, Name: 3},
+			},
+		},
+		Methods: []bytecode.MethodInfo{{}},
+		Instances: []bytecode.InstanceInfo{{
+			Name:  1,
+			Flags: bytecode.InstanceInfoClassSealed,
+			Traits: []bytecode.TraitsInfo{
+				{Name: 3, Kind: bytecode.TraitsInfoConst, SlotID: 1, VIndex: 1, VKind: bytecode.SlotKindDouble},
+			},
+		}},
+		Classes: []bytecode.ClassInfo{{}},
+		MethodBodies: []bytecode.MethodBodyInfo{{
+			MaxStack:   1,
+			Code:       []byte{0x60, 0x02, 0x2f, 0x01, 0x10, 0x00, 0x00, 0x00, 0x47},
+			Exceptions: []bytecode.ExceptionInfo{{From: 0, To: 4, Target: 8}},
+		}},
+	}
+	want := `minorversion 16
+majorversion 46
+
+method 0
+  returns null
+end
+
+class 0
+  instance QName(PackageNamespace(""), "Foo")
+    flags SEALED
+    iinit 0
+    trait const QName(PrivateNamespace("x"), "x") slotid 1 type null value Double(0.5)
+  end
+  cinit 0
+  end
+end
+
+body 0
+  method 0
+  maxstack 1
+  localcount 0
+  initscopedepth 0
+  maxscopedepth 0
+  code
+  L2:
+    getlex Multiname("x", [PackageNamespace(""), PrivateNamespace("x", 1)])
+    pushdouble 0.5
+  L3:
+    jump L1
+  L1:
+    returnvoid
+  end
+  try from L2 to L3 target L1 type null name null
+end
+`
+	buf := &bytes.Buffer{}
+	if err := Print(buf, abc); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if buf.String() != want {
+		t.Errorf("expected\n%v\ngot\n%v", want, buf.String())
+	}
+}
+
+func TestPrint_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		abc  bytecode.AbcFile
+		err  error
+	}{
+		{
+			"string out of range",
+			bytecode.AbcFile{Methods: []bytecode.MethodInfo{{Name: 1}}},
+			ErrIndexOutOfRange,
+		},
+		{
+			"unknown namespace kind",
+			bytecode.AbcFile{
+				ConstantPool: bytecode.CpoolInfo{Namespaces: []bytecode.NamespaceInfo{{}, {Kind: 0x42}}},
+				MethodBodies: []bytecode.MethodBodyInfo{{Code: []byte{0x31, 0x01}}},
+			},
+			ErrUnknownKind,
+		},
+		{
+			"recursive TypeName",
+			bytecode.AbcFile{
+				ConstantPool: bytecode.CpoolInfo{Multinames: []bytecode.MultinameInfo{
+					{},
+					{Kind: bytecode.MultinameKindTypename, Name: 1, Params: []uint32{1}},
+				}},
+				Methods: []bytecode.MethodInfo{{ReturnType: 1}},
+			},
+			ErrRecursiveTypeName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Print(&bytes.Buffer{}, tt.abc); err != tt.err {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
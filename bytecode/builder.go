@@ -0,0 +1,205 @@
+package bytecode
+
+import (
+	"fmt"
+	"math"
+)
+
+// CpoolBuilder adds entries to a constant pool. Equal entries are interned:
+// adding an entry already in the pool returns its existing index.
+// The reserved index 0 is never returned.
+//
+// The builder indexes the pool when created; entries appended to the pool
+// by hand afterwards are not interned.
+type CpoolBuilder struct {
+	Pool *CpoolInfo
+
+	integers   map[int32]uint32
+	uintegers  map[uint32]uint32
+	doubles    map[uint64]uint32
+	strings    map[string]uint32
+	namespaces map[NamespaceInfo]uint32
+	nsSets     map[string]uint32
+	multinames map[string]uint32
+}
+
+// NewCpoolBuilder returns a builder adding entries to the constant pool.
+// Empty tables are given their reserved entry.
+func NewCpoolBuilder(c *CpoolInfo) *CpoolBuilder {
+	if len(c.Integers) == 0 {
+		c.Integers = []int32{0}
+	}
+	if len(c.UIntegers) == 0 {
+		c.UIntegers = []uint32{0}
+	}
+	if len(c.Doubles) == 0 {
+		c.Doubles = []float64{0}
+	}
+	if len(c.Strings) == 0 {
+		c.Strings = []string{""}
+	}
+	if len(c.Namespaces) == 0 {
+		c.Namespaces = []NamespaceInfo{{}}
+	}
+	if len(c.NsSets) == 0 {
+		c.NsSets = []NsSetInfo{{}}
+	}
+	if len(c.Multinames) == 0 {
+		c.Multinames = []MultinameInfo{{}}
+	}
+	b := &CpoolBuilder{
+		Pool:       c,
+		integers:   map[int32]uint32{},
+		uintegers:  map[uint32]uint32{},
+		doubles:    map[uint64]uint32{},
+		strings:    map[string]uint32{},
+		namespaces: map[NamespaceInfo]uint32{},
+		nsSets:     map[string]uint32{},
+		multinames: map[string]uint32{},
+	}
+	// the first of equal entries wins
+	for i := len(c.Integers) - 1; i > 0; i-- {
+		b.integers[c.Integers[i]] = uint32(i)
+	}
+	for i := len(c.UIntegers) - 1; i > 0; i-- {
+		b.uintegers[c.UIntegers[i]] = uint32(i)
+	}
+	for i := len(c.Doubles) - 1; i > 0; i-- {
+		b.doubles[math.Float64bits(c.Doubles[i])] = uint32(i)
+	}
+	for i := len(c.Strings) - 1; i > 0; i-- {
+		b.strings[c.Strings[i]] = uint32(i)
+	}
+	for i := len(c.Namespaces) - 1; i > 0; i-- {
+		b.namespaces[c.Namespaces[i]] = uint32(i)
+	}
+	for i := len(c.NsSets) - 1; i > 0; i-- {
+		b.nsSets[nsSetKey(c.NsSets[i].Namespaces)] = uint32(i)
+	}
+	for i := len(c.Multinames) - 1; i > 0; i-- {
+		b.multinames[multinameKey(c.Multinames[i])] = uint32(i)
+	}
+	return b
+}
+
+func nsSetKey(namespaces []uint32) string {
+	return fmt.Sprint(namespaces)
+}
+
+func multinameKey(info MultinameInfo) string {
+	return fmt.Sprint(info.Kind, info.Name, info.Namespace, info.NsSet, info.Params)
+}
+
+// AddInt returns the index of an integer
+func (b *CpoolBuilder) AddInt(v int32) uint32 {
+	i, ok := b.integers[v]
+	if !ok {
+		i = uint32(len(b.Pool.Integers))
+		b.Pool.Integers = append(b.Pool.Integers, v)
+		b.integers[v] = i
+	}
+	return i
+}
+
+// AddUInt returns the index of an unsigned integer
+func (b *CpoolBuilder) AddUInt(v uint32) uint32 {
+	i, ok := b.uintegers[v]
+	if !ok {
+		i = uint32(len(b.Pool.UIntegers))
+		b.Pool.UIntegers = append(b.Pool.UIntegers, v)
+		b.uintegers[v] = i
+	}
+	return i
+}
+
+// AddDouble returns the index of a double. Doubles are compared bit for bit,
+// so that NaN is interned and 0 differs from -0.
+func (b *CpoolBuilder) AddDouble(v float64) uint32 {
+	key := math.Float64bits(v)
+	i, ok := b.doubles[key]
+	if !ok {
+		i = uint32(len(b.Pool.Doubles))
+		b.Pool.Doubles = append(b.Pool.Doubles, v)
+		b.doubles[key] = i
+	}
+	return i
+}
+
+// AddString returns the index of a string
+func (b *CpoolBuilder) AddString(v string) uint32 {
+	i, ok := b.strings[v]
+	if !ok {
+		i = uint32(len(b.Pool.Strings))
+		b.Pool.Strings = append(b.Pool.Strings, v)
+		b.strings[v] = i
+	}
+	return i
+}
+
+// AddNamespace returns the index of a namespace given its kind and the index
+// of its name. Private namespaces are interned as well; use NewNamespace to
+// get a distinct one.
+func (b *CpoolBuilder) AddNamespace(kind uint8, name uint32) uint32 {
+	info := NamespaceInfo{kind, name}
+	i, ok := b.namespaces[info]
+	if !ok {
+		i = uint32(len(b.Pool.Namespaces))
+		b.Pool.Namespaces = append(b.Pool.Namespaces, info)
+		b.namespaces[info] = i
+	}
+	return i
+}
+
+// NewNamespace appends a namespace even if an equal one exists, and returns
+// its index. It is meant for private namespaces, which are only equal to
+// themselves.
+func (b *CpoolBuilder) NewNamespace(kind uint8, name uint32) uint32 {
+	info := NamespaceInfo{kind, name}
+	i := uint32(len(b.Pool.Namespaces))
+	b.Pool.Namespaces = append(b.Pool.Namespaces, info)
+	if _, ok := b.namespaces[info]; !ok {
+		b.namespaces[info] = i
+	}
+	return i
+}
+
+// AddNsSet returns the index of a namespace set, given the indexes of its
+// namespaces
+func (b *CpoolBuilder) AddNsSet(namespaces ...uint32) uint32 {
+	key := nsSetKey(namespaces)
+	i, ok := b.nsSets[key]
+	if !ok {
+		i = uint32(len(b.Pool.NsSets))
+		namespaces = append([]uint32{}, namespaces...)
+		b.Pool.NsSets = append(b.Pool.NsSets, NsSetInfo{uint32(len(namespaces)), namespaces})
+		b.nsSets[key] = i
+	}
+	return i
+}
+
+// AddMultiname returns the index of a multiname. The fields that are not
+// used by the kind of the multiname must be zero.
+func (b *CpoolBuilder) AddMultiname(info MultinameInfo) uint32 {
+	key := multinameKey(info)
+	i, ok := b.multinames[key]
+	if !ok {
+		i = uint32(len(b.Pool.Multinames))
+		if info.Params != nil {
+			info.Params = append([]uint32{}, info.Params...)
+		}
+		b.Pool.Multinames = append(b.Pool.Multinames, info)
+		b.multinames[key] = i
+	}
+	return i
+}
+
+// AddQName returns the index of a QName multiname
+func (b *CpoolBuilder) AddQName(ns uint32, name string) uint32 {
+	return b.AddMultiname(MultinameInfo{Kind: MultinameKindQName, Namespace: ns, Name: b.AddString(name)})
+}
+
+// AddTypename returns the index of a Typename multiname: the multiname name
+// applied to the multinames params, like Vector.<int>
+func (b *CpoolBuilder) AddTypename(name uint32, params ...uint32) uint32 {
+	return b.AddMultiname(MultinameInfo{Kind: MultinameKindTypename, Name: name, Params: params})
+}
@@ -0,0 +1,82 @@
+package bytecode
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCpoolBuilder(t *testing.T) {
+	c := &CpoolInfo{}
+	b := NewCpoolBuilder(c)
+
+	if i, j := b.AddInt(-1), b.AddInt(-1); i != 1 || j != 1 {
+		t.Errorf("expected 1, got %v and %v", i, j)
+	}
+	if i := b.AddUInt(7); i != 1 {
+		t.Errorf("expected 1, got %v", i)
+	}
+	nan := b.AddDouble(math.NaN())
+	if b.AddDouble(math.NaN()) != nan || b.AddDouble(0) == b.AddDouble(math.Copysign(0, -1)) {
+		t.Errorf("expected doubles to be compared bit for bit, got %v", c.Doubles)
+	}
+	empty := b.AddString("")
+	if empty != 1 || b.AddString("foo") != 2 || b.AddString("") != empty {
+		t.Errorf("expected strings [ foo], got %v", c.Strings)
+	}
+
+	pkg := b.AddNamespace(NamespaceKindPackageNamespace, empty)
+	if b.AddNamespace(NamespaceKindPackageNamespace, empty) != pkg {
+		t.Errorf("expected namespace %v to be interned", pkg)
+	}
+	private := b.NewNamespace(NamespaceKindPrivateNs, empty)
+	if b.NewNamespace(NamespaceKindPrivateNs, empty) == private {
+		t.Errorf("expected a new private namespace")
+	}
+	if b.AddNamespace(NamespaceKindPrivateNs, empty) != private {
+		t.Errorf("expected the first private namespace")
+	}
+
+	set := b.AddNsSet(pkg, private)
+	if b.AddNsSet(pkg, private) != set || b.AddNsSet(private, pkg) == set {
+		t.Errorf("expected namespace sets to be interned in order, got %v", c.NsSets)
+	}
+
+	vector := b.AddQName(pkg, "Vector")
+	integer := b.AddQName(pkg, "int")
+	nested := b.AddTypename(vector, b.AddTypename(vector, integer))
+	if b.AddTypename(vector, b.AddTypename(vector, integer)) != nested {
+		t.Errorf("expected nested Typename to be interned, got %v", c.Multinames)
+	}
+	if b.AddTypename(vector, integer) == nested {
+		t.Errorf("expected distinct Typename params to differ")
+	}
+	want := MultinameInfo{Kind: MultinameKindTypename, Name: vector, Params: []uint32{nested - 1}}
+	if got := c.Multinames[nested]; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if c.MultinameString(nested) != "Vector<Vector<int>>" {
+		t.Errorf("expected Vector<Vector<int>>, got %v", c.MultinameString(nested))
+	}
+}
+
+func TestNewCpoolBuilder_Existing(t *testing.T) {
+	c := &CpoolInfo{
+		Strings:    []string{"", "foo", "bar", "foo"},
+		Namespaces: []NamespaceInfo{{}, {NamespaceKindPackageNamespace, 2}},
+	}
+	b := NewCpoolBuilder(c)
+	if i := b.AddString("foo"); i != 1 {
+		t.Errorf("expected the first equal entry 1, got %v", i)
+	}
+	if i := b.AddNamespace(NamespaceKindPackageNamespace, 2); i != 1 {
+		t.Errorf("expected 1, got %v", i)
+	}
+	if i := b.AddString("baz"); i != 4 {
+		t.Errorf("expected 4, got %v", i)
+	}
+	if len(c.Integers) != 1 || len(c.Multinames) != 1 {
+		t.Errorf("expected reserved entries, got %v and %v", c.Integers, c.Multinames)
+	}
+}
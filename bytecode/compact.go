@@ -0,0 +1,403 @@
+package bytecode
+
+import "errors"
+
+// ErrIndexOutOfRange means that a reference indexes past the end of the
+// constant pool table it refers to
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+// ErrCodeLayoutChanged means that rewriting the operands of a method body
+// would move its instructions while its branch or exception offsets cannot
+// be resolved to labels
+var ErrCodeLayoutChanged = errors.New("code layout changed")
+
+// CpoolRemoved lists, by table, the indexes the entries removed from the
+// constant pool had before compaction
+type CpoolRemoved struct {
+	Integers   []uint32
+	UIntegers  []uint32
+	Doubles    []uint32
+	Strings    []uint32
+	Namespaces []uint32
+	NsSets     []uint32
+	Multinames []uint32
+}
+
+// cpoolTable identifies a table of the constant pool
+type cpoolTable uint8
+
+const (
+	tableInteger = cpoolTable(iota)
+	tableUInteger
+	tableDouble
+	tableString
+	tableNamespace
+	tableNsSet
+	tableMultiname
+	tableCount
+)
+
+// refVisitor is called for each constant pool reference, with the table it
+// refers to
+type refVisitor func(t cpoolTable, ref *uint32)
+
+func (c *CpoolInfo) tableLen(t cpoolTable) int {
+	switch t {
+	case tableInteger:
+		return len(c.Integers)
+	case tableUInteger:
+		return len(c.UIntegers)
+	case tableDouble:
+		return len(c.Doubles)
+	case tableString:
+		return len(c.Strings)
+	case tableNamespace:
+		return len(c.Namespaces)
+	case tableNsSet:
+		return len(c.NsSets)
+	}
+	return len(c.Multinames)
+}
+
+// valueTable returns the table the index of a constant value of the given
+// kind refers to. True, false, null and undefined refer to no table.
+func valueTable(kind uint8) (cpoolTable, bool) {
+	switch kind {
+	case SlotKindInt:
+		return tableInteger, true
+	case SlotKindUInt:
+		return tableUInteger, true
+	case SlotKindDouble:
+		return tableDouble, true
+	case SlotKindUtf8:
+		return tableString, true
+	case SlotKindNamespace, SlotKindPackageNamespace, SlotKindPackageInternalNs, SlotKindProtectedNamespace,
+		SlotKindExplicitNamespace, SlotKindStaticProtectedNs, SlotKindPrivateNs:
+		return tableNamespace, true
+	}
+	return 0, false
+}
+
+// operandTable returns the table an instruction operand of the given type
+// refers to, if any
+func operandTable(t OperandType) (cpoolTable, bool) {
+	switch t {
+	case OperandTypeInt:
+		return tableInteger, true
+	case OperandTypeUInt:
+		return tableUInteger, true
+	case OperandTypeDouble:
+		return tableDouble, true
+	case OperandTypeString:
+		return tableString, true
+	case OperandTypeNamespace:
+		return tableNamespace, true
+	case OperandTypeMultiname:
+		return tableMultiname, true
+	}
+	return 0, false
+}
+
+// walkEntry visits the references of a constant pool entry
+func (c *CpoolInfo) walkEntry(t cpoolTable, i uint32, visit refVisitor) {
+	switch t {
+	case tableNamespace:
+		visit(tableString, &c.Namespaces[i].Name)
+	case tableNsSet:
+		for j := range c.NsSets[i].Namespaces {
+			visit(tableNamespace, &c.NsSets[i].Namespaces[j])
+		}
+	case tableMultiname:
+		m := &c.Multinames[i]
+		switch m.Kind {
+		case MultinameKindQName, MultinameKindQNameA:
+			visit(tableNamespace, &m.Namespace)
+			visit(tableString, &m.Name)
+		case MultinameKindRTQName, MultinameKindRTQNameA:
+			visit(tableString, &m.Name)
+		case MultinameKindMultiname, MultinameKindMultinameA:
+			visit(tableString, &m.Name)
+			visit(tableNsSet, &m.NsSet)
+		case MultinameKindMultinameL, MultinameKindMultinameLA:
+			visit(tableNsSet, &m.NsSet)
+		case MultinameKindTypename:
+			visit(tableMultiname, &m.Name)
+			for j := range m.Params {
+				visit(tableMultiname, &m.Params[j])
+			}
+		}
+	}
+}
+
+func walkTraits(traits []TraitsInfo, visit refVisitor) {
+	for i := range traits {
+		t := &traits[i]
+		visit(tableMultiname, &t.Name)
+		switch t.GetType() {
+		case TraitsInfoSlot, TraitsInfoConst:
+			visit(tableMultiname, &t.Typename)
+			if table, ok := valueTable(t.VKind); ok && t.VIndex != 0 {
+				visit(table, &t.VIndex)
+			}
+		}
+	}
+}
+
+// walkFile visits the constant pool references of the file, apart from the
+// ones held by the constant pool itself and by the code of method bodies
+func (abc *AbcFile) walkFile(visit refVisitor) {
+	for i := range abc.Methods {
+		m := &abc.Methods[i]
+		visit(tableMultiname, &m.ReturnType)
+		for j := range m.ParamTypes {
+			visit(tableMultiname, &m.ParamTypes[j])
+		}
+		visit(tableString, &m.Name)
+		for j := range m.OptionInfo.Options {
+			o := &m.OptionInfo.Options[j]
+			if table, ok := valueTable(o.Kind); ok {
+				visit(table, &o.Value)
+			}
+		}
+		for j := range m.ParamInfo.ParamNames {
+			visit(tableString, &m.ParamInfo.ParamNames[j])
+		}
+	}
+	for i := range abc.Metadatas {
+		m := &abc.Metadatas[i]
+		visit(tableString, &m.Names)
+		for j := range m.Items {
+			visit(tableString, &m.Items[j].Key)
+			visit(tableString, &m.Items[j].Value)
+		}
+	}
+	for i := range abc.Instances {
+		instance := &abc.Instances[i]
+		visit(tableMultiname, &instance.Name)
+		visit(tableMultiname, &instance.SuperName)
+		if instance.Flags&InstanceInfoClassProtectedNs != 0 {
+			visit(tableNamespace, &instance.ProtectedNs)
+		}
+		for j := range instance.Interfaces {
+			visit(tableMultiname, &instance.Interfaces[j])
+		}
+		walkTraits(instance.Traits, visit)
+	}
+	for i := range abc.Classes {
+		walkTraits(abc.Classes[i].Traits, visit)
+	}
+	for i := range abc.Scripts {
+		walkTraits(abc.Scripts[i].Traits, visit)
+	}
+	for i := range abc.MethodBodies {
+		body := &abc.MethodBodies[i]
+		for j := range body.Exceptions {
+			visit(tableMultiname, &body.Exceptions[j].ExcType)
+			visit(tableMultiname, &body.Exceptions[j].VarName)
+		}
+		walkTraits(body.Traits, visit)
+	}
+}
+
+// walkInstrs visits the constant pool references of instruction operands
+func walkInstrs(instrs []Instr, visit refVisitor) {
+	for i := range instrs {
+		for j, t := range instrs[i].OperandTypes() {
+			if table, ok := operandTable(t); ok && j < len(instrs[i].Operands) {
+				visit(table, &instrs[i].Operands[j])
+			}
+		}
+	}
+}
+
+type marker struct {
+	c    *CpoolInfo
+	live [tableCount][]bool
+	err  error
+}
+
+func (m *marker) mark(t cpoolTable, ref *uint32) {
+	i := *ref
+	if i == 0 || m.err != nil {
+		return
+	}
+	if int64(i) >= int64(len(m.live[t])) {
+		m.err = ErrIndexOutOfRange
+		return
+	}
+	if m.live[t][i] {
+		return
+	}
+	m.live[t][i] = true
+	m.c.walkEntry(t, i, m.mark)
+}
+
+// compactBody is a disassembled copy of a method body
+type compactBody struct {
+	body     MethodBodyInfo
+	offsets  []uint32
+	resolved bool
+	changed  bool
+}
+
+func prepareBody(body MethodBodyInfo) (compactBody, error) {
+	body.Instructions = nil
+	body.EndLabel = 0
+	body.Exceptions = append([]ExceptionInfo(nil), body.Exceptions...)
+	if err := body.Disassemble(); err != nil {
+		return compactBody{}, err
+	}
+	offsets, err := body.Offsets()
+	if err != nil {
+		return compactBody{}, err
+	}
+	// offsets are kept as they are when they cannot be resolved
+	resolved := body.ResolveLabels() == nil
+	return compactBody{body, offsets, resolved, false}, nil
+}
+
+// rewrite assembles the body after its operands were rewritten
+func (b *compactBody) rewrite() error {
+	if !b.changed {
+		return nil
+	}
+	if !b.resolved {
+		offsets, err := b.body.Offsets()
+		if err != nil {
+			return err
+		}
+		for i := range offsets {
+			if offsets[i] != b.offsets[i] {
+				return ErrCodeLayoutChanged
+			}
+		}
+	}
+	return b.body.Assemble()
+}
+
+// CompactCpool removes the constant pool entries that nothing in the file
+// refers to and rewrites every reference to the remaining ones, in place.
+// The reserved entries are kept. The code of a method body is reassembled,
+// with its instructions left disassembled, only when one of its operands
+// changes.
+//
+// The file is left untouched when an error is returned.
+func (abc *AbcFile) CompactCpool() (CpoolRemoved, error) {
+	c := &abc.ConstantPool
+	bodies := make([]compactBody, len(abc.MethodBodies))
+	for i, body := range abc.MethodBodies {
+		b, err := prepareBody(body)
+		if err != nil {
+			return CpoolRemoved{}, err
+		}
+		bodies[i] = b
+	}
+
+	m := marker{c: c}
+	for t := range m.live {
+		m.live[t] = make([]bool, c.tableLen(cpoolTable(t)))
+	}
+	abc.walkFile(m.mark)
+	for i := range bodies {
+		walkInstrs(bodies[i].body.Instructions, m.mark)
+	}
+	if m.err != nil {
+		return CpoolRemoved{}, m.err
+	}
+
+	var removed [tableCount][]uint32
+	var remap [tableCount][]uint32
+	for t, live := range m.live {
+		remap[t] = make([]uint32, len(live))
+		next := uint32(1)
+		for i := 1; i < len(live); i++ {
+			if live[i] {
+				remap[t][i] = next
+				next++
+			} else {
+				removed[t] = append(removed[t], uint32(i))
+			}
+		}
+	}
+
+	for i := range bodies {
+		b := &bodies[i]
+		walkInstrs(b.body.Instructions, func(t cpoolTable, ref *uint32) {
+			if v := remap[t][*ref]; v != *ref {
+				*ref = v
+				b.changed = true
+			}
+		})
+		if err := b.rewrite(); err != nil {
+			return CpoolRemoved{}, err
+		}
+	}
+
+	rewrite := func(t cpoolTable, ref *uint32) {
+		*ref = remap[t][*ref]
+	}
+	abc.walkFile(rewrite)
+	for i := range bodies {
+		if !bodies[i].changed {
+			continue
+		}
+		body := &abc.MethodBodies[i]
+		exceptions := bodies[i].body.Exceptions
+		for j := range exceptions {
+			exceptions[j].ExcType = body.Exceptions[j].ExcType
+			exceptions[j].VarName = body.Exceptions[j].VarName
+		}
+		body.Code = bodies[i].body.Code
+		body.Instructions = bodies[i].body.Instructions
+		body.EndLabel = bodies[i].body.EndLabel
+		body.Exceptions = exceptions
+	}
+	abc.ConstantPool = c.compacted(m.live)
+	c = &abc.ConstantPool
+	for t := tableNamespace; t < tableCount; t++ {
+		for i := 1; i < c.tableLen(t); i++ {
+			c.walkEntry(t, uint32(i), rewrite)
+		}
+	}
+
+	return CpoolRemoved{
+		removed[tableInteger],
+		removed[tableUInteger],
+		removed[tableDouble],
+		removed[tableString],
+		removed[tableNamespace],
+		removed[tableNsSet],
+		removed[tableMultiname],
+	}, nil
+}
+
+// compacted returns a copy of the constant pool holding only the live
+// entries, whose references are not rewritten yet
+func (c *CpoolInfo) compacted(live [tableCount][]bool) CpoolInfo {
+	var n CpoolInfo
+	keep := func(t cpoolTable, add func(i int)) {
+		for i := range live[t] {
+			if i == 0 || live[t][i] {
+				add(i)
+			}
+		}
+	}
+	keep(tableInteger, func(i int) { n.Integers = append(n.Integers, c.Integers[i]) })
+	keep(tableUInteger, func(i int) { n.UIntegers = append(n.UIntegers, c.UIntegers[i]) })
+	keep(tableDouble, func(i int) { n.Doubles = append(n.Doubles, c.Doubles[i]) })
+	keep(tableString, func(i int) { n.Strings = append(n.Strings, c.Strings[i]) })
+	keep(tableNamespace, func(i int) { n.Namespaces = append(n.Namespaces, c.Namespaces[i]) })
+	keep(tableNsSet, func(i int) {
+		s := c.NsSets[i]
+		s.Namespaces = append([]uint32(nil), s.Namespaces...)
+		n.NsSets = append(n.NsSets, s)
+	})
+	keep(tableMultiname, func(i int) {
+		m := c.Multinames[i]
+		if m.Params != nil {
+			m.Params = append([]uint32(nil), m.Params...)
+		}
+		n.Multinames = append(n.Multinames, m)
+	})
+	return n
+}
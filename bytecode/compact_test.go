@@ -0,0 +1,153 @@
+package bytecode
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// resolvedOperands returns the resolved operands of the instructions of
+// every method body, with multinames formatted as strings
+func resolvedOperands(t *testing.T, a AbcFile) []string {
+	var operands []string
+	for i, body := range a.MethodBodies {
+		if err := body.Disassemble(); err != nil {
+			t.Fatalf("method_body %v: %v", i, err)
+		}
+		for _, instr := range body.Instructions {
+			for _, o := range instr.TypedOperands() {
+				v, err := o.Resolve(&a.ConstantPool)
+				if err != nil {
+					t.Fatalf("method_body %v: %v", i, err)
+				}
+				switch o.Type {
+				case OperandTypeMultiname:
+					v = a.ConstantPool.MultinameString(o.Value)
+				case OperandTypeNamespace:
+					v = a.ConstantPool.NamespaceString(o.Value)
+				}
+				operands = append(operands, fmt.Sprint(v))
+			}
+		}
+	}
+	return operands
+}
+
+func TestAbcFile_CompactCpool_Fixtures(t *testing.T) {
+	for _, name := range []string{"obf1", "obf2"} {
+		file := openFixture(t, name)
+		a, err := Parse(NewReader(file))
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if err = file.Close(); err != nil {
+			t.Errorf("file.close: %v", err)
+		}
+		before := resolvedOperands(t, a)
+
+		if _, err = a.CompactCpool(); err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if after := resolvedOperands(t, a); !reflect.DeepEqual(before, after) {
+			t.Errorf("%v: operands changed", name)
+		}
+
+		// compacting twice removes nothing
+		removed, err := a.CompactCpool()
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if !reflect.DeepEqual(removed, CpoolRemoved{}) {
+			t.Errorf("%v: expected nothing removed, got %v", name, removed)
+		}
+
+		buf := &bytes.Buffer{}
+		if err = Extract(buf, a); err != nil {
+			t.Fatalf("%v: Extract: %v", name, err)
+		}
+		if _, err = Parse(NewReader(buf)); err != nil {
+			t.Errorf("%v: Parse: %v", name, err)
+		}
+	}
+}
+
+func TestAbcFile_CompactCpool(t *testing.T) {
+	a := AbcFile{
+		ConstantPool: CpoolInfo{
+			Integers: []int32{0, 1, 2},
+			Strings:  []string{"", "dead", "foo", "bar"},
+			Namespaces: []NamespaceInfo{
+				{},
+				{NamespaceKindPackageNamespace, 1},
+				{NamespaceKindPackageNamespace, 3},
+			},
+			Multinames: []MultinameInfo{
+				{},
+				{Kind: MultinameKindQName, Namespace: 1, Name: 1},
+				{Kind: MultinameKindQName, Namespace: 2, Name: 2},
+			},
+		},
+		Methods: []MethodInfo{{ReturnType: 2}},
+		MethodBodies: []MethodBodyInfo{{
+			// pushint 2; getlex 2; returnvoid
+			Code: []byte{0x2d, 0x02, 0x60, 0x02, 0x47},
+		}},
+	}
+	removed, err := a.CompactCpool()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	want := CpoolRemoved{Integers: []uint32{1}, Strings: []uint32{1}, Namespaces: []uint32{1}, Multinames: []uint32{1}}
+	if !reflect.DeepEqual(removed, want) {
+		t.Errorf("expected %v, got %v", want, removed)
+	}
+	wantPool := CpoolInfo{
+		Integers:   []int32{0, 2},
+		Strings:    []string{"", "foo", "bar"},
+		Namespaces: []NamespaceInfo{{}, {NamespaceKindPackageNamespace, 2}},
+		Multinames: []MultinameInfo{{}, {Kind: MultinameKindQName, Namespace: 1, Name: 1}},
+	}
+	if !reflect.DeepEqual(a.ConstantPool, wantPool) {
+		t.Errorf("expected %v, got %v", wantPool, a.ConstantPool)
+	}
+	if a.Methods[0].ReturnType != 1 {
+		t.Errorf("expected 1, got %v", a.Methods[0].ReturnType)
+	}
+	if want := []byte{0x2d, 0x01, 0x60, 0x01, 0x47}; !bytes.Equal(a.MethodBodies[0].Code, want) {
+		t.Errorf("expected %x, got %x", want, a.MethodBodies[0].Code)
+	}
+}
+
+func TestAbcFile_CompactCpool_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		abc  AbcFile
+		err  error
+	}{
+		{
+			"out of range",
+			AbcFile{Methods: []MethodInfo{{ReturnType: 1}}},
+			ErrIndexOutOfRange,
+		},
+		{
+			"layout changed",
+			AbcFile{
+				ConstantPool: CpoolInfo{Multinames: make([]MultinameInfo, 201)},
+				MethodBodies: []MethodBodyInfo{{
+					// getlex 200; returnvoid, with a range starting inside getlex
+					Code:       []byte{0x60, 0xc8, 0x01, 0x47},
+					Exceptions: []ExceptionInfo{{From: 1, To: 3, Target: 3}},
+				}},
+			},
+			ErrCodeLayoutChanged,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.abc.CompactCpool(); err != tt.err {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package bytecode
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrStackUnderflow means that an instruction pops more values than the
+// operand stack holds
+var ErrStackUnderflow = errors.New("stack underflow")
+
+// ErrScopeUnderflow means that popscope is executed on an empty scope stack
+var ErrScopeUnderflow = errors.New("scope underflow")
+
+// ErrStackMismatch means that the paths reaching an instruction leave the
+// operand stack with different depths
+var ErrStackMismatch = errors.New("inconsistent stack depth")
+
+// ErrScopeMismatch means that the paths reaching an instruction leave the
+// scope stack with different depths
+var ErrScopeMismatch = errors.New("inconsistent scope depth")
+
+// ErrFallOffCode means that the execution can run past the last instruction
+var ErrFallOffCode = errors.New("fall off the end of the code")
+
+const (
+	throwCode       = 0x03
+	jumpCode        = 0x10
+	returnVoidCode  = 0x47
+	returnValueCode = 0x48
+)
+
+// IsTerminator reports whether the instruction never falls through to the
+// next one
+func (i Instr) IsTerminator() bool {
+	switch i.Model.Code {
+	case throwCode, jumpCode, lookupswitchCode, returnVoidCode, returnValueCode:
+		return true
+	}
+	return false
+}
+
+// DepthProblem is a problem found by AnalyzeDepths at an instruction
+type DepthProblem struct {
+	Instr int
+	Err   error
+}
+
+// DepthAnalysis is the depth of the operand and scope stacks along the
+// control flow of a method body. Offsets holds the offset of each
+// instruction followed by the length of the code. Stack and Scope hold the
+// depths before each instruction, or -1 for unreachable instructions.
+// MaxRegister is the highest register used, or -1 when the code uses none.
+type DepthAnalysis struct {
+	Offsets     []uint32
+	Stack       []int
+	Scope       []int
+	MaxStack    int
+	MaxScope    int
+	MaxRegister int
+	Problems    []DepthProblem
+}
+
+// depths is the depth of the operand and scope stacks at a point of the code
+type depths struct {
+	stack int
+	scope int
+}
+
+type depthWalker struct {
+	*DepthAnalysis
+	cpool    *CpoolInfo
+	indexes  map[uint32]int
+	reported []bool
+	worklist []int
+}
+
+func (w *depthWalker) problem(i int, err error) {
+	w.Problems = append(w.Problems, DepthProblem{i, err})
+}
+
+func (w *depthWalker) indexOf(offset int64) (int, error) {
+	i, ok := w.indexes[uint32(offset)]
+	if !ok || offset < 0 || i >= len(w.Stack) {
+		return 0, ErrInvalidBranchTarget
+	}
+	return i, nil
+}
+
+// enter merges the depths reaching an instruction, queuing it when it is
+// reached for the first time. A mismatch is reported once per instruction.
+func (w *depthWalker) enter(i int, d depths) {
+	if w.Stack[i] < 0 {
+		w.Stack[i], w.Scope[i] = d.stack, d.scope
+		w.worklist = append(w.worklist, i)
+		return
+	}
+	if (depths{w.Stack[i], w.Scope[i]}) == d || w.reported[i] {
+		return
+	}
+	w.reported[i] = true
+	if w.Stack[i] != d.stack {
+		w.problem(i, ErrStackMismatch)
+	} else {
+		w.problem(i, ErrScopeMismatch)
+	}
+}
+
+// step interprets an instruction, returning the depths after it. Pops past
+// the bottom of a stack leave it empty.
+func (w *depthWalker) step(i int, instr Instr) depths {
+	d := depths{w.Stack[i], w.Scope[i]}
+	for _, r := range instr.Registers() {
+		if int(r) > w.MaxRegister {
+			w.MaxRegister = int(r)
+		}
+	}
+	pop, push, err := instr.StackEffect(w.cpool)
+	if err != nil {
+		w.problem(i, err)
+	}
+	if d.stack < pop {
+		w.problem(i, ErrStackUnderflow)
+		pop = d.stack
+	}
+	d.stack += push - pop
+	if d.stack > w.MaxStack {
+		w.MaxStack = d.stack
+	}
+	pop, push = instr.ScopeEffect()
+	if d.scope < pop {
+		w.problem(i, ErrScopeUnderflow)
+		pop = d.scope
+	}
+	d.scope += push - pop
+	if d.scope > w.MaxScope {
+		w.MaxScope = d.scope
+	}
+	return d
+}
+
+// AnalyzeDepths interprets the disassembled instructions of a method body,
+// following the depths of the stacks along the branches and into the
+// exception handlers. The stacks are empty when the body starts, and an
+// exception handler starts with the exception alone on the operand stack
+// and an empty scope stack. Exception ranges that do not start or end on an
+// instruction cover the instructions starting inside them.
+//
+// Errors are returned for branches and handlers that do not point to an
+// instruction; problems of the code itself are listed in the analysis, in
+// instruction order.
+func (m *MethodBodyInfo) AnalyzeDepths(c *CpoolInfo) (*DepthAnalysis, error) {
+	offsets, err := m.Offsets()
+	if err != nil {
+		return nil, err
+	}
+	n := len(m.Instructions)
+	w := depthWalker{
+		DepthAnalysis: &DepthAnalysis{Offsets: offsets, Stack: make([]int, n), Scope: make([]int, n), MaxRegister: -1},
+		cpool:         c,
+		indexes:       make(map[uint32]int, len(offsets)),
+		reported:      make([]bool, n),
+	}
+	for i, offset := range offsets {
+		w.indexes[offset] = i
+	}
+	for i := range w.Stack {
+		w.Stack[i], w.Scope[i] = -1, -1
+	}
+	handlers := make([]int, len(m.Exceptions))
+	for i, e := range m.Exceptions {
+		if handlers[i], err = w.indexOf(int64(e.Target)); err != nil {
+			return nil, err
+		}
+	}
+
+	if n > 0 {
+		w.enter(0, depths{})
+	}
+	for len(w.worklist) > 0 {
+		i := w.worklist[len(w.worklist)-1]
+		w.worklist = w.worklist[:len(w.worklist)-1]
+		instr := m.Instructions[i]
+		d := w.step(i, instr)
+
+		for _, offset := range instr.BranchTargets(offsets[i], offsets[i+1]) {
+			target, err := w.indexOf(offset)
+			if err != nil {
+				return nil, err
+			}
+			w.enter(target, d)
+		}
+		if !instr.IsTerminator() {
+			if i+1 < n {
+				w.enter(i+1, d)
+			} else {
+				w.problem(i, ErrFallOffCode)
+			}
+		}
+		for j, e := range m.Exceptions {
+			if offsets[i] >= e.From && offsets[i] < e.To {
+				w.enter(handlers[j], depths{1, 0})
+				if w.MaxStack < 1 {
+					w.MaxStack = 1
+				}
+			}
+		}
+	}
+	sort.SliceStable(w.Problems, func(i, j int) bool { return w.Problems[i].Instr < w.Problems[j].Instr })
+	return w.DepthAnalysis, nil
+}
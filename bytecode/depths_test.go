@@ -0,0 +1,33 @@
+package bytecode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMethodBodyInfo_AnalyzeDepths(t *testing.T) {
+	// pushtrue; iffalse L; pushnull; L: popscope; pushnull; pop
+	body := MethodBodyInfo{Code: []byte{0x26, 0x12, 0x01, 0x00, 0x00, 0x20, 0x1d, 0x20, 0x29}}
+	if err := body.Disassemble(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	a, err := body.AnalyzeDepths(&CpoolInfo{})
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if want := []int{0, 1, 0, 0, 0, 1}; !reflect.DeepEqual(a.Stack, want) {
+		t.Errorf("expected %v, got %v", want, a.Stack)
+	}
+	want := []DepthProblem{{3, ErrStackMismatch}, {3, ErrScopeUnderflow}, {5, ErrFallOffCode}}
+	if !reflect.DeepEqual(a.Problems, want) {
+		t.Errorf("expected %v, got %v", want, a.Problems)
+	}
+	if a.MaxStack != 1 || a.MaxScope != 0 || a.MaxRegister != -1 {
+		t.Errorf("expected 1, 0 and -1, got %v, %v and %v", a.MaxStack, a.MaxScope, a.MaxRegister)
+	}
+
+	body.Exceptions = []ExceptionInfo{{From: 0, To: 1, Target: 2}}
+	if _, err = body.AnalyzeDepths(&CpoolInfo{}); err != ErrInvalidBranchTarget {
+		t.Errorf("expected %v, got %v", ErrInvalidBranchTarget, err)
+	}
+}
@@ -1,12 +1,25 @@
 package bytecode
 
 import "io"
-import "fmt"
 
 type extractor struct {
 	w   Writer
 	ex  *extractWriter
 	abc AbcFile
+
+	// scope depths on entry of the method bodies whose limits are
+	// recomputed, by body index
+	initScopes map[int]uint32
+}
+
+// ExtractOptions changes how ExtractWithOptions serializes an AbcFile.
+// RecomputeLimits lists the indexes of the modified method bodies: each is
+// assembled, from its Instructions or else from its Code, and its limits
+// recomputed (see InitScopeLengths and ComputeLimits) before being written.
+// The other bodies are written as they are and the AbcFile itself is left
+// untouched.
+type ExtractOptions struct {
+	RecomputeLimits []int
 }
 
 type extractWriter struct {
@@ -27,8 +40,23 @@ func (w *extractWriter) Write(b []byte) (int, error) {
 
 // Extract is used to serialize an AbcFile
 func Extract(w io.Writer, abc AbcFile) error {
+	return ExtractWithOptions(w, abc, ExtractOptions{})
+}
+
+// ExtractWithOptions serializes an AbcFile like Extract, with options
+func ExtractWithOptions(w io.Writer, abc AbcFile, opts ExtractOptions) error {
 	wrappedWriter := &extractWriter{w: w}
-	ex := extractor{NewWriter(wrappedWriter), wrappedWriter, abc}
+	ex := extractor{NewWriter(wrappedWriter), wrappedWriter, abc, nil}
+	if len(opts.RecomputeLimits) > 0 {
+		lengths := abc.InitScopeLengths()
+		ex.initScopes = make(map[int]uint32, len(opts.RecomputeLimits))
+		for _, i := range opts.RecomputeLimits {
+			if i < 0 || i >= len(lengths) {
+				return ErrIndexOutOfRange
+			}
+			ex.initScopes[i] = lengths[i]
+		}
+	}
 	ex.Extract()
 	return wrappedWriter.err
 }
@@ -37,15 +65,10 @@ func (e *extractor) Extract() {
 	e.w.WriteU16(e.abc.MinorVersion)
 	e.w.WriteU16(e.abc.MajorVersion)
 	e.extractCpool()
-	fmt.Printf("cpool end: %x\n", e.ex.n)
 	e.extractMethods()
-	fmt.Printf("methods end: %x\n", e.ex.n)
 	e.extractMetadatas()
-	fmt.Printf("metadatas end: %x\n", e.ex.n)
 	e.extractInstancesClasses()
-	fmt.Printf("instances/classes end: %x\n", e.ex.n)
 	e.extractScripts()
-	fmt.Printf("scripts end: %x\n", e.ex.n)
 	e.extractMethodBodies()
 }
 
@@ -333,7 +356,31 @@ func (e *extractor) extractExceptionInfo(v ExceptionInfo) {
 	e.w.WriteU30(v.VarName)
 }
 
-func (e *extractor) extractMethodBody(v MethodBodyInfo) {
+// prepareMethodBody assembles a modified method body and recomputes its
+// limits
+func (e *extractor) prepareMethodBody(v *MethodBodyInfo, initScope uint32) error {
+	if int64(v.Method) >= int64(len(e.abc.Methods)) {
+		return ErrIndexOutOfRange
+	}
+	if v.Instructions == nil {
+		if err := v.Disassemble(); err != nil {
+			return err
+		}
+	}
+	if err := v.Assemble(); err != nil {
+		return err
+	}
+	v.InitScopeLength = initScope
+	return v.ComputeLimits(&e.abc.ConstantPool, e.abc.Methods[v.Method])
+}
+
+func (e *extractor) extractMethodBody(i int, v MethodBodyInfo) {
+	if initScope, ok := e.initScopes[i]; ok && e.ex.err == nil {
+		if err := e.prepareMethodBody(&v, initScope); err != nil {
+			e.ex.err = err
+			return
+		}
+	}
 	e.w.WriteU30(v.Method)
 	e.w.WriteU30(v.MaxStack)
 	e.w.WriteU30(v.LocalCount)
@@ -352,7 +399,7 @@ func (e *extractor) extractMethodBody(v MethodBodyInfo) {
 func (e *extractor) extractMethodBodies() {
 	methodBodies := e.abc.MethodBodies
 	e.w.WriteU30(uint32(len(methodBodies)))
-	for _, methodBody := range methodBodies {
-		e.extractMethodBody(methodBody)
+	for i, methodBody := range methodBodies {
+		e.extractMethodBody(i, methodBody)
 	}
 }
@@ -0,0 +1,247 @@
+package bytecode
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Label names a position in the instructions of a method body.
+// The zero Label names no position.
+type Label uint32
+
+// ErrInvalidBranchTarget means that a branch or an exception offset does not
+// point to the start of an instruction
+var ErrInvalidBranchTarget = errors.New("invalid branch target")
+
+// ErrUnknownLabel means that a branch or an exception refers to a label
+// carried by no instruction
+var ErrUnknownLabel = errors.New("unknown label")
+
+const lookupswitchCode = 0x1b
+
+// branchOperands returns the indexes of the operands of instr that are
+// branch offsets
+func branchOperands(instr Instr) []int {
+	var indexes []int
+	if instr.Model.Code == lookupswitchCode {
+		for i := range instr.Operands {
+			indexes = append(indexes, i)
+		}
+		return indexes
+	}
+	for i, t := range instr.Model.Operands {
+		if t == InstrOperandS24 {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// branchBase returns the offset the branch operands of an instruction are
+// relative to: the instruction itself for lookupswitch, the next one otherwise
+func branchBase(instr Instr, offset, next uint32) uint32 {
+	if instr.Model.Code == lookupswitchCode {
+		return offset
+	}
+	return next
+}
+
+// instrOffsets returns the offset of each instruction followed by the
+// length of the code
+func instrOffsets(instrs []Instr) ([]uint32, error) {
+	offsets := make([]uint32, len(instrs)+1)
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	for i, instr := range instrs {
+		offsets[i] = uint32(buf.Len())
+		if err := assembleInstr(w, instr); err != nil {
+			return nil, err
+		}
+	}
+	offsets[len(instrs)] = uint32(buf.Len())
+	return offsets, nil
+}
+
+// Offsets returns the offset of each instruction of the method body followed
+// by the length of the assembled code
+func (m *MethodBodyInfo) Offsets() ([]uint32, error) {
+	return instrOffsets(m.Instructions)
+}
+
+// BranchTargets returns the offsets the branch operands of the instruction
+// point to, in operand order, given the offset of the instruction and of the
+// next one. Offsets are computed from the operands, not from the Targets.
+func (i Instr) BranchTargets(offset, next uint32) []int64 {
+	var targets []int64
+	base := int64(branchBase(i, offset, next))
+	for _, operand := range branchOperands(i) {
+		targets = append(targets, base+int64(int32(i.Operands[operand])))
+	}
+	return targets
+}
+
+func (m *MethodBodyInfo) maxLabel() Label {
+	max := m.EndLabel
+	for _, instr := range m.Instructions {
+		if instr.Label > max {
+			max = instr.Label
+		}
+	}
+	return max
+}
+
+// NewLabel returns a label carried by no instruction of the method body
+func (m *MethodBodyInfo) NewLabel() Label {
+	return m.maxLabel() + 1
+}
+
+// LabelAt returns the label of the instruction at index, or the label of the
+// end of the code when index is len(Instructions). A new label is attached
+// to the position if it has none.
+func (m *MethodBodyInfo) LabelAt(index int) Label {
+	if index == len(m.Instructions) {
+		if m.EndLabel == 0 {
+			m.EndLabel = m.NewLabel()
+		}
+		return m.EndLabel
+	}
+	if m.Instructions[index].Label == 0 {
+		m.Instructions[index].Label = m.NewLabel()
+	}
+	return m.Instructions[index].Label
+}
+
+// ResolveLabels converts the branch offsets and the exception offsets of the
+// disassembled instructions to labels, so that instructions can be inserted
+// or removed without breaking them. Assemble then recomputes the offsets.
+func (m *MethodBodyInfo) ResolveLabels() error {
+	offsets, err := instrOffsets(m.Instructions)
+	if err != nil {
+		return err
+	}
+	indexes := make(map[uint32]int, len(offsets))
+	labels := make([]Label, len(offsets))
+	for i, offset := range offsets {
+		indexes[offset] = i
+	}
+	for i, instr := range m.Instructions {
+		labels[i] = instr.Label
+	}
+	labels[len(m.Instructions)] = m.EndLabel
+
+	next := m.NewLabel()
+	labelAt := func(offset int64) (Label, error) {
+		i, ok := indexes[uint32(offset)]
+		if offset < 0 || !ok {
+			return 0, ErrInvalidBranchTarget
+		}
+		if labels[i] == 0 {
+			labels[i] = next
+			next++
+		}
+		return labels[i], nil
+	}
+
+	targets := make([][]Label, len(m.Instructions))
+	for i, instr := range m.Instructions {
+		for _, target := range instr.BranchTargets(offsets[i], offsets[i+1]) {
+			l, err := labelAt(target)
+			if err != nil {
+				return err
+			}
+			targets[i] = append(targets[i], l)
+		}
+	}
+	exceptions := make([]ExceptionInfo, len(m.Exceptions))
+	for i, e := range m.Exceptions {
+		if e.FromLabel, err = labelAt(int64(e.From)); err != nil {
+			return err
+		}
+		if e.ToLabel, err = labelAt(int64(e.To)); err != nil {
+			return err
+		}
+		if e.TargetLabel, err = labelAt(int64(e.Target)); err != nil {
+			return err
+		}
+		exceptions[i] = e
+	}
+
+	for i := range m.Instructions {
+		m.Instructions[i].Label = labels[i]
+		m.Instructions[i].Targets = targets[i]
+	}
+	m.EndLabel = labels[len(m.Instructions)]
+	m.Exceptions = exceptions
+	return nil
+}
+
+// layoutLabels returns the instructions of the method body with their branch
+// operands computed from their targets, and updates the exception offsets
+func (m *MethodBodyInfo) layoutLabels() ([]Instr, error) {
+	instrs := make([]Instr, len(m.Instructions))
+	for i, instr := range m.Instructions {
+		if instr.Targets != nil {
+			if instr.Model.Code == lookupswitchCode {
+				instr.Operands = make([]uint32, len(instr.Targets))
+			} else {
+				instr.Operands = append([]uint32(nil), instr.Operands...)
+				if len(branchOperands(instr)) != len(instr.Targets) {
+					return nil, ErrInstructionOperandCount
+				}
+			}
+		}
+		instrs[i] = instr
+	}
+	offsets, err := instrOffsets(instrs)
+	if err != nil {
+		return nil, err
+	}
+	positions := map[Label]uint32{}
+	for i, instr := range instrs {
+		if instr.Label != 0 {
+			positions[instr.Label] = offsets[i]
+		}
+	}
+	if m.EndLabel != 0 {
+		positions[m.EndLabel] = offsets[len(instrs)]
+	}
+	positionOf := func(l Label, offset uint32) (uint32, error) {
+		if l == 0 {
+			return offset, nil
+		}
+		p, ok := positions[l]
+		if !ok {
+			return 0, ErrUnknownLabel
+		}
+		return p, nil
+	}
+
+	for i, instr := range instrs {
+		if instr.Targets == nil {
+			continue
+		}
+		base := branchBase(instr, offsets[i], offsets[i+1])
+		for j, operand := range branchOperands(instr) {
+			p, ok := positions[instr.Targets[j]]
+			if !ok {
+				return nil, ErrUnknownLabel
+			}
+			instr.Operands[operand] = uint32(int32(p - base))
+		}
+	}
+	exceptions := make([]ExceptionInfo, len(m.Exceptions))
+	for i, e := range m.Exceptions {
+		if e.From, err = positionOf(e.FromLabel, e.From); err != nil {
+			return nil, err
+		}
+		if e.To, err = positionOf(e.ToLabel, e.To); err != nil {
+			return nil, err
+		}
+		if e.Target, err = positionOf(e.TargetLabel, e.Target); err != nil {
+			return nil, err
+		}
+		exceptions[i] = e
+	}
+	m.Exceptions = exceptions
+	return instrs, nil
+}
@@ -0,0 +1,225 @@
+package bytecode
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMethodBodyInfo_ResolveLabels(t *testing.T) {
+	for _, name := range []string{"obf1", "obf2"} {
+		file := openFixture(t, name)
+		a, err := Parse(NewReader(file))
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if err = file.Close(); err != nil {
+			t.Errorf("file.close: %v", err)
+		}
+
+		resolved := 0
+		for i, body := range a.MethodBodies {
+			code := body.Code
+			exceptions := append([]ExceptionInfo(nil), body.Exceptions...)
+			if err = body.Disassemble(); err != nil {
+				t.Fatalf("%v: method_body %v: %v", name, i, err)
+			}
+			// obfuscated bodies may have exception ranges in the middle
+			// of instructions
+			if err = body.ResolveLabels(); err == ErrInvalidBranchTarget {
+				continue
+			} else if err != nil {
+				t.Fatalf("%v: method_body %v: %v", name, i, err)
+			}
+			resolved++
+			if err = body.Assemble(); err != nil {
+				t.Fatalf("%v: method_body %v: %v", name, i, err)
+			}
+			if !bytes.Equal(body.Code, code) {
+				t.Errorf("%v: method_body %v: expected %v, got %v", name, i, code, body.Code)
+			}
+
+			// a leading nop shifts every exception offset by one byte
+			nop := Instr{Model: Instructions[0x02]}
+			body.Instructions = append([]Instr{nop}, body.Instructions...)
+			if err = body.Assemble(); err != nil {
+				t.Fatalf("%v: method_body %v: %v", name, i, err)
+			}
+			for j, e := range body.Exceptions {
+				want := exceptions[j]
+				if e.From != want.From+1 || e.To != want.To+1 || e.Target != want.Target+1 {
+					t.Errorf("%v: method_body %v: expected %v, got %v", name, i, want, e)
+				}
+			}
+			if !bytes.Equal(body.Code[1:], code) {
+				t.Errorf("%v: method_body %v: expected %v, got %v", name, i, code, body.Code[1:])
+			}
+		}
+		if resolved == 0 {
+			t.Errorf("%v: expected resolved method bodies, got none", name)
+		}
+	}
+}
+
+func TestMethodBodyInfo_Assemble_Labels(t *testing.T) {
+	jump := Instructions[0x10]
+	iftrue := Instructions[0x11]
+	pushtrue := Instructions[0x26]
+	pushstring := Instructions[0x2c]
+	returnvoid := Instructions[0x47]
+	lookupswitch := Instructions[0x1b]
+
+	tests := []struct {
+		name    string
+		body    MethodBodyInfo
+		want    []byte
+		wantErr error
+	}{
+		{
+			"forward",
+			MethodBodyInfo{Instructions: []Instr{
+				{Model: pushtrue},
+				{Model: iftrue, Operands: []uint32{0}, Targets: []Label{1}},
+				{Model: pushstring, Operands: []uint32{300}},
+				{Model: returnvoid, Label: 1},
+			}},
+			[]byte{0x26, 0x11, 0x03, 0x00, 0x00, 0x2c, 0xac, 0x02, 0x47},
+			nil,
+		},
+		{
+			"backward",
+			MethodBodyInfo{Instructions: []Instr{
+				{Model: pushtrue, Label: 7},
+				{Model: jump, Operands: []uint32{0}, Targets: []Label{7}},
+			}},
+			[]byte{0x26, 0x10, 0xfb, 0xff, 0xff},
+			nil,
+		},
+		{
+			"lookupswitch",
+			MethodBodyInfo{Instructions: []Instr{
+				{Model: lookupswitch, Label: 1, Targets: []Label{2, 1, 3}},
+				{Model: returnvoid, Label: 2},
+			}, EndLabel: 3},
+			[]byte{0x1b, 0x0b, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x0c, 0x00, 0x00, 0x47},
+			nil,
+		},
+		{
+			"unknown label",
+			MethodBodyInfo{Instructions: []Instr{
+				{Model: jump, Operands: []uint32{0}, Targets: []Label{1}},
+			}},
+			nil,
+			ErrUnknownLabel,
+		},
+		{
+			"missing target",
+			MethodBodyInfo{Instructions: []Instr{
+				{Model: jump, Operands: []uint32{0}, Targets: []Label{}},
+			}},
+			nil,
+			ErrInstructionOperandCount,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.body
+			err := m.Assemble()
+			if err != tt.wantErr {
+				t.Fatalf("MethodBodyInfo.Assemble() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !bytes.Equal(m.Code, tt.want) {
+				t.Errorf("MethodBodyInfo.Assemble() = %v, want %v", m.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestMethodBodyInfo_Assemble_Operands(t *testing.T) {
+	m := MethodBodyInfo{Instructions: []Instr{
+		{Model: Instructions[0x26]},
+		{Model: Instructions[0x11], Operands: []uint32{0}},
+		{Model: Instructions[0x02]},
+		{Model: Instructions[0x47]},
+	}}
+	if err := m.Assemble(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	m.Instructions[1].Operands[0] = 1
+	if err := m.ResolveLabels(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	nops := []Instr{{Model: Instructions[0x02]}, {Model: Instructions[0x02]}}
+	m.Instructions = append(m.Instructions[:2], append(nops, m.Instructions[2:]...)...)
+	if err := m.Assemble(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	offsets, err := m.Offsets()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	want := []int64{int64(offsets[5])}
+	if got := m.Instructions[1].BranchTargets(offsets[1], offsets[2]); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMethodBodyInfo_Assemble_Exceptions(t *testing.T) {
+	m := MethodBodyInfo{
+		Instructions: []Instr{
+			{Model: Instructions[0x26]},
+			{Model: Instructions[0x29], Label: 1},
+			{Model: Instructions[0x47], Label: 2},
+		},
+		Exceptions: []ExceptionInfo{{ExcType: 4, FromLabel: 1, ToLabel: 2, TargetLabel: 3}},
+		EndLabel:   3,
+	}
+	m.Instructions = append([]Instr{{Model: Instructions[0x24], Operands: []uint32{1}}}, m.Instructions...)
+	if err := m.Assemble(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	want := ExceptionInfo{3, 4, 5, 4, 0, 1, 2, 3}
+	if !reflect.DeepEqual(m.Exceptions[0], want) {
+		t.Errorf("expected %v, got %v", want, m.Exceptions[0])
+	}
+}
+
+func TestMethodBodyInfo_LabelAt(t *testing.T) {
+	m := MethodBodyInfo{Instructions: []Instr{
+		{Model: Instructions[0x26], Label: 4},
+		{Model: Instructions[0x47]},
+	}}
+	tests := []struct {
+		name  string
+		index int
+		want  Label
+	}{
+		{"existing", 0, 4},
+		{"new", 1, 5},
+		{"same", 1, 5},
+		{"end", 2, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.LabelAt(tt.index); got != tt.want {
+				t.Errorf("MethodBodyInfo.LabelAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if m.EndLabel != 6 {
+		t.Errorf("expected 6, got %v", m.EndLabel)
+	}
+}
+
+func TestMethodBodyInfo_ResolveLabels_InvalidTarget(t *testing.T) {
+	m := MethodBodyInfo{Code: []byte{0x10, 0x01, 0x00, 0x00, 0x2c, 0xac, 0x02}}
+	if err := m.Disassemble(); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := m.ResolveLabels(); err != ErrInvalidBranchTarget {
+		t.Errorf("expected %v, got %v", ErrInvalidBranchTarget, err)
+	}
+}
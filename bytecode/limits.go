@@ -0,0 +1,159 @@
+package bytecode
+
+const (
+	newfunctionCode = 0x40
+	newclassCode    = 0x58
+)
+
+// ComputeLimits recomputes MaxStack, LocalCount and MaxScopeLength from the
+// instructions of the method body and the signature of its method, so that
+// they hold after code was injected or removed.
+//
+// The depths of the stacks are those found by AnalyzeDepths. LocalCount
+// covers the receiver, the parameters, the rest or arguments array and every
+// register used by the instructions.
+// InitScopeLength depends on where the method is defined, it is computed for
+// the whole file by InitScopeLengths: MaxScopeLength is set to
+// InitScopeLength plus the deepest scope stack.
+//
+// Branch targets and exceptions are taken from the labels when the
+// instructions carry them (see ResolveLabels).
+func (m *MethodBodyInfo) ComputeLimits(c *CpoolInfo, method MethodInfo) error {
+	laidOut := *m
+	instrs, err := laidOut.layoutLabels()
+	if err != nil {
+		return err
+	}
+	laidOut.Instructions = instrs
+	a, err := laidOut.AnalyzeDepths(c)
+	if err != nil {
+		return err
+	}
+	// the depths are clamped on underflows and the first path reaching an
+	// instruction wins, only the instructions of unknown effect are fatal
+	for _, p := range a.Problems {
+		switch p.Err {
+		case ErrStackUnderflow, ErrScopeUnderflow, ErrStackMismatch, ErrScopeMismatch, ErrFallOffCode:
+		default:
+			return p.Err
+		}
+	}
+
+	locals := int(method.ParamCount) + 1
+	if method.Flags&(MethodNeedRest|MethodNeedArguments) != 0 {
+		locals++
+	}
+	if a.MaxRegister >= locals {
+		locals = a.MaxRegister + 1
+	}
+	m.MaxStack = uint32(a.MaxStack)
+	m.LocalCount = uint32(locals)
+	m.MaxScopeLength = m.InitScopeLength + uint32(a.MaxScope)
+	return nil
+}
+
+// InitScopeLengths returns the scope depth on entry of each method body,
+// following the depth of the scope stack from the script initializers into
+// the methods they define:
+//
+//   - a function created by newfunction starts with the scopes of the body
+//     creating it,
+//   - the static initializer of a class created by newclass starts with the
+//     scopes of the body creating it, its constructor and its methods with
+//     the class object on top of them,
+//   - the methods defined by the traits of a script start with the scopes of
+//     the script initializer.
+//
+// Script initializers, and the bodies no initializer leads to, keep their
+// InitScopeLength, as the bodies defined by code that cannot be analyzed. A
+// method defined twice keeps the first depth found.
+func (a *AbcFile) InitScopeLengths() []uint32 {
+	lengths := make([]uint32, len(a.MethodBodies))
+	bodies := make(map[uint32]int, len(a.MethodBodies))
+	for i, b := range a.MethodBodies {
+		lengths[i] = b.InitScopeLength
+		bodies[b.Method] = i
+	}
+	visited := make([]bool, len(a.MethodBodies))
+	var worklist []int
+	define := func(method uint32, length uint32) {
+		if i, ok := bodies[method]; ok && !visited[i] {
+			visited[i] = true
+			lengths[i] = length
+			worklist = append(worklist, i)
+		}
+	}
+	for _, s := range a.Scripts {
+		if i, ok := bodies[s.Init]; ok {
+			define(s.Init, lengths[i])
+			for _, m := range traitMethods(s.Traits) {
+				define(m, lengths[i])
+			}
+		}
+	}
+
+	for len(worklist) > 0 {
+		i := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		body := a.MethodBodies[i]
+		d, err := body.scopeDepths(&a.ConstantPool)
+		if err != nil {
+			continue
+		}
+		for j, instr := range body.Instructions {
+			if d.Scope[j] < 0 || len(instr.Operands) == 0 {
+				continue
+			}
+			length := lengths[i] + uint32(d.Scope[j])
+			switch index := instr.Operands[0]; instr.Model.Code {
+			case newfunctionCode:
+				define(index, length)
+			case newclassCode:
+				if int64(index) >= int64(len(a.Classes)) || int64(index) >= int64(len(a.Instances)) {
+					continue
+				}
+				define(a.Classes[index].CInit, length)
+				define(a.Instances[index].IInit, length+1)
+				for _, m := range traitMethods(a.Instances[index].Traits) {
+					define(m, length+1)
+				}
+				for _, m := range traitMethods(a.Classes[index].Traits) {
+					define(m, length+1)
+				}
+			}
+		}
+	}
+	return lengths
+}
+
+// scopeDepths lays out or disassembles the instructions of a copy of the
+// method body and analyzes their depths. The receiver holds the
+// instructions analyzed.
+func (m *MethodBodyInfo) scopeDepths(c *CpoolInfo) (*DepthAnalysis, error) {
+	if m.Instructions == nil {
+		if err := m.Disassemble(); err != nil {
+			return nil, err
+		}
+	} else {
+		instrs, err := m.layoutLabels()
+		if err != nil {
+			return nil, err
+		}
+		m.Instructions = instrs
+	}
+	return m.AnalyzeDepths(c)
+}
+
+// traitMethods returns the indexes of the methods defined by traits
+func traitMethods(traits []TraitsInfo) []uint32 {
+	var methods []uint32
+	for _, t := range traits {
+		switch t.GetType() {
+		case TraitsInfoMethod, TraitsInfoGetter, TraitsInfoSetter:
+			methods = append(methods, t.Method)
+		case TraitsInfoFunction:
+			methods = append(methods, t.Function)
+		}
+	}
+	return methods
+}
@@ -0,0 +1,175 @@
+package bytecode
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMethodBodyInfo_ComputeLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     MethodInfo
+		body       MethodBodyInfo
+		maxStack   uint32
+		localCount uint32
+		maxScope   uint32
+		err        error
+	}{
+		{
+			"straight",
+			MethodInfo{ParamCount: 1},
+			MethodBodyInfo{
+				InitScopeLength: 3,
+				// getlocal_0; pushscope; pushbyte 1; pushbyte 2; add; setlocal 5; returnvoid
+				Code: []byte{0xd0, 0x30, 0x24, 0x01, 0x24, 0x02, 0xa0, 0x63, 0x05, 0x47},
+			},
+			2, 6, 4, nil,
+		},
+		{
+			"branch",
+			MethodInfo{Flags: MethodNeedRest},
+			MethodBodyInfo{
+				// pushtrue; iffalse L; pushnull; pushnull; pop; pop; L: returnvoid
+				Code: []byte{0x26, 0x12, 0x04, 0x00, 0x00, 0x20, 0x20, 0x29, 0x29, 0x47},
+			},
+			2, 2, 0, nil,
+		},
+		{
+			"exception handler",
+			MethodInfo{},
+			MethodBodyInfo{
+				MaxStack:   9,
+				LocalCount: 9,
+				// pushnull; pop; returnvoid; handler: pop; returnvoid
+				Code:       []byte{0x20, 0x29, 0x47, 0x29, 0x47},
+				Exceptions: []ExceptionInfo{{From: 0, To: 3, Target: 3}},
+			},
+			1, 1, 0, nil,
+		},
+		{
+			"invalid branch",
+			MethodInfo{},
+			MethodBodyInfo{
+				// jump +100
+				Code: []byte{0x10, 0x64, 0x00, 0x00},
+			},
+			0, 0, 0, ErrInvalidBranchTarget,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body
+			if err := body.Disassemble(); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			err := body.ComputeLimits(&CpoolInfo{}, tt.method)
+			if err != tt.err {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if body.MaxStack != tt.maxStack {
+				t.Errorf("expected max stack %v, got %v", tt.maxStack, body.MaxStack)
+			}
+			if body.LocalCount != tt.localCount {
+				t.Errorf("expected local count %v, got %v", tt.localCount, body.LocalCount)
+			}
+			if body.MaxScopeLength != tt.maxScope {
+				t.Errorf("expected max scope length %v, got %v", tt.maxScope, body.MaxScopeLength)
+			}
+		})
+	}
+}
+
+func TestExtractWithOptions_RecomputeLimits(t *testing.T) {
+	pushnull, pop := Instr{Model: Instructions[0x20]}, Instr{Model: Instructions[0x29]}
+	returnvoid := Instr{Model: Instructions[0x47]}
+	a := AbcFile{
+		Methods: []MethodInfo{{}},
+		MethodBodies: []MethodBodyInfo{
+			// injected code, the code and the limits are stale
+			{
+				Code:         []byte{0x47},
+				Instructions: []Instr{pushnull, pushnull, pop, pop, returnvoid},
+			},
+			// not disassembled, written as is
+			{MaxStack: 7, Code: []byte{0x47}},
+			// disassembled but not modified, written as is
+			{
+				MaxStack:     7,
+				Code:         []byte{0x47},
+				Instructions: []Instr{pushnull, pop, returnvoid},
+			},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := ExtractWithOptions(buf, a, ExtractOptions{RecomputeLimits: []int{0}}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if a.MethodBodies[0].MaxStack != 0 {
+		t.Errorf("expected the file untouched, got max stack %v", a.MethodBodies[0].MaxStack)
+	}
+	b, err := Parse(NewReader(buf))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if want := []byte{0x20, 0x20, 0x29, 0x29, 0x47}; !bytes.Equal(b.MethodBodies[0].Code, want) {
+		t.Errorf("expected %x, got %x", want, b.MethodBodies[0].Code)
+	}
+	if b.MethodBodies[0].MaxStack != 2 || b.MethodBodies[0].LocalCount != 1 {
+		t.Errorf("expected 2, 1, got %v, %v", b.MethodBodies[0].MaxStack, b.MethodBodies[0].LocalCount)
+	}
+	for _, i := range []int{1, 2} {
+		if b.MethodBodies[i].MaxStack != 7 || !bytes.Equal(b.MethodBodies[i].Code, []byte{0x47}) {
+			t.Errorf("body %v: expected 7, 47, got %v, %x", i, b.MethodBodies[i].MaxStack, b.MethodBodies[i].Code)
+		}
+	}
+
+	if err := ExtractWithOptions(&bytes.Buffer{}, a, ExtractOptions{RecomputeLimits: []int{3}}); err != ErrIndexOutOfRange {
+		t.Errorf("expected %v, got %v", ErrIndexOutOfRange, err)
+	}
+	a.MethodBodies[0].Method = 1
+	if err := ExtractWithOptions(&bytes.Buffer{}, a, ExtractOptions{RecomputeLimits: []int{0}}); err != ErrIndexOutOfRange {
+		t.Errorf("expected %v, got %v", ErrIndexOutOfRange, err)
+	}
+}
+
+func TestAbcFile_InitScopeLengths(t *testing.T) {
+	body := func(method uint32, code ...byte) MethodBodyInfo {
+		return MethodBodyInfo{Method: method, InitScopeLength: 9, Code: code}
+	}
+	script := body(0,
+		0xd0,       // getlocal_0
+		0x30,       // pushscope
+		0x40, 0x01, // newfunction 1
+		0x29,       // pop
+		0x20,       // pushnull
+		0x58, 0x00, // newclass 0
+		0x29, // pop
+		0x47, // returnvoid
+	)
+	script.InitScopeLength = 1
+	a := AbcFile{
+		Methods:   make([]MethodInfo, 8),
+		Instances: []InstanceInfo{{IInit: 3, Traits: []TraitsInfo{{Kind: TraitsInfoMethod, Method: 4}}}},
+		Classes:   []ClassInfo{{CInit: 2, Traits: []TraitsInfo{{Kind: TraitsInfoGetter, Method: 5}}}},
+		Scripts:   []ScriptInfo{{Init: 0, Traits: []TraitsInfo{{Kind: TraitsInfoFunction, Function: 6}}}},
+		MethodBodies: []MethodBodyInfo{
+			script,
+			body(1, 0x47),
+			body(2, 0x47),
+			body(3, 0x47),
+			body(4, 0x47),
+			body(5, 0x47),
+			body(6, 0x47),
+			body(7, 0x47),
+		},
+	}
+	want := []uint32{1, 2, 2, 3, 3, 3, 1, 9}
+	if got := a.InitScopeLengths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -25,9 +25,15 @@ type InstrModel struct {
 
 // Instr represents a disassembled avm2 instruction.
 // Since all the operands are at most 30 bits, operands are stored as uint32.
+//
+// Label names the instruction so that branches and exception ranges can refer
+// to it. Targets holds the labels of the branch destinations in operand
+// order; when set, the branch operands are recomputed by Assemble.
 type Instr struct {
 	Model    InstrModel
 	Operands []uint32
+	Label    Label
+	Targets  []Label
 }
 
 // ErrUnknownInstruction means that an invalid instruction code was read
@@ -38,34 +44,51 @@ var ErrUnknownInstruction = errors.New("unknown instruction")
 // was found in the Instructions map
 var ErrUnknownInstructionOperand = errors.New("unknown instruction operand")
 
+// ErrInstructionOperandCount means that an instruction does not have the
+// number of operands required by its model
+var ErrInstructionOperandCount = errors.New("invalid instruction operand count")
+
 // Instructions is a map of Instruction. Each key represents the instruction code
 var Instructions = map[uint8]InstrModel{
 	0xa0: {0xa0, "add", nil},
 	0xc5: {0xc5, "add_i", nil},
+	0xb5: {0xb5, "add_p", []InstrOperand{InstrOperandU30}},
+	0x53: {0x53, "applytype", []InstrOperand{InstrOperandU30}},
 	0x86: {0x86, "astype", []InstrOperand{InstrOperandU30}},
 	0x87: {0x87, "astypelate", nil},
 	0xa8: {0xa8, "bitand", nil},
 	0x97: {0x97, "bitnot", nil},
 	0xa9: {0xa9, "bitor", nil},
 	0xaa: {0xaa, "bitxor", nil},
+	0x01: {0x01, "bkpt", nil},
+	0xf2: {0xf2, "bkptline", []InstrOperand{InstrOperandU30}},
 	0x41: {0x41, "call", []InstrOperand{InstrOperandU30}},
+	0x4d: {0x4d, "callinterface", nil},
 	0x43: {0x43, "callmethod", []InstrOperand{InstrOperandU30, InstrOperandU30}},
 	0x46: {0x46, "callproperty", []InstrOperand{InstrOperandU30, InstrOperandU30}},
 	0x4c: {0x4c, "callproplex", []InstrOperand{InstrOperandU30, InstrOperandU30}},
 	0x4f: {0x4f, "callpropvoid", []InstrOperand{InstrOperandU30, InstrOperandU30}},
 	0x44: {0x44, "callstatic", []InstrOperand{InstrOperandU30, InstrOperandU30}},
 	0x45: {0x45, "callsuper", []InstrOperand{InstrOperandU30, InstrOperandU30}},
+	0x4b: {0x4b, "callsuperid", nil},
 	0x4e: {0x4e, "callsupervoid", []InstrOperand{InstrOperandU30, InstrOperandU30}},
 	0x78: {0x78, "checkfilter", nil},
 	0x80: {0x80, "coerce", []InstrOperand{InstrOperandU30}},
 	0x82: {0x82, "coerce_a", nil},
+	0x81: {0x81, "coerce_b", nil},
+	0x84: {0x84, "coerce_d", nil},
+	0x83: {0x83, "coerce_i", nil},
+	0x89: {0x89, "coerce_o", nil},
 	0x85: {0x85, "coerce_s", nil},
+	0x88: {0x88, "coerce_u", nil},
 	0x42: {0x42, "construct", []InstrOperand{InstrOperandU30}},
-	0x4a: {0x4a, "contructprop", []InstrOperand{InstrOperandU30, InstrOperandU30}},
+	0x4a: {0x4a, "constructprop", []InstrOperand{InstrOperandU30, InstrOperandU30}},
 	0x49: {0x49, "constructsuper", []InstrOperand{InstrOperandU30}},
 	0x76: {0x76, "convert_b", nil},
 	0x73: {0x73, "convert_i", nil},
 	0x75: {0x75, "convert_d", nil},
+	0x79: {0x79, "convert_m", nil},
+	0x7a: {0x7a, "convert_m_p", []InstrOperand{InstrOperandU30}},
 	0x77: {0x77, "convert_o", nil},
 	0x74: {0x74, "convert_u", nil},
 	0x70: {0x70, "convert_s", nil},
@@ -74,17 +97,23 @@ var Instructions = map[uint8]InstrModel{
 	0xf0: {0xf0, "debugline", []InstrOperand{InstrOperandU30}},
 	0x94: {0x94, "declocal", []InstrOperand{InstrOperandU30}},
 	0xc3: {0xc3, "declocal_i", []InstrOperand{InstrOperandU30}},
+	0x9f: {0x9f, "declocal_p", []InstrOperand{InstrOperandU30, InstrOperandU30}},
 	0x93: {0x93, "decrement", nil},
 	0xc1: {0xc1, "decrement_i", nil},
+	0x9e: {0x9e, "decrement_p", []InstrOperand{InstrOperandU30}},
+	0x5b: {0x5b, "deldescendants", nil},
 	0x6a: {0x6a, "deleteproperty", []InstrOperand{InstrOperandU30}},
 	0xa3: {0xa3, "divide", nil},
+	0xb8: {0xb8, "divide_p", []InstrOperand{InstrOperandU30}},
 	0x2a: {0x2a, "dup", nil},
 	0x06: {0x06, "dxns", []InstrOperand{InstrOperandU30}},
 	0x07: {0x07, "dxnslate", nil},
 	0xab: {0xab, "equals", nil},
 	0x72: {0x72, "esc_xattr", nil},
 	0x71: {0x71, "esc_xelem", nil},
+	0x5f: {0x5f, "finddef", []InstrOperand{InstrOperandU30}},
 	0x5e: {0x5e, "findproperty", []InstrOperand{InstrOperandU30}},
+	0x5c: {0x5c, "findpropglobal", []InstrOperand{InstrOperandU30}},
 	0x5d: {0x5d, "findpropstrict", []InstrOperand{InstrOperandU30}},
 	0x59: {0x59, "getdescendants", []InstrOperand{InstrOperandU30}},
 	0x64: {0x64, "getglobalscope", nil},
@@ -95,14 +124,15 @@ var Instructions = map[uint8]InstrModel{
 	0xd1: {0xd1, "getlocal_1", nil},
 	0xd2: {0xd2, "getlocal_2", nil},
 	0xd3: {0xd3, "getlocal_3", nil},
+	0x67: {0x67, "getouterscope", []InstrOperand{InstrOperandU30}},
 	0x66: {0x66, "getproperty", []InstrOperand{InstrOperandU30}},
-	0x65: {0x65, "getscopeobject", []InstrOperand{InstrOperandU30}},
+	0x65: {0x65, "getscopeobject", []InstrOperand{InstrOperandU8}},
 	0x6c: {0x6c, "getslot", []InstrOperand{InstrOperandU30}},
 	0x04: {0x04, "getsuper", []InstrOperand{InstrOperandU30}},
 	0xb0: {0xb0, "greaterequals", nil},
 	0xaf: {0xaf, "greaterthan", nil},
 	0x1f: {0x1f, "hasnext", nil},
-	0x32: {0x32, "hasnext2", []InstrOperand{InstrOperandU30}},
+	0x32: {0x32, "hasnext2", []InstrOperand{InstrOperandU30, InstrOperandU30}},
 	0x13: {0x13, "ifeq", []InstrOperand{InstrOperandS24}},
 	0x12: {0x12, "iffalse", []InstrOperand{InstrOperandS24}},
 	0x18: {0x18, "ifge", []InstrOperand{InstrOperandS24}},
@@ -120,8 +150,10 @@ var Instructions = map[uint8]InstrModel{
 	0xb4: {0xb4, "in", nil},
 	0x92: {0x92, "inclocal", []InstrOperand{InstrOperandU30}},
 	0xc2: {0xc2, "inclocal_i", []InstrOperand{InstrOperandU30}},
+	0x9d: {0x9d, "inclocal_p", []InstrOperand{InstrOperandU30, InstrOperandU30}},
 	0x91: {0x91, "increment", nil},
 	0xc0: {0xc0, "increment_i", nil},
+	0x9c: {0x9c, "increment_p", []InstrOperand{InstrOperandU30}},
 	0x68: {0x68, "initproperty", []InstrOperand{InstrOperandU30}},
 	0xb1: {0xb1, "instanceof", nil},
 	0xb2: {0xb2, "istype", []InstrOperand{InstrOperandU30}},
@@ -131,13 +163,21 @@ var Instructions = map[uint8]InstrModel{
 	0x09: {0x09, "label", nil},
 	0xae: {0xae, "lessequals", nil},
 	0xad: {0xad, "lessthan", nil},
+	0x38: {0x38, "lf32", nil},
+	0x39: {0x39, "lf64", nil},
+	0x36: {0x36, "li16", nil},
+	0x37: {0x37, "li32", nil},
+	0x35: {0x35, "li8", nil},
 	0x1b: {0x1b, "lookupswitch", []InstrOperand{InstrOperandS24, InstrOperandCaseCount}},
 	0xa5: {0xa5, "lshift", nil},
 	0xa4: {0xa4, "modulo", nil},
+	0xb9: {0xb9, "modulo_p", []InstrOperand{InstrOperandU30}},
 	0xa2: {0xa2, "multiply", nil},
 	0xc7: {0xc7, "multiply_i", nil},
+	0xb7: {0xb7, "multiply_p", []InstrOperand{InstrOperandU30}},
 	0x90: {0x90, "negate", nil},
 	0xc4: {0xc4, "negate_i", nil},
+	0x8f: {0x8f, "negate_p", []InstrOperand{InstrOperandU30}},
 	0x57: {0x57, "newactivation", nil},
 	0x56: {0x56, "newarray", []InstrOperand{InstrOperandU30}},
 	0x5a: {0x5a, "newcatch", []InstrOperand{InstrOperandU30}},
@@ -151,6 +191,9 @@ var Instructions = map[uint8]InstrModel{
 	0x29: {0x29, "pop", nil},
 	0x1d: {0x1d, "popscope", nil},
 	0x24: {0x24, "pushbyte", []InstrOperand{InstrOperandU8}},
+	0x22: {0x22, "pushconstant", []InstrOperand{InstrOperandU30}},
+	0x33: {0x33, "pushdecimal", []InstrOperand{InstrOperandU30}},
+	0x34: {0x34, "pushdnan", nil},
 	0x2f: {0x2f, "pushdouble", []InstrOperand{InstrOperandU30}},
 	0x27: {0x27, "pushfalse", nil},
 	0x2d: {0x2d, "pushint", []InstrOperand{InstrOperandU30}},
@@ -162,7 +205,7 @@ var Instructions = map[uint8]InstrModel{
 	0x2c: {0x2c, "pushstring", []InstrOperand{InstrOperandU30}},
 	0x26: {0x26, "pushtrue", nil},
 	0x2e: {0x2e, "pushuint", []InstrOperand{InstrOperandU30}},
-	0x21: {0x21, "undefined", nil},
+	0x21: {0x21, "pushundefined", nil},
 	0x1c: {0x1c, "pushwith", nil},
 	0x48: {0x48, "returnvalue", nil},
 	0x47: {0x47, "returnvoid", nil},
@@ -176,11 +219,21 @@ var Instructions = map[uint8]InstrModel{
 	0x61: {0x61, "setproperty", []InstrOperand{InstrOperandU30}},
 	0x6d: {0x6d, "setslot", []InstrOperand{InstrOperandU30}},
 	0x05: {0x05, "setsuper", []InstrOperand{InstrOperandU30}},
+	0x3d: {0x3d, "sf32", nil},
+	0x3e: {0x3e, "sf64", nil},
+	0x3b: {0x3b, "si16", nil},
+	0x3c: {0x3c, "si32", nil},
+	0x3a: {0x3a, "si8", nil},
 	0xac: {0xac, "strictequals", nil},
 	0xa1: {0xa1, "subtract", nil},
 	0xc6: {0xc6, "subtract_i", nil},
+	0xb6: {0xb6, "subtract_p", []InstrOperand{InstrOperandU30}},
 	0x2b: {0x2b, "swap", nil},
+	0x50: {0x50, "sxi1", nil},
+	0x52: {0x52, "sxi16", nil},
+	0x51: {0x51, "sxi8", nil},
 	0x03: {0x03, "throw", nil},
+	0xf3: {0xf3, "timestamp", nil},
 	0x95: {0x95, "typeof", nil},
 	0xa7: {0xa7, "urshift", nil},
 }
@@ -221,7 +274,8 @@ func dissassembleInstr(r Reader, code uint8) (Instr, error) {
 			if err != nil {
 				return Instr{}, err
 			}
-			for i := uint32(0); i < count; i++ {
+			// there are case_count + 1 case offsets
+			for i := uint32(0); i <= count; i++ {
 				v, err := disassembleInstrOperand(r, InstrOperandS24)
 				if err != nil {
 					return Instr{}, err
@@ -236,7 +290,7 @@ func dissassembleInstr(r Reader, code uint8) (Instr, error) {
 			operands = append(operands, v)
 		}
 	}
-	return Instr{model, operands}, nil
+	return Instr{Model: model, Operands: operands}, nil
 }
 
 // Disassemble parses the instructions of the method body
@@ -268,3 +322,76 @@ func (m *MethodBodyInfo) Disassemble() (err error) {
 	err = nil
 	return
 }
+
+func assembleInstrOperand(w Writer, t InstrOperand, v uint32) error {
+	switch t {
+	case InstrOperandU30:
+		return w.WriteU30(v)
+	case InstrOperandS24:
+		return w.WriteS24(int32(v))
+	case InstrOperandU8:
+		return w.WriteU8(uint8(v))
+	}
+	return ErrUnknownInstructionOperand
+}
+
+func assembleInstr(w Writer, instr Instr) error {
+	if err := w.WriteU8(instr.Model.Code); err != nil {
+		return err
+	}
+	operands := instr.Operands
+	for _, t := range instr.Model.Operands {
+		if t == InstrOperandCaseCount {
+			// the default offset was already written, the remaining
+			// operands are the case offsets
+			if len(operands) == 0 {
+				return ErrInstructionOperandCount
+			}
+			if err := w.WriteU30(uint32(len(operands) - 1)); err != nil {
+				return err
+			}
+			for _, v := range operands {
+				if err := assembleInstrOperand(w, InstrOperandS24, v); err != nil {
+					return err
+				}
+			}
+			operands = nil
+			continue
+		}
+		if len(operands) == 0 {
+			return ErrInstructionOperandCount
+		}
+		if err := assembleInstrOperand(w, t, operands[0]); err != nil {
+			return err
+		}
+		operands = operands[1:]
+	}
+	if len(operands) != 0 {
+		return ErrInstructionOperandCount
+	}
+	return nil
+}
+
+// Assemble encodes the instructions of the method body and replaces its code.
+// It is the inverse of Disassemble: assembling freshly disassembled
+// instructions produces the original code.
+//
+// Branch offsets of instructions with Targets and exception offsets with
+// labels are recomputed from the new position of the labels
+// (see ResolveLabels), and written back to the operands of the instructions.
+func (m *MethodBodyInfo) Assemble() error {
+	instrs, err := m.layoutLabels()
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	for _, instr := range instrs {
+		if err := assembleInstr(w, instr); err != nil {
+			return err
+		}
+	}
+	m.Code = buf.Bytes()
+	m.Instructions = instrs
+	return nil
+}
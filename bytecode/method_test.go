@@ -1,29 +1,161 @@
 package bytecode
 
-import "testing"
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
 
 func TestMethodBodyInfo_Disassemble(t *testing.T) {
-	t.Skip("skipping test because some instructions are not implemented")
+	for _, name := range []string{"obf1", "obf2"} {
+		file := openFixture(t, name)
+		a, err := Parse(NewReader(file))
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if err = file.Close(); err != nil {
+			t.Errorf("file.close: %v", err)
+		}
+		for i, body := range a.MethodBodies {
+			if err = body.Disassemble(); err != nil {
+				t.Errorf("%v: method_body %v: %v", name, i, err)
+			}
+			if len(body.Code) > 0 && len(body.Instructions) == 0 {
+				t.Errorf("%v: method_body %v: expected instructions", name, i)
+			}
+		}
+	}
+}
 
-	file := openFixture(t, "frame1")
-	defer func() {
-		if err := file.Close(); err != nil {
+func TestMethodBodyInfo_Assemble(t *testing.T) {
+	for _, name := range []string{"obf1", "obf2"} {
+		file := openFixture(t, name)
+		a, err := Parse(NewReader(file))
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if err = file.Close(); err != nil {
 			t.Errorf("file.close: %v", err)
 		}
-	}()
 
-	reader := NewReader(file)
-	a, err := Parse(reader)
-	if err != nil {
-		t.Fatalf("expected non-nil, got %v", err)
+		for i, body := range a.MethodBodies {
+			code := body.Code
+			if err = body.Disassemble(); err != nil {
+				t.Fatalf("%v: method_body %v: %v", name, i, err)
+			}
+			if err = body.Assemble(); err != nil {
+				t.Fatalf("%v: method_body %v: %v", name, i, err)
+			}
+			if !bytes.Equal(body.Code, code) {
+				t.Errorf("%v: method_body %v: expected %v, got %v", name, i, code, body.Code)
+			}
+		}
+	}
+}
+
+func TestMethodBodyInfo_Assemble_Instructions(t *testing.T) {
+	tests := []struct {
+		name    string
+		instrs  []Instr
+		want    []byte
+		wantErr error
+	}{
+		{
+			"no operand",
+			[]Instr{{Model: Instructions[0x47]}},
+			[]byte{0x47},
+			nil,
+		},
+		{
+			"u30 and u8",
+			[]Instr{{Model: Instructions[0x24], Operands: []uint32{0xff}}, {Model: Instructions[0x2c], Operands: []uint32{300}}},
+			[]byte{0x24, 0xff, 0x2c, 0xac, 0x02},
+			nil,
+		},
+		{
+			"negative s24",
+			[]Instr{{Model: Instructions[0x10], Operands: []uint32{0xfffffffc}}},
+			[]byte{0x10, 0xfc, 0xff, 0xff},
+			nil,
+		},
+		{
+			"lookupswitch",
+			[]Instr{{Model: Instructions[0x1b], Operands: []uint32{1, 2, 3}}},
+			[]byte{0x1b, 0x01, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x03, 0x00, 0x00},
+			nil,
+		},
+		{
+			"missing operand",
+			[]Instr{{Model: Instructions[0x2c]}},
+			nil,
+			ErrInstructionOperandCount,
+		},
+		{
+			"extra operand",
+			[]Instr{{Model: Instructions[0x47], Operands: []uint32{1}}},
+			nil,
+			ErrInstructionOperandCount,
+		},
 	}
-	if len(a.MethodBodies) != 45243 {
-		t.Errorf("expected 45243, got %v", len(a.MethodBodies))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MethodBodyInfo{Instructions: tt.instrs}
+			err := m.Assemble()
+			if err != tt.wantErr {
+				t.Fatalf("MethodBodyInfo.Assemble() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !bytes.Equal(m.Code, tt.want) {
+				t.Errorf("MethodBodyInfo.Assemble() = %v, want %v", m.Code, tt.want)
+			}
+			if err = m.Disassemble(); err != nil {
+				t.Fatalf("MethodBodyInfo.Disassemble() error = %v", err)
+			}
+			if !reflect.DeepEqual(m.Instructions, tt.instrs) {
+				t.Errorf("MethodBodyInfo.Disassemble() = %v, want %v", m.Instructions, tt.instrs)
+			}
+		})
 	}
+}
 
-	for i, body := range a.MethodBodies {
-		if err = body.Disassemble(); err != nil {
-			t.Errorf("method_body %v: %v", i, err)
+func TestInstructions_RoundTrip(t *testing.T) {
+	names := map[string]uint8{}
+	for code, model := range Instructions {
+		if model.Code != code {
+			t.Errorf("%v: expected code %#x, got %#x", model.Name, code, model.Code)
+		}
+		if other, ok := names[model.Name]; ok {
+			t.Errorf("%v: used by %#x and %#x", model.Name, other, code)
+		}
+		names[model.Name] = code
+
+		var operands []uint32
+		for _, o := range model.Operands {
+			switch o {
+			case InstrOperandU8:
+				operands = append(operands, 0xfe)
+			case InstrOperandU30:
+				operands = append(operands, 0x3fffffff)
+			case InstrOperandS24:
+				operands = append(operands, 0xfffffff0)
+			case InstrOperandCaseCount:
+				operands = append(operands, 3, 0x7fffff)
+			}
+		}
+		instrs := []Instr{{Model: model, Operands: operands}}
+		m := MethodBodyInfo{Instructions: instrs}
+		if err := m.Assemble(); err != nil {
+			t.Errorf("%v: Assemble: %v", model.Name, err)
+			continue
+		}
+		if err := m.Disassemble(); err != nil {
+			t.Errorf("%v: Disassemble: %v", model.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(m.Instructions, instrs) {
+			t.Errorf("%v: expected %v, got %v", model.Name, instrs, m.Instructions)
 		}
 	}
 }
@@ -0,0 +1,93 @@
+package bytecode
+
+import "fmt"
+
+// NamespaceKindNames maps the namespace kinds to their names
+var NamespaceKindNames = map[uint8]string{
+	NamespaceKindNamespace:          "Namespace",
+	NamespaceKindPackageNamespace:   "PackageNamespace",
+	NamespaceKindPackageInternalNs:  "PackageInternalNs",
+	NamespaceKindProtectedNamespace: "ProtectedNamespace",
+	NamespaceKindExplicitNamespace:  "ExplicitNamespace",
+	NamespaceKindStaticProtectedNs:  "StaticProtectedNs",
+	NamespaceKindPrivateNs:          "PrivateNamespace",
+}
+
+// MultinameKindNames maps the multiname kinds to their names
+var MultinameKindNames = map[uint8]string{
+	MultinameKindQName:       "QName",
+	MultinameKindQNameA:      "QNameA",
+	MultinameKindRTQName:     "RTQName",
+	MultinameKindRTQNameA:    "RTQNameA",
+	MultinameKindRTQNameL:    "RTQNameL",
+	MultinameKindRTQNameLA:   "RTQNameLA",
+	MultinameKindMultiname:   "Multiname",
+	MultinameKindMultinameA:  "MultinameA",
+	MultinameKindMultinameL:  "MultinameL",
+	MultinameKindMultinameLA: "MultinameLA",
+	MultinameKindTypename:    "TypeName",
+}
+
+// TraitKindNames maps the trait types to their names
+var TraitKindNames = map[uint8]string{
+	TraitsInfoSlot:     "slot",
+	TraitsInfoMethod:   "method",
+	TraitsInfoGetter:   "getter",
+	TraitsInfoSetter:   "setter",
+	TraitsInfoClass:    "class",
+	TraitsInfoFunction: "function",
+	TraitsInfoConst:    "const",
+}
+
+// ValueKindNames maps the kinds of constant values, other than namespaces,
+// to their names
+var ValueKindNames = map[uint8]string{
+	SlotKindInt:       "Integer",
+	SlotKindUInt:      "UInteger",
+	SlotKindDouble:    "Double",
+	SlotKindUtf8:      "Utf8",
+	SlotKindTrue:      "True",
+	SlotKindFalse:     "False",
+	SlotKindNull:      "Null",
+	SlotKindUndefined: "Undefined",
+}
+
+// Flag names a bit of a flags field
+type Flag struct {
+	Bit  uint8
+	Name string
+}
+
+// MethodFlagNames names the flags of a method_info
+var MethodFlagNames = []Flag{
+	{MethodNeedArguments, "NEED_ARGUMENTS"},
+	{MethodNeedActivation, "NEED_ACTIVATION"},
+	{MethodNeedRest, "NEED_REST"},
+	{MethodHasOptional, "HAS_OPTIONAL"},
+	{MethodSetDxns, "SET_DXNS"},
+	{MethodHasParamNames, "HAS_PARAM_NAMES"},
+}
+
+// InstanceFlagNames names the flags of an instance_info
+var InstanceFlagNames = []Flag{
+	{InstanceInfoClassSealed, "SEALED"},
+	{InstanceInfoClassFinal, "FINAL"},
+	{InstanceInfoClassInterface, "INTERFACE"},
+	{InstanceInfoClassProtectedNs, "PROTECTEDNS"},
+}
+
+// FlagNames returns the names of the bits set in a flags field, unknown bits
+// being written in hexadecimal
+func FlagNames(flags uint8, names []Flag) []string {
+	var words []string
+	for _, f := range names {
+		if flags&f.Bit != 0 {
+			words = append(words, f.Name)
+			flags &^= f.Bit
+		}
+	}
+	if flags != 0 {
+		words = append(words, fmt.Sprintf("%#x", flags))
+	}
+	return words
+}
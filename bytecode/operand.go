@@ -0,0 +1,210 @@
+package bytecode
+
+import "errors"
+
+// OperandType is the meaning of an instruction operand, as opposed to
+// InstrOperand which is its encoding
+type OperandType uint8
+
+// These are possible operand types
+const (
+	OperandTypeUnsigned  = OperandType(iota) // plain unsigned value
+	OperandTypeByte                          // signed byte value
+	OperandTypeShort                         // signed 16 bits value
+	OperandTypeArgCount                      // number of arguments on the stack
+	OperandTypeInt                           // index into CpoolInfo.Integers
+	OperandTypeUInt                          // index into CpoolInfo.UIntegers
+	OperandTypeDouble                        // index into CpoolInfo.Doubles
+	OperandTypeString                        // index into CpoolInfo.Strings
+	OperandTypeNamespace                     // index into CpoolInfo.Namespaces
+	OperandTypeMultiname                     // index into CpoolInfo.Multinames
+	OperandTypeMethod                        // index into AbcFile.Methods
+	OperandTypeClass                         // index into AbcFile.Classes
+	OperandTypeException                     // index into MethodBodyInfo.Exceptions
+	OperandTypeRegister                      // local register
+	OperandTypeSlot                          // slot id
+	OperandTypeBranch                        // signed branch offset
+)
+
+// ErrOperandOutOfRange means that an operand indexes past the end of the
+// constant pool table it refers to
+var ErrOperandOutOfRange = errors.New("operand out of range")
+
+// operandTypes maps an instruction code to the types of its operands.
+// Instructions missing from this map have no operand or, for lookupswitch,
+// only branch operands.
+var operandTypes = map[uint8][]OperandType{
+	0x04: {OperandTypeMultiname},                      // getsuper
+	0x05: {OperandTypeMultiname},                      // setsuper
+	0x06: {OperandTypeString},                         // dxns
+	0x08: {OperandTypeRegister},                       // kill
+	0x0c: {OperandTypeBranch},                         // ifnlt
+	0x0d: {OperandTypeBranch},                         // ifnle
+	0x0e: {OperandTypeBranch},                         // ifngt
+	0x0f: {OperandTypeBranch},                         // ifnge
+	0x10: {OperandTypeBranch},                         // jump
+	0x11: {OperandTypeBranch},                         // iftrue
+	0x12: {OperandTypeBranch},                         // iffalse
+	0x13: {OperandTypeBranch},                         // ifeq
+	0x14: {OperandTypeBranch},                         // ifne
+	0x15: {OperandTypeBranch},                         // iflt
+	0x16: {OperandTypeBranch},                         // ifle
+	0x17: {OperandTypeBranch},                         // ifgt
+	0x18: {OperandTypeBranch},                         // ifge
+	0x19: {OperandTypeBranch},                         // ifstricteq
+	0x1a: {OperandTypeBranch},                         // ifstrictne
+	0x22: {OperandTypeUnsigned},                       // pushconstant
+	0x24: {OperandTypeByte},                           // pushbyte
+	0x25: {OperandTypeShort},                          // pushshort
+	0x2c: {OperandTypeString},                         // pushstring
+	0x2d: {OperandTypeInt},                            // pushint
+	0x2e: {OperandTypeUInt},                           // pushuint
+	0x2f: {OperandTypeDouble},                         // pushdouble
+	0x31: {OperandTypeNamespace},                      // pushnamespace
+	0x32: {OperandTypeRegister, OperandTypeRegister},  // hasnext2
+	0x33: {OperandTypeUnsigned},                       // pushdecimal
+	0x40: {OperandTypeMethod},                         // newfunction
+	0x41: {OperandTypeArgCount},                       // call
+	0x42: {OperandTypeArgCount},                       // construct
+	0x43: {OperandTypeUnsigned, OperandTypeArgCount},  // callmethod
+	0x44: {OperandTypeMethod, OperandTypeArgCount},    // callstatic
+	0x45: {OperandTypeMultiname, OperandTypeArgCount}, // callsuper
+	0x46: {OperandTypeMultiname, OperandTypeArgCount}, // callproperty
+	0x49: {OperandTypeArgCount},                       // constructsuper
+	0x4a: {OperandTypeMultiname, OperandTypeArgCount}, // constructprop
+	0x4c: {OperandTypeMultiname, OperandTypeArgCount}, // callproplex
+	0x4e: {OperandTypeMultiname, OperandTypeArgCount}, // callsupervoid
+	0x4f: {OperandTypeMultiname, OperandTypeArgCount}, // callpropvoid
+	0x53: {OperandTypeArgCount},                       // applytype
+	0x55: {OperandTypeArgCount},                       // newobject
+	0x56: {OperandTypeArgCount},                       // newarray
+	0x58: {OperandTypeClass},                          // newclass
+	0x59: {OperandTypeMultiname},                      // getdescendants
+	0x5a: {OperandTypeException},                      // newcatch
+	0x5c: {OperandTypeMultiname},                      // findpropglobal
+	0x5d: {OperandTypeMultiname},                      // findpropstrict
+	0x5e: {OperandTypeMultiname},                      // findproperty
+	0x5f: {OperandTypeMultiname},                      // finddef
+	0x60: {OperandTypeMultiname},                      // getlex
+	0x61: {OperandTypeMultiname},                      // setproperty
+	0x62: {OperandTypeRegister},                       // getlocal
+	0x63: {OperandTypeRegister},                       // setlocal
+	0x65: {OperandTypeUnsigned},                       // getscopeobject
+	0x66: {OperandTypeMultiname},                      // getproperty
+	0x67: {OperandTypeUnsigned},                       // getouterscope
+	0x68: {OperandTypeMultiname},                      // initproperty
+	0x6a: {OperandTypeMultiname},                      // deleteproperty
+	0x6c: {OperandTypeSlot},                           // getslot
+	0x6d: {OperandTypeSlot},                           // setslot
+	0x6e: {OperandTypeSlot},                           // getglobalslot
+	0x6f: {OperandTypeSlot},                           // setglobalslot
+	0x7a: {OperandTypeUnsigned},                       // convert_m_p
+	0x80: {OperandTypeMultiname},                      // coerce
+	0x86: {OperandTypeMultiname},                      // astype
+	0x8f: {OperandTypeUnsigned},                       // negate_p
+	0x92: {OperandTypeRegister},                       // inclocal
+	0x94: {OperandTypeRegister},                       // declocal
+	0x9c: {OperandTypeUnsigned},                       // increment_p
+	0x9d: {OperandTypeUnsigned, OperandTypeRegister},  // inclocal_p
+	0x9e: {OperandTypeUnsigned},                       // decrement_p
+	0x9f: {OperandTypeUnsigned, OperandTypeRegister},  // declocal_p
+	0xb2: {OperandTypeMultiname},                      // istype
+	0xb5: {OperandTypeUnsigned},                       // add_p
+	0xb6: {OperandTypeUnsigned},                       // subtract_p
+	0xb7: {OperandTypeUnsigned},                       // multiply_p
+	0xb8: {OperandTypeUnsigned},                       // divide_p
+	0xb9: {OperandTypeUnsigned},                       // modulo_p
+	0xc2: {OperandTypeRegister},                       // inclocal_i
+	0xc3: {OperandTypeRegister},                       // declocal_i
+	0xef: { // debug
+		OperandTypeUnsigned, OperandTypeString, OperandTypeRegister, OperandTypeUnsigned,
+	},
+	0xf0: {OperandTypeUnsigned}, // debugline
+	0xf1: {OperandTypeString},   // debugfile
+	0xf2: {OperandTypeUnsigned}, // bkptline
+}
+
+// Operand represents a typed instruction operand
+type Operand struct {
+	Type  OperandType
+	Value uint32
+}
+
+// OperandTypes returns the types of the operands of the instruction
+func (i Instr) OperandTypes() []OperandType {
+	if i.Model.Code == lookupswitchCode {
+		types := make([]OperandType, len(i.Operands))
+		for j := range types {
+			types[j] = OperandTypeBranch
+		}
+		return types
+	}
+	return operandTypes[i.Model.Code]
+}
+
+// TypedOperands returns the operands of the instruction with their type
+func (i Instr) TypedOperands() []Operand {
+	types := i.OperandTypes()
+	operands := make([]Operand, len(i.Operands))
+	for j, v := range i.Operands {
+		operands[j].Value = v
+		if j < len(types) {
+			operands[j].Type = types[j]
+		}
+	}
+	return operands
+}
+
+// Signed returns the value of a byte, short or branch operand
+// sign-extended to 32 bits
+func (o Operand) Signed() int32 {
+	switch o.Type {
+	case OperandTypeByte:
+		return int32(int8(o.Value))
+	case OperandTypeShort:
+		return int32(int16(o.Value))
+	}
+	return int32(o.Value)
+}
+
+// Resolve returns the value of the operand: the constant pool entry for
+// int, uint, double, string, namespace and multiname operands (int32, uint32,
+// float64, string, NamespaceInfo and MultinameInfo), an int32 for byte, short
+// and branch operands, and the raw uint32 value for other operands.
+func (o Operand) Resolve(c *CpoolInfo) (interface{}, error) {
+	var length int
+	switch o.Type {
+	case OperandTypeByte, OperandTypeShort, OperandTypeBranch:
+		return o.Signed(), nil
+	case OperandTypeInt:
+		length = len(c.Integers)
+	case OperandTypeUInt:
+		length = len(c.UIntegers)
+	case OperandTypeDouble:
+		length = len(c.Doubles)
+	case OperandTypeString:
+		length = len(c.Strings)
+	case OperandTypeNamespace:
+		length = len(c.Namespaces)
+	case OperandTypeMultiname:
+		length = len(c.Multinames)
+	default:
+		return o.Value, nil
+	}
+	if int64(o.Value) >= int64(length) {
+		return nil, ErrOperandOutOfRange
+	}
+	switch o.Type {
+	case OperandTypeInt:
+		return c.Integers[o.Value], nil
+	case OperandTypeUInt:
+		return c.UIntegers[o.Value], nil
+	case OperandTypeDouble:
+		return c.Doubles[o.Value], nil
+	case OperandTypeString:
+		return c.Strings[o.Value], nil
+	case OperandTypeNamespace:
+		return c.Namespaces[o.Value], nil
+	}
+	return c.Multinames[o.Value], nil
+}
@@ -0,0 +1,110 @@
+package bytecode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInstr_OperandTypes(t *testing.T) {
+	for code, model := range Instructions {
+		if code == lookupswitchCode {
+			continue
+		}
+		types := Instr{Model: model}.OperandTypes()
+		if len(types) != len(model.Operands) {
+			t.Errorf("%v: expected %v operand types, got %v", model.Name, len(model.Operands), len(types))
+			continue
+		}
+		for i, o := range model.Operands {
+			if (o == InstrOperandS24) != (types[i] == OperandTypeBranch) {
+				t.Errorf("%v: operand %v: branch type does not match its encoding", model.Name, i)
+			}
+		}
+	}
+	for code := range operandTypes {
+		if _, ok := Instructions[code]; !ok {
+			t.Errorf("%#x: operand types of an unknown instruction", code)
+		}
+	}
+
+	lookupswitch := Instr{Model: Instructions[lookupswitchCode], Operands: []uint32{1, 2, 3}}
+	want := []OperandType{OperandTypeBranch, OperandTypeBranch, OperandTypeBranch}
+	if got := lookupswitch.OperandTypes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestInstr_TypedOperands(t *testing.T) {
+	instr := Instr{Model: Instructions[0x46], Operands: []uint32{12, 3}}
+	want := []Operand{{OperandTypeMultiname, 12}, {OperandTypeArgCount, 3}}
+	if got := instr.TypedOperands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestOperand_Resolve(t *testing.T) {
+	c := &CpoolInfo{
+		Integers:   []int32{0, -7},
+		UIntegers:  []uint32{0, 0xffffffff},
+		Doubles:    []float64{0, 1.5},
+		Strings:    []string{"", "foo"},
+		Namespaces: []NamespaceInfo{{}, {NamespaceKindPackageNamespace, 1}},
+		Multinames: []MultinameInfo{{}, {Kind: MultinameKindQName, Namespace: 1, Name: 1}},
+	}
+	tests := []struct {
+		name    string
+		operand Operand
+		want    interface{}
+		wantErr bool
+	}{
+		{"byte", Operand{OperandTypeByte, 0xfe}, int32(-2), false},
+		{"short", Operand{OperandTypeShort, 0xffff8000}, int32(-32768), false},
+		{"branch", Operand{OperandTypeBranch, 0xfffffff0}, int32(-16), false},
+		{"int", Operand{OperandTypeInt, 1}, int32(-7), false},
+		{"uint", Operand{OperandTypeUInt, 1}, uint32(0xffffffff), false},
+		{"double", Operand{OperandTypeDouble, 1}, 1.5, false},
+		{"string", Operand{OperandTypeString, 1}, "foo", false},
+		{"namespace", Operand{OperandTypeNamespace, 1}, c.Namespaces[1], false},
+		{"multiname", Operand{OperandTypeMultiname, 1}, c.Multinames[1], false},
+		{"register", Operand{OperandTypeRegister, 4}, uint32(4), false},
+		{"method", Operand{OperandTypeMethod, 400}, uint32(400), false},
+		{"out of range", Operand{OperandTypeString, 2}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.operand.Resolve(c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Operand.Resolve() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Operand.Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOperand_Resolve_Fixtures(t *testing.T) {
+	for _, name := range []string{"obf1", "obf2"} {
+		file := openFixture(t, name)
+		a, err := Parse(NewReader(file))
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if err = file.Close(); err != nil {
+			t.Errorf("file.close: %v", err)
+		}
+		for i, body := range a.MethodBodies {
+			if err = body.Disassemble(); err != nil {
+				t.Fatalf("%v: method_body %v: %v", name, i, err)
+			}
+			for _, instr := range body.Instructions {
+				for _, o := range instr.TypedOperands() {
+					if _, err = o.Resolve(&a.ConstantPool); err != nil {
+						t.Errorf("%v: method_body %v: %v %v: %v", name, i, instr.Model.Name, o, err)
+					}
+				}
+			}
+		}
+	}
+}
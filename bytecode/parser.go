@@ -736,7 +736,7 @@ func (p *parser) ParseExceptionInfo() (ExceptionInfo, error) {
 	if err != nil {
 		return ExceptionInfo{}, err
 	}
-	return ExceptionInfo{from, to, target, excType, varName}, nil
+	return ExceptionInfo{from, to, target, excType, varName, 0, 0, 0}, nil
 }
 
 func (p *parser) ParseMethodBody() (MethodBodyInfo, error) {
@@ -784,7 +784,7 @@ func (p *parser) ParseMethodBody() (MethodBodyInfo, error) {
 	if err != nil {
 		return MethodBodyInfo{}, err
 	}
-	return MethodBodyInfo{method, maxStack, localCount, initScopeLength, maxScopeLength, code, exceptions, traits, nil}, nil
+	return MethodBodyInfo{method, maxStack, localCount, initScopeLength, maxScopeLength, code, exceptions, traits, nil, 0}, nil
 }
 
 func (p *parser) ParseMethodBodies() ([]MethodBodyInfo, error) {
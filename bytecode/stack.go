@@ -0,0 +1,273 @@
+package bytecode
+
+import "errors"
+
+// ErrUnknownStackEffect means that the effect of an instruction on the
+// operand stack is not known, as for the undocumented callsuperid,
+// callinterface and deldescendants
+var ErrUnknownStackEffect = errors.New("unknown stack effect")
+
+// stackEffect describes how an instruction changes the operand stack: it
+// pops Pop values, plus Args values per argument of its argument count
+// operand, plus the runtime parts of its multiname operand, then pushes Push
+// values
+type stackEffect struct {
+	Pop  int
+	Push int
+	Args int
+}
+
+// stackEffects maps an instruction code to its effect on the operand stack
+var stackEffects = map[uint8]stackEffect{
+	0x01: {0, 0, 0}, // bkpt
+	0x02: {0, 0, 0}, // nop
+	0x03: {1, 0, 0}, // throw
+	0x04: {1, 1, 0}, // getsuper
+	0x05: {2, 0, 0}, // setsuper
+	0x06: {0, 0, 0}, // dxns
+	0x07: {1, 0, 0}, // dxnslate
+	0x08: {0, 0, 0}, // kill
+	0x09: {0, 0, 0}, // label
+	0x0c: {2, 0, 0}, // ifnlt
+	0x0d: {2, 0, 0}, // ifnle
+	0x0e: {2, 0, 0}, // ifngt
+	0x0f: {2, 0, 0}, // ifnge
+	0x10: {0, 0, 0}, // jump
+	0x11: {1, 0, 0}, // iftrue
+	0x12: {1, 0, 0}, // iffalse
+	0x13: {2, 0, 0}, // ifeq
+	0x14: {2, 0, 0}, // ifne
+	0x15: {2, 0, 0}, // iflt
+	0x16: {2, 0, 0}, // ifle
+	0x17: {2, 0, 0}, // ifgt
+	0x18: {2, 0, 0}, // ifge
+	0x19: {2, 0, 0}, // ifstricteq
+	0x1a: {2, 0, 0}, // ifstrictne
+	0x1b: {1, 0, 0}, // lookupswitch
+	0x1c: {1, 0, 0}, // pushwith
+	0x1d: {0, 0, 0}, // popscope
+	0x1e: {2, 1, 0}, // nextname
+	0x1f: {2, 1, 0}, // hasnext
+	0x20: {0, 1, 0}, // pushnull
+	0x21: {0, 1, 0}, // pushundefined
+	0x22: {0, 1, 0}, // pushconstant
+	0x23: {2, 1, 0}, // nextvalue
+	0x24: {0, 1, 0}, // pushbyte
+	0x25: {0, 1, 0}, // pushshort
+	0x26: {0, 1, 0}, // pushtrue
+	0x27: {0, 1, 0}, // pushfalse
+	0x28: {0, 1, 0}, // pushnan
+	0x29: {1, 0, 0}, // pop
+	0x2a: {1, 2, 0}, // dup
+	0x2b: {2, 2, 0}, // swap
+	0x2c: {0, 1, 0}, // pushstring
+	0x2d: {0, 1, 0}, // pushint
+	0x2e: {0, 1, 0}, // pushuint
+	0x2f: {0, 1, 0}, // pushdouble
+	0x30: {1, 0, 0}, // pushscope
+	0x31: {0, 1, 0}, // pushnamespace
+	0x32: {0, 1, 0}, // hasnext2
+	0x33: {0, 1, 0}, // pushdecimal
+	0x34: {0, 1, 0}, // pushdnan
+	0x35: {1, 1, 0}, // li8
+	0x36: {1, 1, 0}, // li16
+	0x37: {1, 1, 0}, // li32
+	0x38: {1, 1, 0}, // lf32
+	0x39: {1, 1, 0}, // lf64
+	0x3a: {2, 0, 0}, // si8
+	0x3b: {2, 0, 0}, // si16
+	0x3c: {2, 0, 0}, // si32
+	0x3d: {2, 0, 0}, // sf32
+	0x3e: {2, 0, 0}, // sf64
+	0x40: {0, 1, 0}, // newfunction
+	0x41: {2, 1, 1}, // call
+	0x42: {1, 1, 1}, // construct
+	0x43: {1, 1, 1}, // callmethod
+	0x44: {1, 1, 1}, // callstatic
+	0x45: {1, 1, 1}, // callsuper
+	0x46: {1, 1, 1}, // callproperty
+	0x47: {0, 0, 0}, // returnvoid
+	0x48: {1, 0, 0}, // returnvalue
+	0x49: {1, 0, 1}, // constructsuper
+	0x4a: {1, 1, 1}, // constructprop
+	0x4c: {1, 1, 1}, // callproplex
+	0x4e: {1, 0, 1}, // callsupervoid
+	0x4f: {1, 0, 1}, // callpropvoid
+	0x50: {1, 1, 0}, // sxi1
+	0x51: {1, 1, 0}, // sxi8
+	0x52: {1, 1, 0}, // sxi16
+	0x53: {1, 1, 1}, // applytype
+	0x55: {0, 1, 2}, // newobject
+	0x56: {0, 1, 1}, // newarray
+	0x57: {0, 1, 0}, // newactivation
+	0x58: {1, 1, 0}, // newclass
+	0x59: {1, 1, 0}, // getdescendants
+	0x5a: {0, 1, 0}, // newcatch
+	0x5c: {0, 1, 0}, // findpropglobal
+	0x5d: {0, 1, 0}, // findpropstrict
+	0x5e: {0, 1, 0}, // findproperty
+	0x5f: {0, 1, 0}, // finddef
+	0x60: {0, 1, 0}, // getlex
+	0x61: {2, 0, 0}, // setproperty
+	0x62: {0, 1, 0}, // getlocal
+	0x63: {1, 0, 0}, // setlocal
+	0x64: {0, 1, 0}, // getglobalscope
+	0x65: {0, 1, 0}, // getscopeobject
+	0x66: {1, 1, 0}, // getproperty
+	0x67: {0, 1, 0}, // getouterscope
+	0x68: {2, 0, 0}, // initproperty
+	0x6a: {1, 1, 0}, // deleteproperty
+	0x6c: {1, 1, 0}, // getslot
+	0x6d: {2, 0, 0}, // setslot
+	0x6e: {0, 1, 0}, // getglobalslot
+	0x6f: {1, 0, 0}, // setglobalslot
+	0x70: {1, 1, 0}, // convert_s
+	0x71: {1, 1, 0}, // esc_xelem
+	0x72: {1, 1, 0}, // esc_xattr
+	0x73: {1, 1, 0}, // convert_i
+	0x74: {1, 1, 0}, // convert_u
+	0x75: {1, 1, 0}, // convert_d
+	0x76: {1, 1, 0}, // convert_b
+	0x77: {1, 1, 0}, // convert_o
+	0x78: {1, 1, 0}, // checkfilter
+	0x79: {1, 1, 0}, // convert_m
+	0x7a: {1, 1, 0}, // convert_m_p
+	0x80: {1, 1, 0}, // coerce
+	0x81: {1, 1, 0}, // coerce_b
+	0x82: {1, 1, 0}, // coerce_a
+	0x83: {1, 1, 0}, // coerce_i
+	0x84: {1, 1, 0}, // coerce_d
+	0x85: {1, 1, 0}, // coerce_s
+	0x86: {1, 1, 0}, // astype
+	0x87: {2, 1, 0}, // astypelate
+	0x88: {1, 1, 0}, // coerce_u
+	0x89: {1, 1, 0}, // coerce_o
+	0x8f: {1, 1, 0}, // negate_p
+	0x90: {1, 1, 0}, // negate
+	0x91: {1, 1, 0}, // increment
+	0x92: {0, 0, 0}, // inclocal
+	0x93: {1, 1, 0}, // decrement
+	0x94: {0, 0, 0}, // declocal
+	0x95: {1, 1, 0}, // typeof
+	0x96: {1, 1, 0}, // not
+	0x97: {1, 1, 0}, // bitnot
+	0x9c: {1, 1, 0}, // increment_p
+	0x9d: {0, 0, 0}, // inclocal_p
+	0x9e: {1, 1, 0}, // decrement_p
+	0x9f: {0, 0, 0}, // declocal_p
+	0xa0: {2, 1, 0}, // add
+	0xa1: {2, 1, 0}, // subtract
+	0xa2: {2, 1, 0}, // multiply
+	0xa3: {2, 1, 0}, // divide
+	0xa4: {2, 1, 0}, // modulo
+	0xa5: {2, 1, 0}, // lshift
+	0xa6: {2, 1, 0}, // rshift
+	0xa7: {2, 1, 0}, // urshift
+	0xa8: {2, 1, 0}, // bitand
+	0xa9: {2, 1, 0}, // bitor
+	0xaa: {2, 1, 0}, // bitxor
+	0xab: {2, 1, 0}, // equals
+	0xac: {2, 1, 0}, // strictequals
+	0xad: {2, 1, 0}, // lessthan
+	0xae: {2, 1, 0}, // lessequals
+	0xaf: {2, 1, 0}, // greaterthan
+	0xb0: {2, 1, 0}, // greaterequals
+	0xb1: {2, 1, 0}, // instanceof
+	0xb2: {1, 1, 0}, // istype
+	0xb3: {2, 1, 0}, // istypelate
+	0xb4: {2, 1, 0}, // in
+	0xb5: {2, 1, 0}, // add_p
+	0xb6: {2, 1, 0}, // subtract_p
+	0xb7: {2, 1, 0}, // multiply_p
+	0xb8: {2, 1, 0}, // divide_p
+	0xb9: {2, 1, 0}, // modulo_p
+	0xc0: {1, 1, 0}, // increment_i
+	0xc1: {1, 1, 0}, // decrement_i
+	0xc2: {0, 0, 0}, // inclocal_i
+	0xc3: {0, 0, 0}, // declocal_i
+	0xc4: {1, 1, 0}, // negate_i
+	0xc5: {2, 1, 0}, // add_i
+	0xc6: {2, 1, 0}, // subtract_i
+	0xc7: {2, 1, 0}, // multiply_i
+	0xd0: {0, 1, 0}, // getlocal_0
+	0xd1: {0, 1, 0}, // getlocal_1
+	0xd2: {0, 1, 0}, // getlocal_2
+	0xd3: {0, 1, 0}, // getlocal_3
+	0xd4: {1, 0, 0}, // setlocal_0
+	0xd5: {1, 0, 0}, // setlocal_1
+	0xd6: {1, 0, 0}, // setlocal_2
+	0xd7: {1, 0, 0}, // setlocal_3
+	0xef: {0, 0, 0}, // debug
+	0xf0: {0, 0, 0}, // debugline
+	0xf1: {0, 0, 0}, // debugfile
+	0xf2: {0, 0, 0}, // bkptline
+	0xf3: {0, 0, 0}, // timestamp
+}
+
+// runtimeParts returns the number of values a multiname takes from the
+// operand stack at runtime: its namespace and its name for late bound names
+func (c *CpoolInfo) runtimeParts(m uint32) (int, error) {
+	if int64(m) >= int64(len(c.Multinames)) {
+		return 0, ErrOperandOutOfRange
+	}
+	switch c.Multinames[m].Kind {
+	case MultinameKindRTQName, MultinameKindRTQNameA, MultinameKindMultinameL, MultinameKindMultinameLA:
+		return 1, nil
+	case MultinameKindRTQNameL, MultinameKindRTQNameLA:
+		return 2, nil
+	}
+	return 0, nil
+}
+
+// StackEffect returns the number of values the instruction pops from and
+// pushes onto the operand stack. Argument counts are read from the
+// operands and the runtime parts of multinames from the constant pool.
+func (i Instr) StackEffect(c *CpoolInfo) (pop, push int, err error) {
+	effect, ok := stackEffects[i.Model.Code]
+	if !ok {
+		return 0, 0, ErrUnknownStackEffect
+	}
+	pop, push = effect.Pop, effect.Push
+	for _, o := range i.TypedOperands() {
+		switch o.Type {
+		case OperandTypeArgCount:
+			pop += effect.Args * int(o.Value)
+		case OperandTypeMultiname:
+			parts, err := c.runtimeParts(o.Value)
+			if err != nil {
+				return 0, 0, err
+			}
+			pop += parts
+		}
+	}
+	return pop, push, nil
+}
+
+// ScopeEffect returns the number of scopes the instruction pops from and
+// pushes onto the scope stack
+func (i Instr) ScopeEffect() (pop, push int) {
+	switch i.Model.Code {
+	case 0x1c, 0x30: // pushwith, pushscope
+		return 0, 1
+	case 0x1d: // popscope
+		return 1, 0
+	}
+	return 0, 0
+}
+
+// Registers returns the local registers the instruction reads or writes
+func (i Instr) Registers() []uint32 {
+	switch code := i.Model.Code; {
+	case code >= 0xd0 && code <= 0xd3: // getlocal_<n>
+		return []uint32{uint32(code - 0xd0)}
+	case code >= 0xd4 && code <= 0xd7: // setlocal_<n>
+		return []uint32{uint32(code - 0xd4)}
+	}
+	var registers []uint32
+	for _, o := range i.TypedOperands() {
+		if o.Type == OperandTypeRegister {
+			registers = append(registers, o.Value)
+		}
+	}
+	return registers
+}
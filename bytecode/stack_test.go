@@ -0,0 +1,62 @@
+package bytecode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInstr_StackEffect(t *testing.T) {
+	c := &CpoolInfo{Multinames: []MultinameInfo{
+		{},
+		{Kind: MultinameKindQName},
+		{Kind: MultinameKindRTQNameL},
+		{Kind: MultinameKindMultinameL},
+	}}
+	tests := []struct {
+		name      string
+		code      uint8
+		operands  []uint32
+		pop, push int
+		err       error
+	}{
+		{"pushnull", 0x20, nil, 0, 1, nil},
+		{"dup", 0x2a, nil, 1, 2, nil},
+		{"call", 0x41, []uint32{3}, 5, 1, nil},
+		{"callproperty", 0x46, []uint32{1, 2}, 3, 1, nil},
+		{"callpropvoid late", 0x4f, []uint32{2, 2}, 5, 0, nil},
+		{"setproperty late", 0x61, []uint32{3}, 3, 0, nil},
+		{"newobject", 0x55, []uint32{2}, 4, 1, nil},
+		{"getproperty out of range", 0x66, []uint32{4}, 0, 0, ErrOperandOutOfRange},
+		{"callinterface", 0x4d, nil, 0, 0, ErrUnknownStackEffect},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instr := Instr{Model: Instructions[tt.code], Operands: tt.operands}
+			pop, push, err := instr.StackEffect(c)
+			if pop != tt.pop || push != tt.push || err != tt.err {
+				t.Errorf("expected %v, %v, %v, got %v, %v, %v", tt.pop, tt.push, tt.err, pop, push, err)
+			}
+		})
+	}
+}
+
+func TestInstr_Registers(t *testing.T) {
+	tests := []struct {
+		code     uint8
+		operands []uint32
+		want     []uint32
+	}{
+		{0xd2, nil, []uint32{2}},
+		{0xd7, nil, []uint32{3}},
+		{0x62, []uint32{5}, []uint32{5}},
+		{0x32, []uint32{1, 2}, []uint32{1, 2}},
+		{0x9d, []uint32{1, 4}, []uint32{4}},
+		{0x20, nil, nil},
+	}
+	for _, tt := range tests {
+		instr := Instr{Model: Instructions[tt.code], Operands: tt.operands}
+		if got := instr.Registers(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: expected %v, got %v", instr.Model.Name, tt.want, got)
+		}
+	}
+}
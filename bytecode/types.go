@@ -218,6 +218,7 @@ type MethodBodyInfo struct {
 	Exceptions      []ExceptionInfo
 	Traits          []TraitsInfo
 	Instructions    []Instr
+	EndLabel        Label // names the position after the last instruction
 }
 
 // ExceptionInfo represents a exception_info data structure
@@ -227,4 +228,9 @@ type ExceptionInfo struct {
 	Target  uint32
 	ExcType uint32
 	VarName uint32
+
+	// When set, these labels replace From, To and Target on Assemble
+	FromLabel   Label
+	ToLabel     Label
+	TargetLabel Label
 }
@@ -0,0 +1,287 @@
+package bytecode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLengthMismatch means that two fields describing the same entries do
+// not agree, such as ParamCount and ParamTypes, or Instances and Classes
+var ErrLengthMismatch = errors.New("length mismatch")
+
+// ErrFlagMismatch means that a field is set while the flag enabling it is
+// not, so that it would be lost on extraction, or the other way round
+var ErrFlagMismatch = errors.New("flag mismatch")
+
+// ErrDuplicateMethodBody means that several method bodies belong to the same
+// method
+var ErrDuplicateMethodBody = errors.New("duplicate method body")
+
+// ErrInvalidExceptionRange means that the range or the target of an
+// exception lies outside of the code of its method body
+var ErrInvalidExceptionRange = errors.New("invalid exception range")
+
+// ErrUnknownNamespaceKind means that a namespace has an unknown kind
+var ErrUnknownNamespaceKind = errors.New("unknown namespace kind")
+
+// ErrUnknownValueKind means that a slot, a constant or an optional parameter
+// has a value of an unknown kind
+var ErrUnknownValueKind = errors.New("unknown value kind")
+
+// Diagnostic is a problem found by Validate. Location is the path to the
+// faulty entry, such as "method_body 3: exception 0".
+type Diagnostic struct {
+	Location string
+	Err      error
+}
+
+func (d Diagnostic) Error() string {
+	return d.Location + ": " + d.Err.Error()
+}
+
+var tableNames = [tableCount]string{
+	"integer", "uinteger", "double", "string", "namespace", "ns_set", "multiname",
+}
+
+type validator struct {
+	abc         *AbcFile
+	diagnostics []Diagnostic
+}
+
+func (v *validator) report(location string, err error) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{location, err})
+}
+
+// refs returns a visitor checking constant pool references, reporting them
+// at the given location
+func (v *validator) refs(location string) refVisitor {
+	return func(t cpoolTable, ref *uint32) {
+		if *ref != 0 && int64(*ref) >= int64(v.abc.ConstantPool.tableLen(t)) {
+			v.report(fmt.Sprintf("%v: %v %v", location, tableNames[t], *ref), ErrIndexOutOfRange)
+		}
+	}
+}
+
+// index checks a reference to a method, a class or a metadata
+func (v *validator) index(location, table string, i uint32, n int) {
+	if int64(i) >= int64(n) {
+		v.report(fmt.Sprintf("%v: %v %v", location, table, i), ErrIndexOutOfRange)
+	}
+}
+
+// valueKind checks the kind of a constant value
+func (v *validator) valueKind(location string, kind uint8) {
+	if _, ok := valueTable(kind); ok {
+		return
+	}
+	switch kind {
+	case SlotKindTrue, SlotKindFalse, SlotKindNull, SlotKindUndefined:
+	default:
+		v.report(fmt.Sprintf("%v: value kind %#x", location, kind), ErrUnknownValueKind)
+	}
+}
+
+// Validate checks the structure of the file before its extraction: constant
+// pool references, method, class and metadata indexes, the kinds of the
+// entries, the agreement between counts and between flags and the fields
+// they enable, and the method bodies with their code and exceptions.
+// It returns the problems found, located in the file.
+func Validate(abc AbcFile) []Diagnostic {
+	v := validator{abc: &abc}
+	v.validateCpool()
+	for i := range abc.Methods {
+		v.validateMethod(i, &abc.Methods[i])
+	}
+	for i := range abc.Metadatas {
+		m := &abc.Metadatas[i]
+		visit := v.refs(fmt.Sprintf("metadata %v", i))
+		visit(tableString, &m.Names)
+		for j := range m.Items {
+			visit(tableString, &m.Items[j].Key)
+			visit(tableString, &m.Items[j].Value)
+		}
+	}
+	if len(abc.Instances) != len(abc.Classes) {
+		v.report(fmt.Sprintf("instance %v, class %v", len(abc.Instances), len(abc.Classes)), ErrLengthMismatch)
+	}
+	for i := range abc.Instances {
+		v.validateInstance(i, &abc.Instances[i])
+	}
+	for i, c := range abc.Classes {
+		location := fmt.Sprintf("class %v", i)
+		v.index(location, "method", c.CInit, len(abc.Methods))
+		v.validateTraits(location, c.Traits)
+	}
+	for i, s := range abc.Scripts {
+		location := fmt.Sprintf("script %v", i)
+		v.index(location, "method", s.Init, len(abc.Methods))
+		v.validateTraits(location, s.Traits)
+	}
+	bodies := map[uint32]int{}
+	for i := range abc.MethodBodies {
+		location := fmt.Sprintf("method_body %v", i)
+		body := abc.MethodBodies[i]
+		v.index(location, "method", body.Method, len(abc.Methods))
+		if first, ok := bodies[body.Method]; ok {
+			v.report(fmt.Sprintf("%v: method %v, method_body %v", location, body.Method, first), ErrDuplicateMethodBody)
+		} else {
+			bodies[body.Method] = i
+		}
+		v.validateBody(location, body)
+	}
+	return v.diagnostics
+}
+
+func (v *validator) validateCpool() {
+	c := &v.abc.ConstantPool
+	for i := 1; i < len(c.Namespaces); i++ {
+		switch c.Namespaces[i].Kind {
+		case NamespaceKindNamespace, NamespaceKindPackageNamespace, NamespaceKindPackageInternalNs,
+			NamespaceKindProtectedNamespace, NamespaceKindExplicitNamespace, NamespaceKindStaticProtectedNs,
+			NamespaceKindPrivateNs:
+		default:
+			v.report(fmt.Sprintf("namespace %v", i), ErrUnknownNamespaceKind)
+		}
+		c.walkEntry(tableNamespace, uint32(i), v.refs(fmt.Sprintf("namespace %v", i)))
+	}
+	for i := 1; i < len(c.NsSets); i++ {
+		c.walkEntry(tableNsSet, uint32(i), v.refs(fmt.Sprintf("ns_set %v", i)))
+	}
+	for i := 1; i < len(c.Multinames); i++ {
+		switch c.Multinames[i].Kind {
+		case MultinameKindQName, MultinameKindQNameA, MultinameKindRTQName, MultinameKindRTQNameA,
+			MultinameKindRTQNameL, MultinameKindRTQNameLA, MultinameKindMultiname, MultinameKindMultinameA,
+			MultinameKindMultinameL, MultinameKindMultinameLA, MultinameKindTypename:
+		default:
+			v.report(fmt.Sprintf("multiname %v", i), ErrUnknownMultinameKind)
+		}
+		c.walkEntry(tableMultiname, uint32(i), v.refs(fmt.Sprintf("multiname %v", i)))
+	}
+}
+
+func (v *validator) validateMethod(i int, m *MethodInfo) {
+	location := fmt.Sprintf("method %v", i)
+	visit := v.refs(location)
+	visit(tableMultiname, &m.ReturnType)
+	for j := range m.ParamTypes {
+		visit(tableMultiname, &m.ParamTypes[j])
+	}
+	visit(tableString, &m.Name)
+	if int(m.ParamCount) != len(m.ParamTypes) {
+		v.report(fmt.Sprintf("%v: param_count %v, %v param types", location, m.ParamCount, len(m.ParamTypes)), ErrLengthMismatch)
+	}
+
+	options := m.OptionInfo.Options
+	if (m.Flags&MethodHasOptional != 0) != (len(options) > 0) {
+		v.report(location+": option_info", ErrFlagMismatch)
+	}
+	if len(options) > len(m.ParamTypes) {
+		v.report(fmt.Sprintf("%v: %v options, %v params", location, len(options), len(m.ParamTypes)), ErrLengthMismatch)
+	}
+	for j := range options {
+		o := &options[j]
+		optionLocation := fmt.Sprintf("%v: option %v", location, j)
+		v.valueKind(optionLocation, o.Kind)
+		if table, ok := valueTable(o.Kind); ok {
+			v.refs(optionLocation)(table, &o.Value)
+		}
+	}
+
+	names := m.ParamInfo.ParamNames
+	if m.Flags&MethodHasParamNames == 0 {
+		if len(names) > 0 {
+			v.report(location+": param_info", ErrFlagMismatch)
+		}
+	} else if len(names) != len(m.ParamTypes) {
+		v.report(fmt.Sprintf("%v: %v param names, %v params", location, len(names), len(m.ParamTypes)), ErrLengthMismatch)
+	}
+	for j := range names {
+		visit(tableString, &names[j])
+	}
+}
+
+func (v *validator) validateInstance(i int, instance *InstanceInfo) {
+	location := fmt.Sprintf("instance %v", i)
+	visit := v.refs(location)
+	visit(tableMultiname, &instance.Name)
+	visit(tableMultiname, &instance.SuperName)
+	if instance.Flags&InstanceInfoClassProtectedNs != 0 {
+		visit(tableNamespace, &instance.ProtectedNs)
+	} else if instance.ProtectedNs != 0 {
+		v.report(location+": protected_ns", ErrFlagMismatch)
+	}
+	for j := range instance.Interfaces {
+		visit(tableMultiname, &instance.Interfaces[j])
+	}
+	v.index(location, "method", instance.IInit, len(v.abc.Methods))
+	v.validateTraits(location, instance.Traits)
+}
+
+func (v *validator) validateTraits(location string, traits []TraitsInfo) {
+	for i := range traits {
+		t := &traits[i]
+		traitLocation := fmt.Sprintf("%v: trait %v", location, i)
+		switch t.GetType() {
+		case TraitsInfoSlot, TraitsInfoConst:
+			if t.VIndex != 0 {
+				v.valueKind(traitLocation, t.VKind)
+			}
+		case TraitsInfoMethod, TraitsInfoGetter, TraitsInfoSetter:
+			v.index(traitLocation, "method", t.Method, len(v.abc.Methods))
+		case TraitsInfoFunction:
+			v.index(traitLocation, "method", t.Function, len(v.abc.Methods))
+		case TraitsInfoClass:
+			v.index(traitLocation, "class", t.ClassI, len(v.abc.Classes))
+		default:
+			v.report(fmt.Sprintf("%v: kind %#x", traitLocation, t.GetType()), ErrUnknownTraitsInfoKind)
+			continue
+		}
+		walkTraits(traits[i:i+1], v.refs(traitLocation))
+		if t.Kind&TraitsInfoAttributeMetadata == 0 && len(t.Metadatas) > 0 {
+			v.report(traitLocation+": metadata", ErrFlagMismatch)
+		}
+		for _, m := range t.Metadatas {
+			v.index(traitLocation, "metadata", m, len(v.abc.Metadatas))
+		}
+	}
+}
+
+// instrIndexes checks the method and class indexes of the operands of
+// newfunction, newclass and callstatic, and the exception index of newcatch
+func (v *validator) instrIndexes(location string, instr Instr, body MethodBodyInfo) {
+	for _, o := range instr.TypedOperands() {
+		switch o.Type {
+		case OperandTypeMethod:
+			v.index(location, "method", o.Value, len(v.abc.Methods))
+		case OperandTypeClass:
+			v.index(location, "class", o.Value, len(v.abc.Classes))
+		case OperandTypeException:
+			v.index(location, "exception", o.Value, len(body.Exceptions))
+		}
+	}
+}
+
+func (v *validator) validateBody(location string, body MethodBodyInfo) {
+	body.Instructions = nil
+	if err := body.Disassemble(); err != nil {
+		v.report(location, err)
+	} else {
+		for i := range body.Instructions {
+			instrLocation := fmt.Sprintf("%v: instruction %v", location, i)
+			walkInstrs(body.Instructions[i:i+1], v.refs(instrLocation))
+			v.instrIndexes(instrLocation, body.Instructions[i], body)
+		}
+	}
+	size := uint32(len(body.Code))
+	for i := range body.Exceptions {
+		e := &body.Exceptions[i]
+		exceptionLocation := fmt.Sprintf("%v: exception %v", location, i)
+		if e.From > e.To || e.To > size || e.Target >= size {
+			v.report(exceptionLocation, ErrInvalidExceptionRange)
+		}
+		visit := v.refs(exceptionLocation)
+		visit(tableMultiname, &e.ExcType)
+		visit(tableMultiname, &e.VarName)
+	}
+	v.validateTraits(location, body.Traits)
+}
@@ -0,0 +1,207 @@
+package bytecode
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValidate_Fixtures(t *testing.T) {
+	tests := []struct {
+		name        string
+		diagnostics int
+	}{
+		{"obf1", 0},
+		// the obfuscator left 7 junk bodies creating a function and a class
+		// of indexes out of range
+		{"obf2", 14},
+	}
+	for _, tt := range tests {
+		file := openFixture(t, tt.name)
+		a, err := Parse(NewReader(file))
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", tt.name, err)
+		}
+		if err = file.Close(); err != nil {
+			t.Errorf("file.close: %v", err)
+		}
+		d := Validate(a)
+		if len(d) != tt.diagnostics {
+			t.Errorf("%v: expected %v diagnostics, got %v", tt.name, tt.diagnostics, d)
+		}
+		for _, diagnostic := range d {
+			if diagnostic.Err != ErrIndexOutOfRange {
+				t.Errorf("%v: expected %v, got %v", tt.name, ErrIndexOutOfRange, diagnostic)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() AbcFile {
+		return AbcFile{
+			ConstantPool: CpoolInfo{
+				Integers:   []int32{0, 1},
+				Strings:    []string{"", "foo"},
+				Namespaces: []NamespaceInfo{{}, {NamespaceKindPackageNamespace, 1}},
+				Multinames: []MultinameInfo{{}, {Kind: MultinameKindQName, Namespace: 1, Name: 1}},
+			},
+			Methods:   []MethodInfo{{}, {}, {ParamCount: 1, ParamTypes: []uint32{1}}},
+			Metadatas: []MetadataInfo{{Names: 1}},
+			Instances: []InstanceInfo{{Name: 1, Traits: []TraitsInfo{
+				{Name: 1, Kind: TraitsInfoConst, VKind: SlotKindInt, VIndex: 1},
+			}}},
+			Classes: []ClassInfo{{CInit: 1}},
+			Scripts: []ScriptInfo{{Init: 2, Traits: []TraitsInfo{
+				{Name: 1, Kind: TraitsInfoClass | TraitsInfoAttributeMetadata, Metadatas: []uint32{0}},
+			}}},
+			MethodBodies: []MethodBodyInfo{{
+				Method: 2,
+				// getlex 1; returnvalue
+				Code:       []byte{0x60, 0x01, 0x48},
+				Exceptions: []ExceptionInfo{{From: 0, To: 2, Target: 2, ExcType: 1}},
+			}},
+		}
+	}
+	if d := Validate(valid()); len(d) != 0 {
+		t.Fatalf("expected no diagnostics, got %v", d)
+	}
+
+	tests := []struct {
+		name string
+		edit func(a *AbcFile)
+		want []Diagnostic
+	}{
+		{
+			"cpool reference",
+			func(a *AbcFile) { a.ConstantPool.Multinames[1].Namespace = 5 },
+			[]Diagnostic{{"multiname 1: namespace 5", ErrIndexOutOfRange}},
+		},
+		{
+			"namespace kind",
+			func(a *AbcFile) { a.ConstantPool.Namespaces[1].Kind = 0x42 },
+			[]Diagnostic{{"namespace 1", ErrUnknownNamespaceKind}},
+		},
+		{
+			"multiname kind",
+			func(a *AbcFile) {
+				a.ConstantPool.Multinames = append(a.ConstantPool.Multinames, MultinameInfo{Kind: 0x42})
+			},
+			[]Diagnostic{{"multiname 2", ErrUnknownMultinameKind}},
+		},
+		{
+			"param count",
+			func(a *AbcFile) { a.Methods[2].ParamCount = 2 },
+			[]Diagnostic{{"method 2: param_count 2, 1 param types", ErrLengthMismatch}},
+		},
+		{
+			"options",
+			func(a *AbcFile) {
+				a.Methods[2].OptionInfo.Options = []OptionDetail{{Value: 1, Kind: SlotKindInt}, {Kind: 0x42}}
+			},
+			[]Diagnostic{
+				{"method 2: option_info", ErrFlagMismatch},
+				{"method 2: 2 options, 1 params", ErrLengthMismatch},
+				{"method 2: option 1: value kind 0x42", ErrUnknownValueKind},
+			},
+		},
+		{
+			"param names",
+			func(a *AbcFile) {
+				a.Methods[2].Flags = MethodHasParamNames
+				a.Methods[1].ParamInfo.ParamNames = []uint32{1}
+			},
+			[]Diagnostic{
+				{"method 1: param_info", ErrFlagMismatch},
+				{"method 2: 0 param names, 1 params", ErrLengthMismatch},
+			},
+		},
+		{
+			"instances and classes",
+			func(a *AbcFile) { a.Classes = append(a.Classes, ClassInfo{}) },
+			[]Diagnostic{{"instance 1, class 2", ErrLengthMismatch}},
+		},
+		{
+			"protected namespace",
+			func(a *AbcFile) { a.Instances[0].ProtectedNs = 1 },
+			[]Diagnostic{{"instance 0: protected_ns", ErrFlagMismatch}},
+		},
+		{
+			"indexes",
+			func(a *AbcFile) {
+				a.Instances[0].IInit = 3
+				a.Classes[0].CInit = 4
+				a.Scripts[0].Traits[0].ClassI = 1
+				a.Scripts[0].Traits[0].Metadatas = []uint32{1}
+			},
+			[]Diagnostic{
+				{"instance 0: method 3", ErrIndexOutOfRange},
+				{"class 0: method 4", ErrIndexOutOfRange},
+				{"script 0: trait 0: class 1", ErrIndexOutOfRange},
+				{"script 0: trait 0: metadata 1", ErrIndexOutOfRange},
+			},
+		},
+		{
+			"trait",
+			func(a *AbcFile) {
+				a.Instances[0].Traits[0].VIndex = 2
+				a.Instances[0].Traits = append(a.Instances[0].Traits, TraitsInfo{Kind: 0x0f})
+				a.Scripts[0].Traits[0].Kind = TraitsInfoClass
+			},
+			[]Diagnostic{
+				{"instance 0: trait 0: integer 2", ErrIndexOutOfRange},
+				{"instance 0: trait 1: kind 0xf", ErrUnknownTraitsInfoKind},
+				{"script 0: trait 0: metadata", ErrFlagMismatch},
+			},
+		},
+		{
+			"method bodies",
+			func(a *AbcFile) {
+				body := a.MethodBodies[0]
+				body.Code = []byte{0x60, 0x02, 0x48}
+				body.Exceptions = []ExceptionInfo{{From: 2, To: 1, Target: 3}}
+				a.MethodBodies = append(a.MethodBodies, body, MethodBodyInfo{Method: 3, Code: []byte{0xff}})
+			},
+			[]Diagnostic{
+				{"method_body 1: method 2, method_body 0", ErrDuplicateMethodBody},
+				{"method_body 1: instruction 0: multiname 2", ErrIndexOutOfRange},
+				{"method_body 1: exception 0", ErrInvalidExceptionRange},
+				{"method_body 2: method 3", ErrIndexOutOfRange},
+				{"method_body 2", fmt.Errorf("unknown instruction 255")},
+			},
+		},
+		{
+			"instruction indexes",
+			func(a *AbcFile) {
+				// newfunction 5; pop; getlocal_0; newclass 3; pop; callstatic 4 0; returnvalue
+				a.MethodBodies[0].Code = []byte{0x40, 0x05, 0x29, 0xd0, 0x58, 0x03, 0x29, 0x44, 0x04, 0x00, 0x48}
+				a.MethodBodies[0].Exceptions = nil
+			},
+			[]Diagnostic{
+				{"method_body 0: instruction 0: method 5", ErrIndexOutOfRange},
+				{"method_body 0: instruction 3: class 3", ErrIndexOutOfRange},
+				{"method_body 0: instruction 5: method 4", ErrIndexOutOfRange},
+			},
+		},
+		{
+			"newcatch index",
+			func(a *AbcFile) {
+				// newcatch 1; pop; returnvoid, the body has one exception
+				a.MethodBodies[0].Code = []byte{0x5a, 0x01, 0x29, 0x47}
+			},
+			[]Diagnostic{
+				{"method_body 0: instruction 0: exception 1", ErrIndexOutOfRange},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := valid()
+			tt.edit(&a)
+			// diagnostics are compared as strings since disassembly errors
+			// are not comparable
+			if got := Validate(a); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
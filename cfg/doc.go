@@ -0,0 +1,2 @@
+// Package cfg builds the control-flow graph of a disassembled method body
+package cfg
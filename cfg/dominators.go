@@ -0,0 +1,92 @@
+package cfg
+
+// postorder returns the blocks reachable from the entry block in postorder
+func (g *Graph) postorder() []int {
+	var order []int
+	visited := make([]bool, len(g.Blocks))
+	var visit func(int)
+	visit = func(b int) {
+		visited[b] = true
+		for _, s := range g.Successors(b) {
+			if !visited[s] {
+				visit(s)
+			}
+		}
+		order = append(order, b)
+	}
+	if len(g.Blocks) > 0 {
+		visit(0)
+	}
+	return order
+}
+
+// Dominators returns the immediate dominator of each block. The entry block
+// and the blocks unreachable from it have no immediate dominator (-1).
+//
+// It implements "A Simple, Fast Dominance Algorithm" by Cooper, Harvey
+// and Kennedy.
+func (g *Graph) Dominators() []int {
+	order := g.postorder()
+	rank := make([]int, len(g.Blocks))
+	for i := range rank {
+		rank[i] = -1
+	}
+	for i, b := range order {
+		rank[b] = i
+	}
+	idom := make([]int, len(g.Blocks))
+	for i := range idom {
+		idom[i] = -1
+	}
+	if len(order) == 0 {
+		return idom
+	}
+	entry := order[len(order)-1]
+	idom[entry] = entry
+
+	intersect := func(a, b int) int {
+		for a != b {
+			for rank[a] < rank[b] {
+				a = idom[a]
+			}
+			for rank[b] < rank[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(order) - 2; i >= 0; i-- {
+			b := order[i]
+			newIdom := -1
+			for _, p := range g.Predecessors(b) {
+				if idom[p] == -1 {
+					continue
+				}
+				if newIdom == -1 {
+					newIdom = p
+				} else {
+					newIdom = intersect(p, newIdom)
+				}
+			}
+			if idom[b] != newIdom {
+				idom[b] = newIdom
+				changed = true
+			}
+		}
+	}
+	idom[entry] = -1
+	return idom
+}
+
+// Dominates reports whether every path from the entry block to b goes
+// through a, given the immediate dominators returned by Dominators
+func Dominates(idom []int, a, b int) bool {
+	for ; b != -1; b = idom[b] {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package cfg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var edgeStyles = map[EdgeKind]string{
+	EdgeFallthrough: "",
+	EdgeBranch:      " [color=blue]",
+	EdgeSwitch:      " [color=purple]",
+	EdgeException:   " [style=dashed, color=red]",
+}
+
+// WriteDot writes the graph in the Graphviz DOT language, one box per block
+// listing its instructions
+func (g *Graph) WriteDot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph method_%v {\n", g.Body.Method)
+	fmt.Fprintf(bw, "\tnode [shape=box, fontname=\"monospace\"];\n")
+	for _, b := range g.Blocks {
+		var label string
+		for i := b.Start; i < b.End; i++ {
+			instr := g.Body.Instructions[i]
+			label += fmt.Sprintf("%v: %v", g.Offsets[i], instr.Model.Name)
+			for _, operand := range instr.Operands {
+				label += fmt.Sprintf(" %v", int32(operand))
+			}
+			label += "\\l"
+		}
+		label = strings.Replace(label, "\"", "\\\"", -1)
+		fmt.Fprintf(bw, "\tb%v [label=\"%v\"];\n", b.Index, label)
+	}
+	for _, b := range g.Blocks {
+		for _, e := range b.Succs {
+			fmt.Fprintf(bw, "\tb%v -> b%v%v;\n", e.From, e.To, edgeStyles[e.Kind])
+		}
+	}
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
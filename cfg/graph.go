@@ -0,0 +1,201 @@
+package cfg
+
+import (
+	"sort"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+// EdgeKind is the kind of a control-flow edge
+type EdgeKind uint8
+
+// These are possible kinds of edges
+const (
+	EdgeFallthrough = EdgeKind(iota)
+	EdgeBranch
+	EdgeSwitch
+	EdgeException
+)
+
+const opLookupswitch = 0x1b
+
+// Edge represents a control-flow edge between two blocks
+type Edge struct {
+	From int
+	To   int
+	Kind EdgeKind
+}
+
+// Block represents a basic block: the instructions from Start to End
+// (excluded) of the method body
+type Block struct {
+	Index int
+	Start int
+	End   int
+	Succs []Edge
+	Preds []Edge
+}
+
+// Graph represents the control-flow graph of a method body.
+// The entry block is always the first one.
+type Graph struct {
+	Body    *bytecode.MethodBodyInfo
+	Offsets []uint32
+	Blocks  []Block
+
+	blockOf []int
+}
+
+// IsTerminator reports whether the instruction never falls through to the
+// next one (see bytecode.Instr.IsTerminator)
+func IsTerminator(instr bytecode.Instr) bool {
+	return instr.IsTerminator()
+}
+
+type builder struct {
+	body    *bytecode.MethodBodyInfo
+	offsets []uint32
+	indexes map[uint32]int
+	leaders []bool
+	targets [][]int
+}
+
+// Build builds the control-flow graph of a disassembled method body.
+// Exception ranges that do not start or end on an instruction, as found in
+// obfuscated code, cover the instructions starting inside them.
+func Build(body *bytecode.MethodBodyInfo) (*Graph, error) {
+	offsets, err := body.Offsets()
+	if err != nil {
+		return nil, err
+	}
+	b := builder{body: body, offsets: offsets}
+	if err = b.findLeaders(); err != nil {
+		return nil, err
+	}
+	g := b.buildBlocks()
+	b.buildEdges(g)
+	return g, nil
+}
+
+func (b *builder) indexOf(offset int64) (int, bool) {
+	i, ok := b.indexes[uint32(offset)]
+	return i, ok && offset >= 0 && i < len(b.body.Instructions)
+}
+
+func (b *builder) findLeaders() error {
+	instrs := b.body.Instructions
+	b.indexes = make(map[uint32]int, len(b.offsets))
+	for i, offset := range b.offsets {
+		b.indexes[offset] = i
+	}
+	b.leaders = make([]bool, len(instrs)+1)
+	b.leaders[0] = true
+	b.targets = make([][]int, len(instrs))
+	for i, instr := range instrs {
+		for _, offset := range instr.BranchTargets(b.offsets[i], b.offsets[i+1]) {
+			target, ok := b.indexOf(offset)
+			if !ok {
+				return bytecode.ErrInvalidBranchTarget
+			}
+			b.leaders[target] = true
+			b.targets[i] = append(b.targets[i], target)
+		}
+		if b.targets[i] != nil || IsTerminator(instr) {
+			b.leaders[i+1] = true
+		}
+	}
+	for _, e := range b.body.Exceptions {
+		target, ok := b.indexOf(int64(e.Target))
+		if !ok {
+			return bytecode.ErrInvalidBranchTarget
+		}
+		b.leaders[target] = true
+		for _, offset := range []uint32{e.From, e.To} {
+			if i, ok := b.indexes[offset]; ok {
+				b.leaders[i] = true
+			}
+		}
+	}
+	return nil
+}
+
+func (b *builder) buildBlocks() *Graph {
+	n := len(b.body.Instructions)
+	g := &Graph{Body: b.body, Offsets: b.offsets, blockOf: make([]int, n)}
+	for i := 0; i < n; i++ {
+		if b.leaders[i] {
+			if len(g.Blocks) > 0 {
+				g.Blocks[len(g.Blocks)-1].End = i
+			}
+			g.Blocks = append(g.Blocks, Block{Index: len(g.Blocks), Start: i})
+		}
+		g.blockOf[i] = len(g.Blocks) - 1
+	}
+	if len(g.Blocks) > 0 {
+		g.Blocks[len(g.Blocks)-1].End = n
+	}
+	return g
+}
+
+func (g *Graph) addEdge(from, to int, kind EdgeKind) {
+	e := Edge{from, to, kind}
+	g.Blocks[from].Succs = append(g.Blocks[from].Succs, e)
+	g.Blocks[to].Preds = append(g.Blocks[to].Preds, e)
+}
+
+func (b *builder) buildEdges(g *Graph) {
+	for i := range g.Blocks {
+		last := g.Blocks[i].End - 1
+		instr := b.body.Instructions[last]
+		kind := EdgeBranch
+		if instr.Model.Code == opLookupswitch {
+			kind = EdgeSwitch
+		}
+		for _, target := range b.targets[last] {
+			g.addEdge(i, g.blockOf[target], kind)
+		}
+		if !IsTerminator(instr) && i+1 < len(g.Blocks) {
+			g.addEdge(i, i+1, EdgeFallthrough)
+		}
+	}
+	for _, e := range b.body.Exceptions {
+		handler := g.blockOf[b.indexes[e.Target]]
+		for i, block := range g.Blocks {
+			for j := block.Start; j < block.End; j++ {
+				if b.offsets[j] >= e.From && b.offsets[j] < e.To {
+					g.addEdge(i, handler, EdgeException)
+					break
+				}
+			}
+		}
+	}
+}
+
+// BlockOf returns the index of the block containing the instruction
+func (g *Graph) BlockOf(instr int) int {
+	return g.blockOf[instr]
+}
+
+func uniqueBlocks(edges []Edge, end func(Edge) int) []int {
+	seen := map[int]bool{}
+	var blocks []int
+	for _, e := range edges {
+		if b := end(e); !seen[b] {
+			seen[b] = true
+			blocks = append(blocks, b)
+		}
+	}
+	sort.Ints(blocks)
+	return blocks
+}
+
+// Successors returns the indexes of the successors of a block, including
+// exception handlers
+func (g *Graph) Successors(block int) []int {
+	return uniqueBlocks(g.Blocks[block].Succs, func(e Edge) int { return e.To })
+}
+
+// Predecessors returns the indexes of the predecessors of a block
+func (g *Graph) Predecessors(block int) []int {
+	return uniqueBlocks(g.Blocks[block].Preds, func(e Edge) int { return e.From })
+}
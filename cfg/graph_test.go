@@ -0,0 +1,182 @@
+package cfg
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+func openFixture(t *testing.T, name string) *os.File {
+	file, err := os.Open(fmt.Sprintf("../bytecode/fixtures/%v.abc", name))
+	if err != nil {
+		t.Fatalf("openFixture: %v", err)
+	}
+	return file
+}
+
+func buildBody(t *testing.T, code []byte, exceptions []bytecode.ExceptionInfo) *Graph {
+	body := &bytecode.MethodBodyInfo{Code: code, Exceptions: exceptions}
+	if err := body.Disassemble(); err != nil {
+		t.Fatalf("Disassemble: %v", err)
+	}
+	g, err := Build(body)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	return g
+}
+
+func TestBuild_Fixtures(t *testing.T) {
+	for _, name := range []string{"obf1", "obf2"} {
+		file := openFixture(t, name)
+		a, err := bytecode.Parse(bytecode.NewReader(file))
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if err = file.Close(); err != nil {
+			t.Errorf("file.close: %v", err)
+		}
+
+		for i := range a.MethodBodies {
+			body := &a.MethodBodies[i]
+			if err = body.Disassemble(); err != nil {
+				t.Fatalf("%v: method_body %v: %v", name, i, err)
+			}
+			g, err := Build(body)
+			if err != nil {
+				t.Fatalf("%v: method_body %v: %v", name, i, err)
+			}
+			next := 0
+			for _, b := range g.Blocks {
+				if b.Start != next || b.End <= b.Start {
+					t.Errorf("%v: method_body %v: block %v covers [%v, %v)", name, i, b.Index, b.Start, b.End)
+				}
+				next = b.End
+				for _, e := range b.Succs {
+					found := false
+					for _, p := range g.Blocks[e.To].Preds {
+						found = found || p == e
+					}
+					if !found {
+						t.Errorf("%v: method_body %v: edge %v is not a predecessor", name, i, e)
+					}
+				}
+			}
+			if next != len(body.Instructions) {
+				t.Errorf("%v: method_body %v: expected %v, got %v", name, i, len(body.Instructions), next)
+			}
+			idom := g.Dominators()
+			for b := range g.Blocks {
+				if idom[b] != -1 && !Dominates(idom, 0, b) {
+					t.Errorf("%v: method_body %v: entry does not dominate block %v", name, i, b)
+				}
+			}
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       []byte
+		exceptions []bytecode.ExceptionInfo
+		blocks     [][2]int
+		succs      [][]int
+		idom       []int
+	}{
+		{
+			"straight",
+			[]byte{0xd0, 0x30, 0x47},
+			nil,
+			[][2]int{{0, 3}},
+			[][]int{nil},
+			[]int{-1},
+		},
+		{
+			// if (true) { "x" } ; return
+			"if",
+			[]byte{0x26, 0x12, 0x03, 0x00, 0x00, 0x2c, 0x01, 0x29, 0x47},
+			nil,
+			[][2]int{{0, 2}, {2, 4}, {4, 5}},
+			[][]int{{1, 2}, {2}, nil},
+			[]int{-1, 0, 0},
+		},
+		{
+			// while loop jumping back to its condition
+			"loop",
+			[]byte{0x10, 0x01, 0x00, 0x00, 0x02, 0x26, 0x11, 0xfa, 0xff, 0xff, 0x47},
+			nil,
+			[][2]int{{0, 1}, {1, 2}, {2, 4}, {4, 5}},
+			[][]int{{2}, {2}, {1, 3}, nil},
+			[]int{-1, 2, 0, 2},
+		},
+		{
+			"lookupswitch",
+			[]byte{0x24, 0x00, 0x1b, 0x0b, 0x00, 0x00, 0x01, 0x0c, 0x00, 0x00, 0x0d, 0x00, 0x00, 0x47, 0x02, 0x47, 0x03},
+			nil,
+			[][2]int{{0, 2}, {2, 3}, {3, 4}, {4, 5}, {5, 6}},
+			[][]int{{1, 2, 3}, nil, {3}, nil, nil},
+			[]int{-1, 0, 0, 0, -1},
+		},
+		{
+			"exception",
+			[]byte{0x02, 0x20, 0x03, 0x29, 0x47},
+			[]bytecode.ExceptionInfo{{From: 1, To: 3, Target: 3}},
+			[][2]int{{0, 1}, {1, 3}, {3, 5}},
+			[][]int{{1}, {2}, nil},
+			[]int{-1, 0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := buildBody(t, tt.code, tt.exceptions)
+			if len(g.Blocks) != len(tt.blocks) {
+				t.Fatalf("expected %v blocks, got %v", len(tt.blocks), len(g.Blocks))
+			}
+			for i, b := range g.Blocks {
+				if b.Start != tt.blocks[i][0] || b.End != tt.blocks[i][1] {
+					t.Errorf("block %v: expected %v, got [%v %v]", i, tt.blocks[i], b.Start, b.End)
+				}
+				if got := g.Successors(i); !reflect.DeepEqual(got, tt.succs[i]) {
+					t.Errorf("block %v: expected successors %v, got %v", i, tt.succs[i], got)
+				}
+			}
+			if got := g.Dominators(); !reflect.DeepEqual(got, tt.idom) {
+				t.Errorf("expected dominators %v, got %v", tt.idom, got)
+			}
+		})
+	}
+}
+
+func TestBuild_InvalidTarget(t *testing.T) {
+	body := &bytecode.MethodBodyInfo{Code: []byte{0x10, 0x01, 0x00, 0x00, 0x2c, 0xac, 0x02}}
+	if err := body.Disassemble(); err != nil {
+		t.Fatalf("Disassemble: %v", err)
+	}
+	if _, err := Build(body); err != bytecode.ErrInvalidBranchTarget {
+		t.Errorf("expected %v, got %v", bytecode.ErrInvalidBranchTarget, err)
+	}
+}
+
+func TestGraph_WriteDot(t *testing.T) {
+	g := buildBody(t, []byte{0x02, 0x20, 0x03, 0x29, 0x47}, []bytecode.ExceptionInfo{{From: 1, To: 3, Target: 3}})
+	buf := &bytes.Buffer{}
+	if err := g.WriteDot(buf); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	for _, want := range []string{
+		"digraph method_0 {",
+		`b1 [label="1: pushnull\l2: throw\l"];`,
+		"b0 -> b1;",
+		"b1 -> b2 [style=dashed, color=red];",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q in %v", want, buf.String())
+		}
+	}
+}
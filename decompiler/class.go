@@ -0,0 +1,328 @@
+package decompiler
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kelvyne/as3"
+	"github.com/kelvyne/as3/bytecode"
+)
+
+// knownNamespaces names the namespaces of the runtime usually opened by
+// actionscript code
+var knownNamespaces = map[string]string{
+	"http://adobe.com/AS3/2006/builtin":                  "AS3",
+	"http://www.adobe.com/2006/actionscript/flash/proxy": "flash_proxy",
+	"http://www.adobe.com/2006/flex/mx/internal":         "mx_internal",
+}
+
+// classWriter prints the source file declaring a class. Imports collects
+// the definitions of other packages the declarations and the bodies refer
+// to. A stub declares the class without decompiling its code.
+type classWriter struct {
+	abc     *as3.AbcFile
+	class   *as3.Class
+	pkg     string
+	imports map[string]bool
+	stub    bool
+	p       printer
+}
+
+func newClassWriter(abc *as3.AbcFile, c *as3.Class) *classWriter {
+	return &classWriter{abc: abc, class: c, pkg: packageName(c), imports: map[string]bool{}}
+}
+
+// packageName returns the package declaring a class, empty for the top
+// level package and for the classes private to a script
+func packageName(c *as3.Class) string {
+	switch c.Namespace.Kind {
+	case bytecode.NamespaceKindPackageNamespace, bytecode.NamespaceKindPackageInternalNs:
+		return c.Namespace.URI
+	}
+	return ""
+}
+
+// WriteClass writes the actionscript source file of a linked class: its
+// package, imports, metadata and declaration, followed by its fields,
+// static initialization, constructor and methods with their decompiled
+// bodies. Bodies that cannot be decompiled are replaced by a comment.
+func WriteClass(w io.Writer, abc *as3.AbcFile, c *as3.Class) error {
+	return newClassWriter(abc, c).write(w)
+}
+
+func (cw *classWriter) write(w io.Writer) error {
+	header := cw.declaration()
+	members := &bytes.Buffer{}
+	cw.p = printer{bufio.NewWriter(members)}
+	cw.members(2)
+	cw.p.w.Flush()
+
+	out := bufio.NewWriter(w)
+	cw.p = printer{out}
+	if cw.pkg == "" {
+		cw.p.line(0, "package {")
+	} else {
+		cw.p.line(0, "package %v {", cw.pkg)
+	}
+	if imports := cw.importList(); len(imports) > 0 {
+		cw.p.line(0, "")
+		for _, imp := range imports {
+			cw.p.line(1, "import %v;", imp)
+		}
+	}
+	cw.p.line(0, "")
+	for _, line := range header {
+		cw.p.line(1, "%v", line)
+	}
+	out.Write(members.Bytes())
+	cw.p.line(1, "}")
+	cw.p.line(0, "}")
+	return out.Flush()
+}
+
+// importList returns the sorted imports, without the definitions of the
+// package of the class
+func (cw *classWriter) importList() []string {
+	var imports []string
+	for imp := range cw.imports {
+		if i := strings.LastIndex(imp, "."); i >= 0 && imp[:i] != cw.pkg {
+			imports = append(imports, imp)
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// typeName formats a type annotation and records the import it needs
+func (cw *classWriter) typeName(m as3.Multiname) string {
+	cw.addImport(m)
+	return m.TypeString()
+}
+
+func (cw *classWriter) addImport(m as3.Multiname) {
+	for _, p := range m.Params {
+		cw.addImport(p)
+	}
+	if len(m.Namespaces) != 1 || m.Name == "" {
+		return
+	}
+	if ns := m.Namespaces[0]; ns.Kind == bytecode.NamespaceKindPackageNamespace && ns.URI != "" {
+		cw.imports[ns.URI+"."+m.Name] = true
+	}
+}
+
+// declaration returns the lines declaring the class: its metadata and the
+// class or interface header, up to the opening brace
+func (cw *classWriter) declaration() []string {
+	c := cw.class
+	var lines []string
+	for _, md := range c.Metadatas {
+		if s, ok := metadataString(md); ok {
+			lines = append(lines, s)
+		}
+	}
+	flags := c.InstanceInfo.Flags
+	interfaces := make([]string, len(c.Interfaces))
+	for i, m := range c.Interfaces {
+		interfaces[i] = cw.typeName(m)
+	}
+
+	mods := []string{cw.modifier(c.Namespace)}
+	if flags&bytecode.InstanceInfoClassInterface != 0 {
+		s := strings.Join(append(mods, "interface", c.Name), " ")
+		if len(interfaces) > 0 {
+			s += " extends " + strings.Join(interfaces, ", ")
+		}
+		return append(lines, s+" {")
+	}
+	if flags&bytecode.InstanceInfoClassFinal != 0 {
+		mods = append(mods, "final")
+	}
+	if flags&bytecode.InstanceInfoClassSealed == 0 {
+		mods = append(mods, "dynamic")
+	}
+	s := strings.Join(append(mods, "class", c.Name), " ")
+	if c.SuperName.Kind != 0 && c.SuperName.Name != "Object" {
+		s += " extends " + cw.typeName(c.SuperName)
+	}
+	if len(interfaces) > 0 {
+		s += " implements " + strings.Join(interfaces, ", ")
+	}
+	return append(lines, s+" {")
+}
+
+func (cw *classWriter) isInterface() bool {
+	return cw.class.InstanceInfo.Flags&bytecode.InstanceInfoClassInterface != 0
+}
+
+// metadataString formats a metadata as [Name(key="value", "value")]. The
+// metadata the compiler adds for debugging are left out.
+func metadataString(md as3.Metadata) (string, bool) {
+	if strings.HasPrefix(md.Name, "__go_to_") {
+		return "", false
+	}
+	if len(md.Items) == 0 {
+		return "[" + md.Name + "]", true
+	}
+	items := make([]string, len(md.Items))
+	for i, item := range md.Items {
+		items[i] = strconv.Quote(item.Value)
+		if item.Key != "" {
+			items[i] = item.Key + "=" + items[i]
+		}
+	}
+	return "[" + md.Name + "(" + strings.Join(items, ", ") + ")]", true
+}
+
+// modifier returns the access modifier of a namespace. Custom namespaces
+// are named after the constant declaring them, when it is found.
+func (cw *classWriter) modifier(ns as3.Namespace) string {
+	switch ns.Kind {
+	case bytecode.NamespaceKindPackageNamespace:
+		return "public"
+	case bytecode.NamespaceKindPackageInternalNs:
+		return "internal"
+	case bytecode.NamespaceKindProtectedNamespace, bytecode.NamespaceKindStaticProtectedNs:
+		return "protected"
+	case bytecode.NamespaceKindPrivateNs:
+		return "private"
+	}
+	if name, ok := knownNamespaces[ns.URI]; ok {
+		return name
+	}
+	for _, s := range cw.abc.Scripts {
+		for _, t := range s.Traits.Slots {
+			if v, ok := t.Default.Value.(as3.Namespace); ok && t.HasDefault && v.URI == ns.URI {
+				if t.Namespace.Kind == bytecode.NamespaceKindPackageNamespace && t.Namespace.URI != "" {
+					cw.imports[t.Namespace.URI+"."+t.Name] = true
+				}
+				return t.Name
+			}
+		}
+	}
+	return "/* " + strconv.Quote(ns.URI) + " */"
+}
+
+// members prints the fields, the static initialization, the constructor and
+// the methods of the class. The statements of the static initializer are
+// printed in a block of the class body, where actionscript runs them.
+func (cw *classWriter) members(indent int) {
+	c := cw.class
+	for _, t := range c.ClassTraits.Slots {
+		cw.slot(t, true, indent)
+	}
+	for _, t := range c.InstanceTraits.Slots {
+		cw.slot(t, false, indent)
+	}
+	if m := cw.method(c.ClassInfo.CInit); m != nil && m.HasBody && !cw.isInterface() && !cw.stub {
+		if d, err := newDecompiler(cw.abc, m, 0, cw.imports); err == nil {
+			if stmts := d.statements(); len(stmts) > 0 {
+				cw.p.line(0, "")
+				cw.p.line(indent, "{")
+				cw.p.block(indent+1, stmts)
+				cw.p.line(indent, "}")
+			}
+		}
+	}
+	if m := cw.method(c.InstanceInfo.IInit); m != nil && !cw.isInterface() {
+		cw.p.line(0, "")
+		cw.function(m, "public", indent)
+	}
+	for _, t := range c.ClassTraits.Methods {
+		cw.methodTrait(t, true, indent)
+	}
+	for _, t := range c.InstanceTraits.Methods {
+		cw.methodTrait(t, false, indent)
+	}
+}
+
+func (cw *classWriter) method(index uint32) *as3.Method {
+	if int(index) < len(cw.abc.Methods) {
+		return &cw.abc.Methods[index]
+	}
+	return nil
+}
+
+func (cw *classWriter) metadata(metadatas []as3.Metadata, indent int) {
+	for _, md := range metadatas {
+		if s, ok := metadataString(md); ok {
+			cw.p.line(indent, "%v", s)
+		}
+	}
+}
+
+// attributes returns the attributes preceding the declaration of a trait
+func (cw *classWriter) attributes(t as3.Trait, static bool) string {
+	var attrs []string
+	if !cw.isInterface() {
+		attrs = append(attrs, cw.modifier(t.Namespace))
+	}
+	if static {
+		attrs = append(attrs, "static")
+	}
+	if t.IsOverride() {
+		attrs = append(attrs, "override")
+	}
+	if t.IsFinal() && t.Method != nil {
+		attrs = append(attrs, "final")
+	}
+	return strings.Join(attrs, " ")
+}
+
+func (cw *classWriter) slot(t as3.Trait, static bool, indent int) {
+	cw.metadata(t.Metadatas, indent)
+	keyword := "var"
+	if t.Source.GetType() == bytecode.TraitsInfoConst {
+		keyword = "const"
+	}
+	s := cw.attributes(t, static) + " " + keyword + " " + t.Name + ":" + cw.typeName(t.Typename)
+	if t.HasDefault {
+		s += " = " + t.Default.String()
+	}
+	cw.p.line(indent, "%v;", s)
+}
+
+func (cw *classWriter) methodTrait(t as3.Trait, static bool, indent int) {
+	if t.Method == nil {
+		return
+	}
+	cw.p.line(0, "")
+	cw.metadata(t.Metadatas, indent)
+	cw.function(t.Method, cw.attributes(t, static), indent)
+}
+
+// function prints the declaration of a method and its body, empty for a
+// stub. Methods of interfaces have no body and those without one elsewhere
+// are native.
+func (cw *classWriter) function(m *as3.Method, attrs string, indent int) {
+	cw.addImport(m.ReturnType)
+	for _, p := range m.ParamTypes {
+		cw.addImport(p)
+	}
+	decl := m.Signature()
+	if attrs != "" {
+		decl = attrs + " " + decl
+	}
+	switch {
+	case cw.isInterface():
+		cw.p.line(indent, "%v;", decl)
+		return
+	case !m.HasBody:
+		cw.p.line(indent, "native %v;", decl)
+		return
+	case cw.stub:
+		cw.p.line(indent, "%v {}", decl)
+		return
+	}
+	cw.p.line(indent, "%v {", decl)
+	if d, err := newDecompiler(cw.abc, m, 0, cw.imports); err != nil {
+		cw.p.line(indent+1, "// %v", err)
+	} else {
+		cw.p.block(indent+1, d.statements())
+	}
+	cw.p.line(indent, "}")
+}
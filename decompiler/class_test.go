@@ -0,0 +1,129 @@
+package decompiler
+
+import (
+	"bytes"
+	"testing"
+)
+
+const classSource = `
+metadata 0 "Event"
+  item "name" "change"
+  item "type" "flash.events.Event"
+end
+
+method 0
+  returns null
+end
+
+method 1
+  returns null
+  param QName(PackageNamespace(""), "String")
+  flags HAS_PARAM_NAMES
+  paramname "name"
+end
+
+method 2
+  returns QName(PackageNamespace(""), "String")
+end
+
+method 3
+  returns null
+end
+
+class 0
+  instance QName(PackageNamespace("com.example"), "Widget")
+    extends QName(PackageNamespace("flash.display"), "Sprite")
+    flags SEALED
+    implements Multiname("IEventDispatcher", [PackageNamespace("flash.events")])
+    iinit 1
+    trait slot QName(PrivateNamespace("*"), "_label") slotid 0 type QName(PackageNamespace(""), "String")
+    trait getter QName(PackageNamespace(""), "label") method 2 metadata 0
+  end
+  cinit 0
+    trait const QName(PackageNamespace(""), "SIZE") slotid 0 type QName(PackageNamespace(""), "int") value Integer(4)
+  end
+end
+
+script 0
+  init 3
+  trait class QName(PackageNamespace("com.example"), "Widget") slotid 1 class 0
+end
+
+body 0
+  method 0
+  maxstack 1
+  localcount 1
+  initscopedepth 0
+  maxscopedepth 1
+  code
+    getlocal_0
+    pushscope
+    returnvoid
+  end
+end
+
+body 1
+  method 1
+  maxstack 2
+  localcount 2
+  initscopedepth 0
+  maxscopedepth 1
+  code
+    getlocal_0
+    pushscope
+    getlocal_0
+    constructsuper 0
+    getlocal_0
+    getlocal_1
+    initproperty QName(PrivateNamespace("*"), "_label")
+    returnvoid
+  end
+end
+
+body 2
+  method 2
+  maxstack 1
+  localcount 1
+  initscopedepth 0
+  maxscopedepth 1
+  code
+    getlocal_0
+    pushscope
+    getlocal_0
+    getproperty QName(PrivateNamespace("*"), "_label")
+    returnvalue
+  end
+end
+`
+
+func TestWriteClass(t *testing.T) {
+	abc := linkSource(t, classSource)
+	buf := &bytes.Buffer{}
+	if err := WriteClass(buf, &abc, &abc.Classes[0]); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	want := `package com.example {
+
+    import flash.display.Sprite;
+    import flash.events.IEventDispatcher;
+
+    public class Widget extends Sprite implements IEventDispatcher {
+        public static const SIZE:int = 4;
+        private var _label:String;
+
+        public function Widget(name:String) {
+            super();
+            this._label = name;
+        }
+
+        [Event(name="change", type="flash.events.Event")]
+        public function get label():String {
+            return this._label;
+        }
+    }
+}
+`
+	if buf.String() != want {
+		t.Errorf("expected:\n%v\ngot:\n%v", want, buf.String())
+	}
+}
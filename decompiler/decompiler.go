@@ -0,0 +1,223 @@
+package decompiler
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/kelvyne/as3"
+	"github.com/kelvyne/as3/bytecode"
+	"github.com/kelvyne/as3/cfg"
+)
+
+// ErrNoBody means that the method has no body to decompile, as native
+// methods and the methods of interfaces
+var ErrNoBody = errors.New("method has no body")
+
+// ErrStackUnderflow means that the code pops more values than it pushed, as
+// obfuscated code does, or more arguments than the stack holds: the values
+// it works on are unknown and the method cannot be decompiled
+var ErrStackUnderflow = errors.New("stack underflow")
+
+// maxClosureDepth limits the decompilation of closures defined in closures
+const maxClosureDepth = 8
+
+// state is the part of the interpretation a speculative execution restores
+type state struct {
+	stack    []expr
+	scopes   []expr
+	declared map[uint32]bool
+	aliases  map[uint32]expr
+
+	// err is the first problem making the statements wrong
+	err error
+}
+
+func (s state) clone() state {
+	c := state{
+		stack:    append([]expr(nil), s.stack...),
+		scopes:   append([]expr(nil), s.scopes...),
+		declared: make(map[uint32]bool, len(s.declared)),
+		aliases:  make(map[uint32]expr, len(s.aliases)),
+		err:      s.err,
+	}
+	for k, v := range s.declared {
+		c.declared[k] = v
+	}
+	for k, v := range s.aliases {
+		c.aliases[k] = v
+	}
+	return c
+}
+
+type decompiler struct {
+	state
+	abc     *as3.AbcFile
+	cpool   *bytecode.CpoolInfo
+	method  *as3.Method
+	body    bytecode.MethodBodyInfo
+	graph   *cfg.Graph
+	static  bool
+	depth   int
+	imports map[string]bool
+
+	// registers named after the receiver, the parameters and the debug
+	// information
+	names map[uint32]string
+
+	// registers read before their declaration, declared at the start of the
+	// body. They are kept out of the state, as a speculative execution
+	// cannot take back the statements it emitted.
+	hoisted    map[uint32]bool
+	hoistOrder []uint32
+
+	// cond is the condition of the conditional branch, or the value of the
+	// lookupswitch, ending the last block executed
+	cond expr
+
+	idom    []int
+	ipdom   []int
+	loops   map[int]*loop
+	tries   []*tryGroup
+	emitted []bool
+}
+
+func newDecompiler(abc *as3.AbcFile, m *as3.Method, depth int, imports map[string]bool) (*decompiler, error) {
+	if !m.HasBody {
+		return nil, ErrNoBody
+	}
+	d := &decompiler{
+		state:   state{declared: map[uint32]bool{}, aliases: map[uint32]expr{}},
+		abc:     abc,
+		cpool:   &abc.Source.ConstantPool,
+		method:  m,
+		body:    m.BodyInfo,
+		depth:   depth,
+		imports: imports,
+		names:   map[uint32]string{0: "this"},
+		hoisted: map[uint32]bool{},
+	}
+	d.body.Instructions = nil
+	if err := d.body.Disassemble(); err != nil {
+		return nil, err
+	}
+	g, err := cfg.Build(&d.body)
+	if err != nil {
+		return nil, err
+	}
+	d.graph = g
+	d.static = m.Kind == as3.MethodKindStaticInitializer
+	if c := m.Class; c != nil && !d.static {
+		for _, t := range c.ClassTraits.Methods {
+			if t.Method == m {
+				d.static = true
+			}
+		}
+	}
+	d.nameRegisters()
+	d.analyze()
+	return d, nil
+}
+
+// nameRegisters names the registers holding the parameters, the rest or
+// arguments array and the locals named by debug instructions. The registers
+// set on entry are declared already.
+func (d *decompiler) nameRegisters() {
+	params := d.method.Params()
+	d.declared[0] = true
+	for i, p := range params {
+		d.names[uint32(i+1)] = p.Name
+		d.declared[uint32(i+1)] = true
+	}
+	switch {
+	case d.method.IsVariadic():
+		d.names[uint32(len(params)+1)] = "rest"
+		d.declared[uint32(len(params)+1)] = true
+	case d.method.NeedsArguments():
+		d.names[uint32(len(params)+1)] = "arguments"
+		d.declared[uint32(len(params)+1)] = true
+	}
+	for _, instr := range d.body.Instructions {
+		if instr.Model.Code != opDebug || len(instr.Operands) < 3 || instr.Operands[0] != 1 {
+			continue
+		}
+		r := instr.Operands[2] + 1
+		if _, ok := d.names[r]; !ok {
+			d.names[r] = d.str(instr.Operands[1])
+		}
+	}
+}
+
+func (d *decompiler) register(r uint32) string {
+	if name, ok := d.names[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("_loc%v_", r)
+}
+
+// decompile returns the statements of the method body, preceded by the
+// declarations of the variables held by its activation and of the registers
+// read before they are assigned
+func (d *decompiler) decompile() ([]stmt, error) {
+	var out []stmt
+	params := map[string]bool{}
+	for _, p := range d.method.Params() {
+		params[p.Name] = true
+	}
+	for _, t := range d.body.Traits {
+		switch t.GetType() {
+		case bytecode.TraitsInfoSlot, bytecode.TraitsInfoConst:
+			name := d.localName(t.Name)
+			if !params[name] {
+				out = append(out, newStmt("var %v:%v", name, d.localName(t.Typename)))
+			}
+		}
+	}
+	body := d.region(0, -1, context{entering: -1})
+	for _, r := range d.hoistOrder {
+		out = append(out, newStmt("var %v:*", d.register(r)))
+	}
+	out = append(out, body...)
+	if n := len(out); n > 0 {
+		if s, ok := out[n-1].(*simpleStmt); ok && s.text == "return" {
+			out = out[:n-1]
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return out, nil
+}
+
+// statements returns the statements of the method body, or a comment giving
+// the reason it cannot be decompiled
+func (d *decompiler) statements() []stmt {
+	stmts, err := d.decompile()
+	if err != nil {
+		return []stmt{&commentStmt{err.Error()}}
+	}
+	return stmts
+}
+
+func render(stmts []stmt, indent int) string {
+	buf := &bytes.Buffer{}
+	p := printer{bufio.NewWriter(buf)}
+	p.block(indent, stmts)
+	p.w.Flush()
+	return buf.String()
+}
+
+// Method decompiles the body of a linked method into actionscript
+// statements, one per line
+func Method(abc *as3.AbcFile, m *as3.Method) (string, error) {
+	d, err := newDecompiler(abc, m, 0, map[string]bool{})
+	if err != nil {
+		return "", err
+	}
+	stmts, err := d.decompile()
+	if err != nil {
+		return "", err
+	}
+	return render(stmts, 0), nil
+}
@@ -0,0 +1,403 @@
+package decompiler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kelvyne/as3"
+	"github.com/kelvyne/as3/asm"
+	"github.com/kelvyne/as3/bytecode"
+)
+
+func linkFixture(t *testing.T, name string) as3.AbcFile {
+	file, err := os.Open(fmt.Sprintf("../bytecode/fixtures/%v.abc", name))
+	if err != nil {
+		t.Fatalf("openFixture: %v", err)
+	}
+	defer file.Close()
+	a, err := bytecode.Parse(bytecode.NewReader(file))
+	if err != nil {
+		t.Fatalf("%v: expected nil, got %v", name, err)
+	}
+	abc, err := as3.Link(&a)
+	if err != nil {
+		t.Fatalf("%v: expected nil, got %v", name, err)
+	}
+	return abc
+}
+
+func linkSource(t *testing.T, src string) as3.AbcFile {
+	a, err := asm.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("asm.Parse: %v", err)
+	}
+	abc, err := as3.Link(&a)
+	if err != nil {
+		t.Fatalf("as3.Link: %v", err)
+	}
+	return abc
+}
+
+// methodSource returns the source of a file with a single method taking two
+// parameters a and b, with the given code and exception lines
+func methodSource(code, tries string) string {
+	return `
+method 0
+  returns QName(PackageNamespace(""), "*")
+  param QName(PackageNamespace(""), "int")
+  param QName(PackageNamespace(""), "int")
+  flags HAS_PARAM_NAMES
+  paramname "a"
+  paramname "b"
+end
+
+body 0
+  method 0
+  maxstack 4
+  localcount 6
+  initscopedepth 0
+  maxscopedepth 2
+  code
+` + code + `  end
+` + tries + `end
+`
+}
+
+func TestMethod(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		tries string
+		want  string
+	}{
+		{"expression", `
+    getlocal_1
+    getlocal_2
+    add
+    pushbyte 2
+    multiply
+    returnvalue
+`, "", `return (a + b) * 2;
+`},
+		{"if else", `
+    getlocal_1
+    getlocal_2
+    ifnlt L1
+    findpropstrict QName(PackageNamespace(""), "trace")
+    pushstring "less"
+    callpropvoid QName(PackageNamespace(""), "trace") 1
+    jump L2
+  L1:
+    findpropstrict QName(PackageNamespace(""), "trace")
+    pushstring "more"
+    callpropvoid QName(PackageNamespace(""), "trace") 1
+  L2:
+    returnvoid
+`, "", `if (a < b) {
+    trace("less");
+} else {
+    trace("more");
+}
+`},
+		{"for", `
+    debug 1 "i" 2 0
+    pushbyte 0
+    setlocal_3
+    jump L2
+  L1:
+    label
+    findpropstrict QName(PackageNamespace(""), "trace")
+    getlocal_3
+    callpropvoid QName(PackageNamespace(""), "trace") 1
+    getlocal_3
+    increment_i
+    setlocal_3
+  L2:
+    getlocal_3
+    getlocal_1
+    iflt L1
+    returnvoid
+`, "", `for (var i:* = 0; i < a; i++) {
+    trace(i);
+}
+`},
+		{"while", `
+  L1:
+    getlocal_1
+    pushbyte 0
+    ifngt L2
+    getlocal_1
+    decrement_i
+    setlocal_1
+    getlocal_2
+    increment_i
+    setlocal_2
+    jump L1
+  L2:
+    getlocal_2
+    returnvalue
+`, "", `while (a > 0) {
+    a--;
+    b++;
+}
+return b;
+`},
+		{"for in", `
+    debug 1 "key" 2 0
+    pushbyte 0
+    setlocal 4
+    getlocal_0
+    coerce_a
+    setlocal 5
+    jump L2
+  L1:
+    label
+    getlocal 5
+    getlocal 4
+    nextname
+    coerce_s
+    setlocal_3
+    findpropstrict QName(PackageNamespace(""), "trace")
+    getlocal_3
+    callpropvoid QName(PackageNamespace(""), "trace") 1
+  L2:
+    hasnext2 5 4
+    iftrue L1
+    kill 5
+    kill 4
+    returnvoid
+`, "", `for (var key:String in this) {
+    trace(key);
+}
+`},
+		{"switch", `
+    getlocal_1
+    lookupswitch L3 L1 L2
+  L1:
+    pushstring "zero"
+    returnvalue
+  L2:
+    pushstring "one"
+    returnvalue
+  L3:
+    pushstring "other"
+    returnvalue
+`, "", `switch (a) {
+    case 0:
+        return "zero";
+    case 1:
+        return "one";
+    default:
+        return "other";
+}
+`},
+		{"try catch", `
+  L1:
+    findpropstrict QName(PackageNamespace(""), "f")
+    callpropvoid QName(PackageNamespace(""), "f") 0
+  L2:
+    jump L4
+  L3:
+    pop
+    findpropstrict QName(PackageNamespace(""), "trace")
+    pushstring "failed"
+    callpropvoid QName(PackageNamespace(""), "trace") 1
+  L4:
+    returnvoid
+`, `  try from L1 to L2 target L3 type QName(PackageNamespace(""), "Error") name QName(PackageNamespace(""), "e")
+`, `try {
+    f();
+} catch (e:Error) {
+    trace("failed");
+}
+`},
+		{"do while", `
+  L1:
+    label
+    getlocal_1
+    increment_i
+    setlocal_1
+    getlocal_1
+    getlocal_2
+    iflt L1
+    returnvoid
+`, "", `do {
+    a++;
+} while (a < b);
+`},
+		{"for each", `
+    debug 1 "value" 2 0
+    pushbyte 0
+    setlocal 4
+    getlocal_0
+    coerce_a
+    setlocal 5
+    jump L2
+  L1:
+    label
+    getlocal 5
+    getlocal 4
+    nextvalue
+    setlocal_3
+    findpropstrict QName(PackageNamespace(""), "trace")
+    getlocal_3
+    callpropvoid QName(PackageNamespace(""), "trace") 1
+  L2:
+    hasnext2 5 4
+    iftrue L1
+    kill 5
+    kill 4
+    returnvoid
+`, "", `for each (var value:* in this) {
+    trace(value);
+}
+`},
+		{"if and or", `
+    getlocal_1
+    iffalse L1
+    getlocal_2
+    iftrue L2
+  L1:
+    getlocal_1
+    pushbyte 3
+    ifne L3
+  L2:
+    findpropstrict QName(PackageNamespace(""), "trace")
+    pushstring "yes"
+    callpropvoid QName(PackageNamespace(""), "trace") 1
+  L3:
+    returnvoid
+`, "", `if (a && b || a == 3) {
+    trace("yes");
+}
+`},
+		{"ternary", `
+    getlocal_1
+    iffalse L1
+    pushstring "yes"
+    jump L2
+  L1:
+    pushstring "no"
+  L2:
+    returnvalue
+`, "", `return a ? "yes" : "no";
+`},
+		{"and", `
+    getlocal_1
+    dup
+    iffalse L1
+    pop
+    getlocal_2
+  L1:
+    returnvalue
+`, "", `return a && b;
+`},
+		{"this", `
+    getlocal_0
+    setlocal_0
+    getlocal_1
+    setlocal_1
+    getlocal_0
+    returnvalue
+`, "", `return this;
+`},
+		{"read before set", `
+    getlocal_3
+    iffalse L1
+    inclocal 4
+  L1:
+    pushbyte 1
+    setlocal_3
+    getlocal 4
+    returnvalue
+`, "", `var _loc3_:*;
+var _loc4_:*;
+if (_loc3_) {
+    _loc4_++;
+}
+_loc3_ = 1;
+return _loc4_;
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			abc := linkSource(t, methodSource(tt.code, tt.tries))
+			got, err := Method(&abc, &abc.Methods[0])
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected:\n%v\ngot:\n%v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMethod_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		err  error
+	}{
+		{"no body", "", ErrNoBody},
+		{"stack underflow", `
+    getlocal_1
+    add
+    returnvalue
+`, ErrStackUnderflow},
+		{"huge argument count", `
+    getlocal_1
+    getlocal_2
+    newarray 1073741823
+    returnvalue
+`, ErrStackUnderflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			abc := linkSource(t, methodSource(tt.code, ""))
+			m := &abc.Methods[0]
+			if tt.code == "" {
+				m.HasBody = false
+			}
+			if _, err := Method(&abc, m); err != tt.err {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestMethod_Fixtures(t *testing.T) {
+	tests := []struct {
+		name       string
+		underflows int
+	}{
+		{"obf1", 0},
+		// junk bodies popping an empty stack
+		{"obf2", 7},
+	}
+	for _, tt := range tests {
+		abc := linkFixture(t, tt.name)
+		underflows := 0
+		for i := range abc.Methods {
+			m := &abc.Methods[i]
+			_, err := Method(&abc, m)
+			switch {
+			case err == ErrStackUnderflow:
+				underflows++
+			case err != nil && (m.HasBody || err != ErrNoBody):
+				t.Errorf("%v: method %v: expected nil, got %v", tt.name, i, err)
+			}
+		}
+		if underflows != tt.underflows {
+			t.Errorf("%v: expected %v underflows, got %v", tt.name, tt.underflows, underflows)
+		}
+		for i := range abc.Classes {
+			if err := WriteClass(ioutil.Discard, &abc, &abc.Classes[i]); err != nil {
+				t.Errorf("%v: class %v: expected nil, got %v", tt.name, i, err)
+			}
+		}
+	}
+}
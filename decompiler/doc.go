@@ -0,0 +1,15 @@
+// Package decompiler turns the bodies of linked methods back into
+// ActionScript 3 source. Method interprets the instructions of a body into
+// expressions and structures its control-flow graph into if, while, for,
+// for-in, do-while, switch and try statements; code that cannot be
+// structured is left as goto comments. Code popping more values than the
+// stack holds, as junk code does, fails with ErrStackUnderflow. WriteClass
+// prints the complete source file of a class, with its declarations and
+// decompiled bodies, the bodies that cannot be decompiled being replaced
+// by a comment giving the reason.
+//
+// WriteStub prints the declarations of a class alone, with empty bodies, and
+// ExportStubs writes the stubs of all the classes of a file into a package
+// directory tree, for code completion or to compare the API of two
+// versions of a file.
+package decompiler
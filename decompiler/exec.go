@@ -0,0 +1,708 @@
+package decompiler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kelvyne/as3"
+	"github.com/kelvyne/as3/bytecode"
+)
+
+// These are the codes of the instructions handled by the structuring
+const (
+	opThrow        = 0x03
+	opJump         = 0x10
+	opIfTrue       = 0x11
+	opIfFalse      = 0x12
+	opLookupswitch = 0x1b
+	opReturnVoid   = 0x47
+	opReturnValue  = 0x48
+	opDebug        = 0xef
+)
+
+// operator is a binary operator with its precedence
+type operator struct {
+	op   string
+	prec int
+}
+
+var binaryOps = map[uint8]operator{
+	0xa0: {"+", precAdditive},            // add
+	0xc5: {"+", precAdditive},            // add_i
+	0xa1: {"-", precAdditive},            // subtract
+	0xc6: {"-", precAdditive},            // subtract_i
+	0xa2: {"*", precMultiplicative},      // multiply
+	0xc7: {"*", precMultiplicative},      // multiply_i
+	0xa3: {"/", precMultiplicative},      // divide
+	0xa4: {"%", precMultiplicative},      // modulo
+	0xa5: {"<<", precShift},              // lshift
+	0xa6: {">>", precShift},              // rshift
+	0xa7: {">>>", precShift},             // urshift
+	0xa8: {"&", precBitAnd},              // bitand
+	0xa9: {"|", precBitOr},               // bitor
+	0xaa: {"^", precBitXor},              // bitxor
+	0xb1: {"instanceof", precRelational}, // instanceof
+	0xb3: {"is", precRelational},         // istypelate
+	0x87: {"as", precRelational},         // astypelate
+	0xb4: {"in", precRelational},         // in
+}
+
+var comparisonOps = map[uint8]string{
+	0xab: "==",  // equals
+	0xac: "===", // strictequals
+	0xad: "<",   // lessthan
+	0xae: "<=",  // lessequals
+	0xaf: ">",   // greaterthan
+	0xb0: ">=",  // greaterequals
+}
+
+// branchOps maps the conditional branches comparing two values to their
+// comparison, and whether the branch is taken when the comparison fails
+var branchOps = map[uint8]struct {
+	op      string
+	negated bool
+}{
+	0x0c: {"<", true},    // ifnlt
+	0x0d: {"<=", true},   // ifnle
+	0x0e: {">", true},    // ifngt
+	0x0f: {">=", true},   // ifnge
+	0x13: {"==", false},  // ifeq
+	0x14: {"!=", false},  // ifne
+	0x15: {"<", false},   // iflt
+	0x16: {"<=", false},  // ifle
+	0x17: {">", false},   // ifgt
+	0x18: {">=", false},  // ifge
+	0x19: {"===", false}, // ifstricteq
+	0x1a: {"!==", false}, // ifstrictne
+}
+
+// coercions maps the coercion and conversion instructions to the type of
+// their result. They are implicit in actionscript and leave the value as is.
+var coercions = map[uint8]string{
+	0x70: "String",  // convert_s
+	0x73: "int",     // convert_i
+	0x74: "uint",    // convert_u
+	0x75: "Number",  // convert_d
+	0x76: "Boolean", // convert_b
+	0x77: "Object",  // convert_o
+	0x81: "Boolean", // coerce_b
+	0x82: "*",       // coerce_a
+	0x83: "int",     // coerce_i
+	0x84: "Number",  // coerce_d
+	0x85: "String",  // coerce_s
+	0x88: "uint",    // coerce_u
+	0x89: "Object",  // coerce_o
+}
+
+// memoryOps are the domain memory instructions, printed as calls
+var memoryOps = map[uint8]int{
+	0x35: 1, 0x36: 1, 0x37: 1, 0x38: 1, 0x39: 1, // li8, li16, li32, lf32, lf64
+	0x3a: 2, 0x3b: 2, 0x3c: 2, 0x3d: 2, 0x3e: 2, // si8, si16, si32, sf32, sf64
+	0x50: 1, 0x51: 1, 0x52: 1, // sxi1, sxi8, sxi16
+}
+
+// isConditional reports whether the instruction is a conditional branch
+func isConditional(instr bytecode.Instr) bool {
+	_, ok := branchOps[instr.Model.Code]
+	return ok || instr.Model.Code == opIfTrue || instr.Model.Code == opIfFalse
+}
+
+// fail records the first problem making the statements wrong
+func (d *decompiler) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *decompiler) push(e expr) {
+	d.stack = append(d.stack, e)
+}
+
+// pop returns the value on top of the stack. Popping the empty stack fails
+// the decompilation, a placeholder standing for the missing value for the
+// interpretation to go on.
+func (d *decompiler) pop() expr {
+	if len(d.stack) == 0 {
+		d.fail(ErrStackUnderflow)
+		return primary("_stack_")
+	}
+	e := d.stack[len(d.stack)-1]
+	d.stack = d.stack[:len(d.stack)-1]
+	return e
+}
+
+// popN returns the n values on top of the stack, the deepest first. The
+// counts come from the operands and may be huge in obfuscated code: counts
+// past the depth of the stack fail the decompilation and pop the whole stack.
+func (d *decompiler) popN(n int) []expr {
+	if n > len(d.stack) || n < 0 {
+		d.fail(ErrStackUnderflow)
+		n = len(d.stack)
+	}
+	values := make([]expr, n)
+	for i := n - 1; i >= 0; i-- {
+		values[i] = d.pop()
+	}
+	return values
+}
+
+func (d *decompiler) str(i uint32) string {
+	if int(i) >= len(d.cpool.Strings) {
+		return fmt.Sprintf("_str%v_", i)
+	}
+	return d.cpool.Strings[i]
+}
+
+// localName formats a multiname as it is written in the code: its name,
+// prefixed by @ for attributes, or Vector.<T> for a TypeName. Names of
+// package definitions are recorded as imports.
+func (d *decompiler) localName(i uint32) string {
+	return d.localNameDepth(i, 0)
+}
+
+func (d *decompiler) localNameDepth(i uint32, depth int) string {
+	c := d.cpool
+	if i == 0 || int(i) >= len(c.Multinames) || depth > 16 {
+		return "*"
+	}
+	m := c.Multinames[i]
+	if m.Kind == bytecode.MultinameKindTypename {
+		s := d.localNameDepth(m.Name, depth+1) + ".<"
+		for j, p := range m.Params {
+			if j > 0 {
+				s += ", "
+			}
+			s += d.localNameDepth(p, depth+1)
+		}
+		return s + ">"
+	}
+	name := d.str(m.Name)
+	switch m.Kind {
+	case bytecode.MultinameKindQName:
+		if int(m.Namespace) < len(c.Namespaces) {
+			ns := c.Namespaces[m.Namespace]
+			if ns.Kind == bytecode.NamespaceKindPackageNamespace && ns.Name != 0 && d.str(ns.Name) != "" {
+				d.imports[d.str(ns.Name)+"."+name] = true
+			}
+		}
+	case bytecode.MultinameKindQNameA, bytecode.MultinameKindRTQNameA, bytecode.MultinameKindMultinameA:
+		name = "@" + name
+	}
+	return name
+}
+
+// popName pops the runtime parts of a multiname and returns the name of the
+// property, or the expression indexing it for late bound names
+func (d *decompiler) popName(i uint32) (string, *expr) {
+	var kind uint8
+	if int(i) < len(d.cpool.Multinames) {
+		kind = d.cpool.Multinames[i].Kind
+	}
+	switch kind {
+	case bytecode.MultinameKindRTQNameL, bytecode.MultinameKindRTQNameLA:
+		name := d.pop()
+		ns := d.pop()
+		return ns.paren(precMember) + "::[" + name.text + "]", nil
+	case bytecode.MultinameKindRTQName, bytecode.MultinameKindRTQNameA:
+		ns := d.pop()
+		return ns.paren(precMember) + "::" + d.localName(i), nil
+	case bytecode.MultinameKindMultinameLA:
+		index := d.pop()
+		return "@[" + index.text + "]", nil
+	case bytecode.MultinameKindMultinameL:
+		index := d.pop()
+		return "", &index
+	}
+	return d.localName(i), nil
+}
+
+// property pops the name and the object of a property instruction
+func (d *decompiler) property(i uint32) expr {
+	name, index := d.popName(i)
+	return member(d.pop(), name, index)
+}
+
+func literal(kind uint8, v interface{}) expr {
+	text := as3.Value{Kind: kind, Value: v}.String()
+	if text != "" && text[0] == '-' {
+		return expr{text: text, prec: precUnary}
+	}
+	return primary(text)
+}
+
+// constant returns the literal of a constant pool entry
+func (d *decompiler) constant(o bytecode.Operand) expr {
+	v, err := o.Resolve(d.cpool)
+	if err != nil {
+		return primary("_const_")
+	}
+	switch v := v.(type) {
+	case int32:
+		return literal(bytecode.SlotKindInt, v)
+	case uint32:
+		return literal(bytecode.SlotKindUInt, v)
+	case float64:
+		return literal(bytecode.SlotKindDouble, v)
+	case string:
+		return literal(bytecode.SlotKindUtf8, v)
+	case bytecode.NamespaceInfo:
+		return primary(strconv.Quote(d.str(v.Name)))
+	}
+	return primary(fmt.Sprint(v))
+}
+
+// assign builds the assignment of a value to a target, shortened to an
+// increment or a compound assignment when the value updates the target
+func assign(target string, v expr) *simpleStmt {
+	s := &simpleStmt{reg: -1, name: target, value: v}
+	if v.left != nil && v.right != nil && v.cond == nil && v.left.text == target {
+		switch v.op {
+		case "+", "-":
+			if v.right.text == "1" {
+				s.text, s.update = target+v.op+v.op, true
+				return s
+			}
+			fallthrough
+		case "*", "/", "%", "<<", ">>", ">>>", "&", "|", "^":
+			s.text, s.update = target+" "+v.op+"= "+v.right.paren(precAssign), true
+			return s
+		}
+	}
+	s.text = target + " = " + v.paren(precAssign)
+	return s
+}
+
+// slotTrait returns the name of the trait of an object with the slot id
+func slotTrait(o as3.TraitsObject, id uint32) (string, bool) {
+	for _, traits := range [][]as3.Trait{o.Slots, o.Classes, o.Functions} {
+		for _, t := range traits {
+			if t.Source.SlotID == id {
+				return t.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// slot returns the expression accessing a slot of an object: a variable of
+// the activation or of the script, the exception of a catch scope or a
+// property of the receiver
+func (d *decompiler) slot(obj expr, id uint32) expr {
+	switch obj.kind {
+	case kindCatch:
+		return primary(obj.text)
+	case kindActivation:
+		for _, t := range d.body.Traits {
+			if t.SlotID == id {
+				return primary(d.localName(t.Name))
+			}
+		}
+	case kindGlobal:
+		if s := d.method.Script; s != nil {
+			if name, ok := slotTrait(s.Traits, id); ok {
+				return primary(name)
+			}
+		}
+	case kindThis:
+		if c := d.method.Class; c != nil {
+			traits := c.InstanceTraits
+			if d.static {
+				traits = c.ClassTraits
+			}
+			if name, ok := slotTrait(traits, id); ok {
+				return member(obj, name, nil)
+			}
+		}
+	}
+	return member(obj, fmt.Sprintf("slot%v", id), nil)
+}
+
+// setLocal assigns a value to a register, declaring it on its first
+// assignment. Registers holding an activation or a catch scope become
+// aliases of it, as the receiver register, which cannot be assigned.
+// Assigning a register its own value is dropped.
+func (d *decompiler) setLocal(r uint32, v expr) *simpleStmt {
+	switch {
+	case r == 0 && v.kind == kindThis:
+		delete(d.aliases, r)
+		return nil
+	case r == 0, v.kind == kindActivation, v.kind == kindCatch, v.kind == kindGlobal:
+		d.aliases[r] = v
+		return nil
+	}
+	delete(d.aliases, r)
+	name := d.register(r)
+	if d.declared[r] || d.hoisted[r] {
+		if v.text == name {
+			return nil
+		}
+		s := assign(name, v)
+		s.reg = int(r)
+		return s
+	}
+	d.declared[r] = true
+	typ := v.typ
+	if typ == "" {
+		typ = "*"
+	}
+	return &simpleStmt{text: fmt.Sprintf("var %v:%v = %v", name, typ, v.paren(precAssign)), reg: int(r), name: name, value: v}
+}
+
+func (d *decompiler) getLocal(r uint32) expr {
+	if v, ok := d.aliases[r]; ok {
+		return v
+	}
+	if r == 0 {
+		e := primary("this")
+		e.kind = kindThis
+		if d.method.Kind == as3.MethodKindScriptInitializer {
+			e.kind = kindGlobal
+		}
+		return e
+	}
+	return primary(d.use(r))
+}
+
+// use returns the name of a register read by the code. A register read
+// before its declaration is declared at the start of the body.
+func (d *decompiler) use(r uint32) string {
+	if !d.declared[r] && !d.hoisted[r] {
+		d.hoisted[r] = true
+		d.hoistOrder = append(d.hoistOrder, r)
+	}
+	return d.register(r)
+}
+
+// closure returns a function expression for a method defined in the body
+func (d *decompiler) closure(index uint32) expr {
+	if int(index) >= len(d.abc.Methods) {
+		return primary("function () {}")
+	}
+	m := &d.abc.Methods[index]
+	anonymous := *m
+	anonymous.Name, anonymous.QName, anonymous.Kind = "", as3.QName{}, as3.MethodKindFunction
+	sig := anonymous.Signature()
+	if d.depth >= maxClosureDepth {
+		return primary(sig + " {}")
+	}
+	nested, err := newDecompiler(d.abc, m, d.depth+1, d.imports)
+	if err != nil {
+		return primary(sig + " {}")
+	}
+	return primary(sig + " {\n" + render(nested.statements(), 1) + "}")
+}
+
+func (d *decompiler) catchName(i uint32) string {
+	if int(i) < len(d.body.Exceptions) && d.body.Exceptions[i].VarName != 0 {
+		return d.localName(d.body.Exceptions[i].VarName)
+	}
+	return "e"
+}
+
+// exec interprets the instructions of a block, returning its statements.
+// The condition of a final conditional branch, taken when true, and the
+// value of a final lookupswitch are left in d.cond.
+func (d *decompiler) exec(b int) []stmt {
+	var out []stmt
+	emit := func(s *simpleStmt) {
+		if s != nil {
+			out = append(out, s)
+		}
+	}
+	block := d.graph.Blocks[b]
+	for _, instr := range d.body.Instructions[block.Start:block.End] {
+		code := instr.Model.Code
+		operands := instr.TypedOperands()
+		operand := func(i int) uint32 {
+			if i < len(operands) {
+				return operands[i].Value
+			}
+			return 0
+		}
+
+		if o, ok := binaryOps[code]; ok {
+			right := d.pop()
+			d.push(binary(o.op, o.prec, d.pop(), right))
+			continue
+		}
+		if op, ok := comparisonOps[code]; ok {
+			right := d.pop()
+			d.push(comparison(op, d.pop(), right))
+			continue
+		}
+		if o, ok := branchOps[code]; ok {
+			right := d.pop()
+			d.cond = comparison(o.op, d.pop(), right)
+			if o.negated {
+				d.cond = negate(d.cond)
+			}
+			continue
+		}
+		if typ, ok := coercions[code]; ok {
+			v := d.pop()
+			v.typ = typ
+			d.push(v)
+			continue
+		}
+		if n, ok := memoryOps[code]; ok {
+			e := call(primary(instr.Model.Name), d.popN(n))
+			if n == 2 {
+				emit(&simpleStmt{text: e.text, reg: -1})
+			} else {
+				d.push(e)
+			}
+			continue
+		}
+
+		switch code {
+		case 0x01, 0x02, 0x08, 0x09, opJump, 0xef, 0xf0, 0xf1, 0xf2, 0xf3:
+			// bkpt, nop, kill, label, jump, debug, debugline, debugfile,
+			// bkptline, timestamp
+		case opIfTrue:
+			d.cond = d.pop()
+		case opIfFalse:
+			d.cond = negate(d.pop())
+		case opLookupswitch:
+			d.cond = d.pop()
+		case opReturnVoid:
+			emit(newStmt("return"))
+		case opReturnValue:
+			emit(newStmt("return %v", d.pop().text))
+		case opThrow:
+			emit(newStmt("throw %v", d.pop().text))
+
+		case 0x20: // pushnull
+			d.push(primary("null"))
+		case 0x21: // pushundefined
+			d.push(primary("undefined"))
+		case 0x24, 0x25: // pushbyte, pushshort
+			d.push(literal(bytecode.SlotKindInt, operands[0].Signed()))
+		case 0x26: // pushtrue
+			d.push(primary("true"))
+		case 0x27: // pushfalse
+			d.push(primary("false"))
+		case 0x28: // pushnan
+			d.push(primary("NaN"))
+		case 0x2c, 0x2d, 0x2e, 0x2f, 0x31: // pushstring, pushint, pushuint, pushdouble, pushnamespace
+			d.push(d.constant(operands[0]))
+
+		case 0x29: // pop
+			if e := d.pop(); e.effect {
+				emit(&simpleStmt{text: e.text, reg: -1})
+			}
+		case 0x2a: // dup
+			e := d.pop()
+			d.push(e)
+			d.push(e)
+		case 0x2b: // swap
+			a := d.pop()
+			b := d.pop()
+			d.push(a)
+			d.push(b)
+
+		case 0xd0, 0xd1, 0xd2, 0xd3: // getlocal_<n>
+			d.push(d.getLocal(uint32(code - 0xd0)))
+		case 0x62: // getlocal
+			d.push(d.getLocal(operand(0)))
+		case 0xd4, 0xd5, 0xd6, 0xd7: // setlocal_<n>
+			emit(d.setLocal(uint32(code-0xd4), d.pop()))
+		case 0x63: // setlocal
+			emit(d.setLocal(operand(0), d.pop()))
+		case 0x92, 0xc2: // inclocal, inclocal_i
+			name := d.use(operand(0))
+			emit(&simpleStmt{text: name + "++", reg: int(operand(0)), name: name, update: true})
+		case 0x94, 0xc3: // declocal, declocal_i
+			name := d.use(operand(0))
+			emit(&simpleStmt{text: name + "--", reg: int(operand(0)), name: name, update: true})
+
+		case 0x90, 0xc4: // negate, negate_i
+			d.push(unary("-", d.pop()))
+		case 0x96: // not
+			d.push(negate(d.pop()))
+		case 0x97: // bitnot
+			d.push(unary("~", d.pop()))
+		case 0x95: // typeof
+			d.push(unary("typeof ", d.pop()))
+		case 0x91, 0xc0: // increment, increment_i
+			d.push(binary("+", precAdditive, d.pop(), primary("1")))
+		case 0x93, 0xc1: // decrement, decrement_i
+			d.push(binary("-", precAdditive, d.pop(), primary("1")))
+		case 0x80: // coerce
+			v := d.pop()
+			v.typ = d.localName(operand(0))
+			d.push(v)
+		case 0x86: // astype
+			d.push(binary("as", precRelational, d.pop(), primary(d.localName(operand(0)))))
+		case 0xb2: // istype
+			d.push(binary("is", precRelational, d.pop(), primary(d.localName(operand(0)))))
+		case 0x71, 0x72, 0x78: // esc_xelem, esc_xattr, checkfilter
+
+		case 0x5d, 0x5e, 0x5f, 0x5c: // findpropstrict, findproperty, finddef, findpropglobal
+			d.popName(operand(0))
+			d.push(expr{kind: kindScope, prec: precPrimary})
+		case 0x60: // getlex
+			d.push(primary(d.localName(operand(0))))
+		case 0x66: // getproperty
+			d.push(d.property(operand(0)))
+		case 0x61, 0x68: // setproperty, initproperty
+			v := d.pop()
+			emit(assign(d.property(operand(0)).text, v))
+		case 0x6a: // deleteproperty
+			e := unary("delete ", d.property(operand(0)))
+			e.effect = true
+			d.push(e)
+		case 0x59: // getdescendants
+			name, index := d.popName(operand(0))
+			if index != nil {
+				name = "[" + index.text + "]"
+			}
+			d.push(expr{text: d.pop().paren(precMember) + ".." + name, prec: precMember})
+		case 0x04: // getsuper
+			name, index := d.popName(operand(0))
+			d.pop()
+			d.push(member(primary("super"), name, index))
+		case 0x05: // setsuper
+			v := d.pop()
+			name, index := d.popName(operand(0))
+			d.pop()
+			emit(assign(member(primary("super"), name, index).text, v))
+
+		case 0x46, 0x4c, 0x4f: // callproperty, callproplex, callpropvoid
+			args := d.popN(int(operand(1)))
+			e := call(d.property(operand(0)), args)
+			if code == 0x4f {
+				emit(&simpleStmt{text: e.text, reg: -1})
+			} else {
+				d.push(e)
+			}
+		case 0x45, 0x4e: // callsuper, callsupervoid
+			args := d.popN(int(operand(1)))
+			name, index := d.popName(operand(0))
+			d.pop()
+			e := call(member(primary("super"), name, index), args)
+			if code == 0x4e {
+				emit(&simpleStmt{text: e.text, reg: -1})
+			} else {
+				d.push(e)
+			}
+		case 0x41: // call
+			args := d.popN(int(operand(0)))
+			d.pop() // receiver
+			d.push(call(d.pop(), args))
+		case 0x43: // callmethod
+			args := d.popN(int(operand(1)))
+			d.push(call(member(d.pop(), fmt.Sprintf("method%v", operand(0)), nil), args))
+		case 0x44: // callstatic
+			args := d.popN(int(operand(1)))
+			name := fmt.Sprintf("method%v", operand(0))
+			if int(operand(0)) < len(d.abc.Methods) && d.abc.Methods[operand(0)].Name != "" {
+				name = d.abc.Methods[operand(0)].Name
+			}
+			d.push(call(member(d.pop(), name, nil), args))
+		case 0x42: // construct
+			args := d.popN(int(operand(0)))
+			obj := d.pop()
+			d.push(expr{text: "new " + obj.paren(precMember) + "(" + joinExprs(args) + ")", prec: precMember, effect: true})
+		case 0x4a: // constructprop
+			args := d.popN(int(operand(1)))
+			obj := d.property(operand(0))
+			d.push(expr{text: "new " + obj.paren(precMember) + "(" + joinExprs(args) + ")", prec: precMember, effect: true})
+		case 0x49: // constructsuper
+			args := d.popN(int(operand(0)))
+			d.pop()
+			emit(newStmt("super(%v)", joinExprs(args)))
+		case 0x53: // applytype
+			params := d.popN(int(operand(0)))
+			base := d.pop()
+			d.push(primary(base.paren(precMember) + ".<" + joinExprs(params) + ">"))
+
+		case 0x55: // newobject
+			values := d.popN(2 * int(operand(0)))
+			text := "{"
+			for i := 0; i+1 < len(values); i += 2 {
+				if i > 0 {
+					text += ", "
+				}
+				text += values[i].text + ": " + values[i+1].paren(precAssign)
+			}
+			d.push(primary(text + "}"))
+		case 0x56: // newarray
+			d.push(primary("[" + joinExprs(d.popN(int(operand(0)))) + "]"))
+		case 0x57: // newactivation
+			d.push(expr{text: "activation", prec: precPrimary, kind: kindActivation})
+		case 0x58: // newclass
+			d.pop()
+			name := fmt.Sprintf("class%v", operand(0))
+			if int(operand(0)) < len(d.abc.Classes) {
+				name = d.abc.Classes[operand(0)].Name
+			}
+			d.push(primary(name))
+		case 0x40: // newfunction
+			d.push(d.closure(operand(0)))
+		case 0x5a: // newcatch
+			d.push(expr{text: d.catchName(operand(0)), prec: precPrimary, kind: kindCatch})
+
+		case 0x30: // pushscope
+			d.scopes = append(d.scopes, d.pop())
+		case 0x1c: // pushwith
+			obj := d.pop()
+			d.scopes = append(d.scopes, obj)
+			out = append(out, &commentStmt{"with (" + obj.text + ")"})
+		case 0x1d: // popscope
+			if len(d.scopes) > 0 {
+				d.scopes = d.scopes[:len(d.scopes)-1]
+			}
+		case 0x64, 0x67: // getglobalscope, getouterscope
+			d.push(expr{text: "global", prec: precPrimary, kind: kindGlobal})
+		case 0x65: // getscopeobject
+			if i := int(operand(0)); i < len(d.scopes) {
+				d.push(d.scopes[i])
+			} else {
+				d.push(expr{text: "global", prec: precPrimary, kind: kindGlobal})
+			}
+		case 0x6c: // getslot
+			d.push(d.slot(d.pop(), operand(0)))
+		case 0x6d: // setslot
+			v := d.pop()
+			obj := d.pop()
+			if obj.kind == kindCatch {
+				break
+			}
+			if target := d.slot(obj, operand(0)).text; target != v.text {
+				emit(assign(target, v))
+			}
+		case 0x6e: // getglobalslot
+			d.push(d.slot(expr{kind: kindGlobal}, operand(0)))
+		case 0x6f: // setglobalslot
+			emit(assign(d.slot(expr{kind: kindGlobal}, operand(0)).text, d.pop()))
+
+		case 0x32: // hasnext2
+			e := primary(fmt.Sprintf("hasnext2(%v, %v)", d.use(operand(0)), d.use(operand(1))))
+			e.kind, e.regs = kindHasNext, [2]uint32{operand(0), operand(1)}
+			d.push(e)
+		case 0x1f: // hasnext
+			index := d.pop()
+			d.push(call(primary("hasnext"), []expr{d.pop(), index}))
+		case 0x1e, 0x23: // nextname, nextvalue
+			index := d.pop()
+			obj := d.pop()
+			e := call(primary(instr.Model.Name), []expr{obj, index})
+			e.effect = false
+			d.push(e)
+
+		case 0x06: // dxns
+			emit(newStmt("default xml namespace = %v", strconv.Quote(d.str(operand(0)))))
+		case 0x07: // dxnslate
+			emit(newStmt("default xml namespace = %v", d.pop().text))
+
+		default:
+			pop, push, _ := instr.StackEffect(d.cpool)
+			args := d.popN(pop)
+			out = append(out, &commentStmt{instr.Model.Name})
+			for i := 0; i < push; i++ {
+				d.push(call(primary(instr.Model.Name), args))
+			}
+		}
+	}
+	return out
+}
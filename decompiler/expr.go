@@ -0,0 +1,183 @@
+package decompiler
+
+import "strings"
+
+// Precedences of the actionscript operators, from the loosest to the
+// tightest binding
+const (
+	precAssign = iota + 1
+	precTernary
+	precOr
+	precAnd
+	precBitOr
+	precBitXor
+	precBitAnd
+	precEquality
+	precRelational
+	precShift
+	precAdditive
+	precMultiplicative
+	precUnary
+	precPostfix
+	precMember
+	precPrimary
+)
+
+// exprKind marks the values the interpretation of the code treats specially
+type exprKind uint8
+
+const (
+	kindValue      = exprKind(iota)
+	kindScope      // object found by findproperty: its properties are bare names
+	kindActivation // activation object holding the local variables
+	kindGlobal     // global object of the script
+	kindCatch      // catch scope holding the exception variable
+	kindThis       // receiver of the method
+	kindHasNext    // hasnext2 condition of a for-in loop
+)
+
+// expr is a decompiled expression. Op, Left, Right and Cond keep the
+// structure of operators so that conditions can be negated and assignments
+// shortened; Not is the negation of a condition, when it is known.
+// Typ is the type the value was coerced to, used to declare variables, and
+// regs the registers iterated by hasnext2.
+type expr struct {
+	text   string
+	prec   int
+	kind   exprKind
+	effect bool
+
+	op          string
+	left, right *expr
+	cond        *expr
+	not         *expr
+	negated     bool
+
+	typ  string
+	regs [2]uint32
+}
+
+func primary(text string) expr {
+	return expr{text: text, prec: precPrimary}
+}
+
+// paren formats the expression as an operand of an operator of the given
+// precedence
+func (e expr) paren(prec int) string {
+	if e.prec < prec {
+		return "(" + e.text + ")"
+	}
+	return e.text
+}
+
+// binary builds a left associative binary operation
+func binary(op string, prec int, left, right expr) expr {
+	return expr{
+		text:  left.paren(prec) + " " + op + " " + right.paren(prec+1),
+		prec:  prec,
+		op:    op,
+		left:  &left,
+		right: &right,
+	}
+}
+
+// inverses maps comparison operators to their negation. The relational
+// operators have none since comparisons with NaN are false both ways.
+var inverses = map[string]string{
+	"==":  "!=",
+	"!=":  "==",
+	"===": "!==",
+	"!==": "===",
+}
+
+var equalityOps = map[string]bool{"==": true, "!=": true, "===": true, "!==": true}
+
+// comparison builds a comparison, with its negation when it has one
+func comparison(op string, left, right expr) expr {
+	prec := precRelational
+	if equalityOps[op] {
+		prec = precEquality
+	}
+	e := binary(op, prec, left, right)
+	if inverse, ok := inverses[op]; ok {
+		not := binary(inverse, prec, left, right)
+		e.not = &not
+	}
+	return e
+}
+
+func unary(op string, operand expr) expr {
+	return expr{text: op + operand.paren(precUnary), prec: precUnary, op: op, right: &operand}
+}
+
+// negate returns the negation of a condition, simplifying double negations
+// and applying De Morgan's laws to && and ||
+func negate(e expr) expr {
+	if e.not != nil {
+		return *e.not
+	}
+	switch e.op {
+	case "&&", "||":
+		op, prec := "||", precOr
+		if e.op == "||" {
+			op, prec = "&&", precAnd
+		}
+		n := binary(op, prec, negate(*e.left), negate(*e.right))
+		n.not = &e
+		return n
+	}
+	n := unary("!", e)
+	n.negated, n.not = true, &e
+	return n
+}
+
+// ternary builds cond ? then : otherwise, as && or || when one of the
+// branches is the condition itself
+func ternary(cond, then, otherwise expr) expr {
+	if cond.negated {
+		cond, then, otherwise = *cond.not, otherwise, then
+	}
+	switch {
+	case then.text == cond.text:
+		return binary("||", precOr, cond, otherwise)
+	case otherwise.text == cond.text:
+		return binary("&&", precAnd, cond, then)
+	}
+	return expr{
+		text:  cond.paren(precTernary+1) + " ? " + then.paren(precTernary) + " : " + otherwise.paren(precTernary),
+		prec:  precTernary,
+		op:    "?:",
+		cond:  &cond,
+		left:  &then,
+		right: &otherwise,
+	}
+}
+
+// member builds an access to a property of an object: a bare name for the
+// scope objects, obj.name or obj[name]
+func member(obj expr, name string, index *expr) expr {
+	if index != nil {
+		if obj.kind == kindScope {
+			return primary(index.text)
+		}
+		return expr{text: obj.paren(precMember) + "[" + index.text + "]", prec: precMember}
+	}
+	switch obj.kind {
+	case kindScope, kindGlobal, kindActivation:
+		return primary(name)
+	}
+	return expr{text: obj.paren(precMember) + "." + name, prec: precMember}
+}
+
+// call builds a call of a function with its arguments
+func call(fn expr, args []expr) expr {
+	return expr{text: fn.paren(precMember) + "(" + joinExprs(args) + ")", prec: precMember, effect: true}
+}
+
+func joinExprs(exprs []expr) string {
+	texts := make([]string, len(exprs))
+	for i, e := range exprs {
+		texts[i] = e.paren(precAssign)
+	}
+	return strings.Join(texts, ", ")
+}
@@ -0,0 +1,253 @@
+package decompiler
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+const indentUnit = "    "
+
+// printer writes actionscript source, one statement per line
+type printer struct {
+	w *bufio.Writer
+}
+
+// line writes a line at the given indentation. The lines following a new
+// line in the text, such as the body of a closure, are indented the same.
+func (p *printer) line(indent int, format string, args ...interface{}) {
+	prefix := strings.Repeat(indentUnit, indent)
+	text := fmt.Sprintf(format, args...)
+	p.w.WriteString(prefix)
+	p.w.WriteString(strings.Replace(text, "\n", "\n"+prefix, -1))
+	p.w.WriteByte('\n')
+}
+
+func (p *printer) block(indent int, stmts []stmt) {
+	for _, s := range stmts {
+		s.print(p, indent)
+	}
+}
+
+// stmt is a decompiled statement
+type stmt interface {
+	print(p *printer, indent int)
+}
+
+// simpleStmt is a statement fitting on a line, such as an expression, a
+// return or a break. Assignments to a register or a variable record it in
+// reg (-1 otherwise) and name, and the value assigned; update marks
+// increments and compound assignments.
+type simpleStmt struct {
+	text   string
+	reg    int
+	name   string
+	value  expr
+	update bool
+}
+
+func newStmt(format string, args ...interface{}) *simpleStmt {
+	return &simpleStmt{text: fmt.Sprintf(format, args...), reg: -1}
+}
+
+func (s *simpleStmt) print(p *printer, indent int) {
+	p.line(indent, "%v;", s.text)
+}
+
+// commentStmt is a comment standing for code that could not be decompiled
+type commentStmt struct {
+	text string
+}
+
+func (s *commentStmt) print(p *printer, indent int) {
+	p.line(indent, "// %v", s.text)
+}
+
+type ifStmt struct {
+	cond      expr
+	then      []stmt
+	otherwise []stmt
+}
+
+func (s *ifStmt) print(p *printer, indent int) {
+	p.line(indent, "if (%v) {", s.cond.text)
+	p.block(indent+1, s.then)
+	for s.otherwise != nil {
+		if next, ok := s.otherwise[0].(*ifStmt); ok && len(s.otherwise) == 1 {
+			p.line(indent, "} else if (%v) {", next.cond.text)
+			p.block(indent+1, next.then)
+			s = next
+			continue
+		}
+		p.line(indent, "} else {")
+		p.block(indent+1, s.otherwise)
+		break
+	}
+	p.line(indent, "}")
+}
+
+type whileStmt struct {
+	cond expr
+	body []stmt
+}
+
+func (s *whileStmt) print(p *printer, indent int) {
+	p.line(indent, "while (%v) {", s.cond.text)
+	p.block(indent+1, s.body)
+	p.line(indent, "}")
+}
+
+type doWhileStmt struct {
+	body []stmt
+	cond expr
+}
+
+func (s *doWhileStmt) print(p *printer, indent int) {
+	p.line(indent, "do {")
+	p.block(indent+1, s.body)
+	p.line(indent, "} while (%v);", s.cond.text)
+}
+
+type forStmt struct {
+	init   string
+	cond   expr
+	update string
+	body   []stmt
+}
+
+func (s *forStmt) print(p *printer, indent int) {
+	p.line(indent, "for (%v; %v; %v) {", s.init, s.cond.text, s.update)
+	p.block(indent+1, s.body)
+	p.line(indent, "}")
+}
+
+// forInStmt is a for-in loop, or a for each loop when each is set. Regs are
+// the registers holding the object and the index of the iteration.
+type forInStmt struct {
+	each     bool
+	variable string
+	object   expr
+	body     []stmt
+	regs     [2]uint32
+}
+
+func (s *forInStmt) print(p *printer, indent int) {
+	keyword := "for"
+	if s.each {
+		keyword = "for each"
+	}
+	p.line(indent, "%v (%v in %v) {", keyword, s.variable, s.object.text)
+	p.block(indent+1, s.body)
+	p.line(indent, "}")
+}
+
+// switchCase is a clause of a switch; an empty label stands for default
+type switchCase struct {
+	labels []string
+	body   []stmt
+}
+
+type switchStmt struct {
+	value expr
+	cases []switchCase
+}
+
+func (s *switchStmt) print(p *printer, indent int) {
+	p.line(indent, "switch (%v) {", s.value.text)
+	for _, c := range s.cases {
+		for _, label := range c.labels {
+			if label == "" {
+				p.line(indent+1, "default:")
+			} else {
+				p.line(indent+1, "case %v:", label)
+			}
+		}
+		p.block(indent+2, c.body)
+	}
+	p.line(indent, "}")
+}
+
+type catchClause struct {
+	name string
+	typ  string
+	body []stmt
+}
+
+type tryStmt struct {
+	body    []stmt
+	catches []catchClause
+}
+
+func (s *tryStmt) print(p *printer, indent int) {
+	p.line(indent, "try {")
+	p.block(indent+1, s.body)
+	for _, c := range s.catches {
+		p.line(indent, "} catch (%v:%v) {", c.name, c.typ)
+		p.block(indent+1, c.body)
+	}
+	p.line(indent, "}")
+}
+
+// hasContinue reports whether the statements continue the enclosing loop
+func hasContinue(stmts []stmt) bool {
+	for _, s := range stmts {
+		switch s := s.(type) {
+		case *simpleStmt:
+			if s.text == "continue" {
+				return true
+			}
+		case *ifStmt:
+			if hasContinue(s.then) || hasContinue(s.otherwise) {
+				return true
+			}
+		case *switchStmt:
+			for _, c := range s.cases {
+				if hasContinue(c.body) {
+					return true
+				}
+			}
+		case *tryStmt:
+			if hasContinue(s.body) {
+				return true
+			}
+			for _, c := range s.catches {
+				if hasContinue(c.body) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// simplify rewrites the statements into more idiomatic forms: while loops
+// initializing and updating a variable become for loops and nested ifs
+// without else are merged with &&
+func simplify(stmts []stmt) []stmt {
+	var out []stmt
+	for _, s := range stmts {
+		switch loop := s.(type) {
+		case *whileStmt:
+			if len(out) == 0 || len(loop.body) == 0 || hasContinue(loop.body) {
+				break
+			}
+			init, ok := out[len(out)-1].(*simpleStmt)
+			last, ok2 := loop.body[len(loop.body)-1].(*simpleStmt)
+			if !ok || !ok2 || init.name == "" || init.name != last.name || !last.update ||
+				!strings.Contains(loop.cond.text, init.name) {
+				break
+			}
+			out[len(out)-1] = &forStmt{init.text, loop.cond, last.text, loop.body[:len(loop.body)-1]}
+			continue
+		case *ifStmt:
+			if loop.otherwise != nil || len(loop.then) != 1 {
+				break
+			}
+			if inner, ok := loop.then[0].(*ifStmt); ok && inner.otherwise == nil {
+				s = &ifStmt{binary("&&", precAnd, loop.cond, inner.cond), inner.then, nil}
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
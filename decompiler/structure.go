@@ -0,0 +1,778 @@
+package decompiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kelvyne/as3/bytecode"
+	"github.com/kelvyne/as3/cfg"
+)
+
+// loop is a natural loop: the blocks reaching one of its latches, the
+// sources of the back edges, without going through its header.
+// Follow is the block executed after the loop, or -1.
+type loop struct {
+	header  int
+	body    []bool
+	latches []int
+	follow  int
+}
+
+// tryGroup gathers the exceptions protecting the same range of code
+type tryGroup struct {
+	from, to   uint32
+	start      int
+	exceptions []bytecode.ExceptionInfo
+	opened     bool
+	exits      []int
+}
+
+// breakable is a loop or a switch enclosing the code being structured
+type breakable struct {
+	loop   *loop
+	follow int
+	cases  map[int]bool
+}
+
+// context describes the constructs enclosing the code being structured.
+// Entering is the block starting a loop or a case, which is not a jump out
+// of it.
+type context struct {
+	breakables []breakable
+	loop       *loop
+	try        *tryGroup
+	doWhile    *loop
+	entering   int
+}
+
+func (c context) with(b breakable) context {
+	c.breakables = append(append([]breakable(nil), c.breakables...), b)
+	return c
+}
+
+// postDominators returns the immediate post-dominator of each block, -1 for
+// the blocks leaving the method and those never leaving it. Exception edges
+// are ignored.
+func postDominators(g *cfg.Graph) []int {
+	n := len(g.Blocks)
+	reversed := &cfg.Graph{Blocks: make([]cfg.Block, n+1)}
+	edge := func(from, to int) {
+		e := cfg.Edge{From: from, To: to}
+		reversed.Blocks[from].Succs = append(reversed.Blocks[from].Succs, e)
+		reversed.Blocks[to].Preds = append(reversed.Blocks[to].Preds, e)
+	}
+	for i, b := range g.Blocks {
+		exits := true
+		for _, e := range b.Succs {
+			if e.Kind != cfg.EdgeException {
+				edge(e.To+1, i+1)
+				exits = false
+			}
+		}
+		if exits {
+			edge(0, i+1)
+		}
+	}
+	idom := reversed.Dominators()
+	ipdom := make([]int, n)
+	for i := range ipdom {
+		ipdom[i] = idom[i+1] - 1
+	}
+	return ipdom
+}
+
+// analyze finds the dominators, the post-dominators, the loops and the
+// exception groups of the body
+func (d *decompiler) analyze() {
+	g := d.graph
+	n := len(g.Blocks)
+	d.idom = g.Dominators()
+	d.ipdom = postDominators(g)
+	d.emitted = make([]bool, n)
+	d.loops = map[int]*loop{}
+	for _, b := range g.Blocks {
+		for _, e := range b.Succs {
+			if e.Kind != cfg.EdgeException && cfg.Dominates(d.idom, e.To, e.From) {
+				d.addBackEdge(e.From, e.To)
+			}
+		}
+	}
+	for _, l := range d.loops {
+		d.findFollow(l)
+	}
+
+	groups := map[[2]uint32]*tryGroup{}
+	starts := map[uint32]int{}
+	for _, b := range g.Blocks {
+		starts[g.Offsets[b.Start]] = b.Index
+	}
+	for _, e := range d.body.Exceptions {
+		start, ok := starts[e.From]
+		if !ok {
+			continue
+		}
+		key := [2]uint32{e.From, e.To}
+		if groups[key] == nil {
+			groups[key] = &tryGroup{from: e.From, to: e.To, start: start}
+			d.tries = append(d.tries, groups[key])
+		}
+		groups[key].exceptions = append(groups[key].exceptions, e)
+	}
+	sort.SliceStable(d.tries, func(i, j int) bool {
+		a, b := d.tries[i], d.tries[j]
+		return a.from < b.from || a.from == b.from && a.to > b.to
+	})
+}
+
+func (d *decompiler) addBackEdge(latch, header int) {
+	l := d.loops[header]
+	if l == nil {
+		l = &loop{header: header, body: make([]bool, len(d.graph.Blocks)), follow: -1}
+		l.body[header] = true
+		d.loops[header] = l
+	}
+	l.latches = append(l.latches, latch)
+	worklist := []int{latch}
+	for len(worklist) > 0 {
+		b := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		if l.body[b] || !cfg.Dominates(d.idom, header, b) {
+			continue
+		}
+		l.body[b] = true
+		for _, e := range d.graph.Blocks[b].Preds {
+			if e.Kind != cfg.EdgeException {
+				worklist = append(worklist, e.From)
+			}
+		}
+	}
+}
+
+// exitsOf returns the successors of a block outside of the loop
+func (d *decompiler) exitsOf(l *loop, b int) []int {
+	var exits []int
+	for _, e := range d.graph.Blocks[b].Succs {
+		if e.Kind != cfg.EdgeException && !l.body[e.To] {
+			exits = append(exits, e.To)
+		}
+	}
+	return exits
+}
+
+// findFollow picks the block following the loop: the exit of its header for
+// a while loop, of its latch for a do-while loop, or the first exit
+func (d *decompiler) findFollow(l *loop) {
+	if exits := d.exitsOf(l, l.header); len(exits) == 1 {
+		l.follow = exits[0]
+		return
+	}
+	if len(l.latches) == 1 {
+		if exits := d.exitsOf(l, l.latches[0]); len(exits) == 1 {
+			l.follow = exits[0]
+			return
+		}
+	}
+	for b := range l.body {
+		if !l.body[b] {
+			continue
+		}
+		for _, exit := range d.exitsOf(l, b) {
+			if l.follow == -1 || exit < l.follow {
+				l.follow = exit
+			}
+		}
+	}
+}
+
+// restoreStacks restores copies of the stacks of a saved state, so that
+// the branches of a construct start from the same stacks
+func (d *decompiler) restoreStacks(saved state) {
+	d.stack = append([]expr(nil), saved.stack...)
+	d.scopes = append([]expr(nil), saved.scopes...)
+}
+
+func (d *decompiler) last(b int) bytecode.Instr {
+	return d.body.Instructions[d.graph.Blocks[b].End-1]
+}
+
+// instrBlock returns the block starting at an offset, or -1
+func (d *decompiler) blockAt(offset int64) int {
+	g := d.graph
+	for i, o := range g.Offsets[:len(d.body.Instructions)] {
+		if int64(o) == offset {
+			return g.BlockOf(i)
+		}
+	}
+	return -1
+}
+
+// targets returns the blocks a block branches to: the taken block then the
+// next one for a conditional branch
+func (d *decompiler) targets(b int) []int {
+	block := d.graph.Blocks[b]
+	i := block.End - 1
+	var targets []int
+	for _, offset := range d.body.Instructions[i].BranchTargets(d.graph.Offsets[i], d.graph.Offsets[i+1]) {
+		targets = append(targets, d.blockAt(offset))
+	}
+	return targets
+}
+
+func (d *decompiler) next(b int) int {
+	if b+1 < len(d.graph.Blocks) {
+		return b + 1
+	}
+	return -1
+}
+
+// skipJumps follows the blocks made of a jump only
+func (d *decompiler) skipJumps(b int) int {
+	for i := 0; b >= 0 && i < len(d.graph.Blocks); i++ {
+		block := d.graph.Blocks[b]
+		if block.End-block.Start != 1 || d.last(b).Model.Code != opJump || d.emitted[b] {
+			break
+		}
+		b = d.targets(b)[0]
+	}
+	return b
+}
+
+// inTry reports whether a block lies in the range of a try group
+func (d *decompiler) inTry(t *tryGroup, b int) bool {
+	offset := d.graph.Offsets[d.graph.Blocks[b].Start]
+	return offset >= t.from && offset < t.to
+}
+
+// jumpOut returns the statement leaving the enclosing loops and switches
+// when a block is their header or their follow
+func (d *decompiler) jumpOut(b int, ctx context) (stmt, bool) {
+	for i := len(ctx.breakables) - 1; i >= 0; i-- {
+		br := ctx.breakables[i]
+		switch {
+		case br.loop != nil && b == br.loop.header:
+			return newStmt("continue"), true
+		case b == br.follow:
+			return newStmt("break"), true
+		case br.cases[b]:
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+// region structures the code from a block up to the follow block, or up to
+// the end of the enclosing construct
+func (d *decompiler) region(b, follow int, ctx context) []stmt {
+	var out []stmt
+	for b >= 0 && b != follow {
+		entering := b == ctx.entering
+		ctx.entering = -1
+		if !entering {
+			if s, ok := d.jumpOut(b, ctx); ok {
+				if s != nil {
+					out = append(out, s)
+				}
+				break
+			}
+		}
+		if ctx.try != nil && !d.inTry(ctx.try, b) {
+			ctx.try.exits = append(ctx.try.exits, b)
+			break
+		}
+		if d.emitted[b] {
+			out = append(out, &commentStmt{fmt.Sprintf("goto %#x", d.graph.Offsets[d.graph.Blocks[b].Start])})
+			break
+		}
+		if t := d.tryAt(b, ctx); t != nil {
+			var s stmt
+			s, b = d.emitTry(t, b, ctx)
+			out = append(out, s)
+			continue
+		}
+		if l := d.loops[b]; l != nil && !entering {
+			var s []stmt
+			s, b = d.emitLoop(l, ctx)
+			if f, ok := s[0].(*forInStmt); ok {
+				out = hoistForIn(out, f)
+			}
+			out = append(out, s...)
+			continue
+		}
+
+		d.emitted[b] = true
+		out = append(out, d.exec(b)...)
+		last := d.last(b)
+		if l := ctx.doWhile; l != nil && len(l.latches) == 1 && b == l.latches[0] {
+			if d.targets(b)[0] != l.header {
+				d.cond = negate(d.cond)
+			}
+			break
+		}
+		switch code := last.Model.Code; {
+		case code == opReturnVoid || code == opReturnValue || code == opThrow:
+			b = -1
+		case code == opJump:
+			b = d.targets(b)[0]
+		case code == opLookupswitch:
+			var s stmt
+			s, b = d.emitSwitch(b, ctx)
+			out = append(out, s)
+		case isConditional(last):
+			var s []stmt
+			s, b = d.emitIf(b, ctx)
+			out = append(out, s...)
+		default:
+			b = d.next(b)
+		}
+	}
+	return simplify(out)
+}
+
+// condition interprets the header of a loop when it computes a condition
+// only, without statements, and combines it with the following blocks
+func (d *decompiler) condition(b int) (c expr, taken, next int, ok bool) {
+	if d.emitted[b] {
+		return expr{}, 0, 0, false
+	}
+	saved := d.state.clone()
+	if !d.execCondition(b, saved) {
+		d.state = saved
+		return expr{}, 0, 0, false
+	}
+	d.emitted[b] = true
+	c, taken, next = d.combine(d.cond, d.targets(b)[0], d.next(b), []int{b})
+	return c, taken, next, true
+}
+
+// combine merges a condition, branching to taken when true and to next
+// otherwise, with the conditions computed by the blocks it branches to into
+// && and || expressions, as compiled for short-circuit evaluations. Blocks
+// are combined when they are only reached from the blocks already combined.
+func (d *decompiler) combine(c expr, taken, next int, blocks []int) (expr, int, int) {
+	for {
+		if saved, ok := d.conditionBlock(next, blocks); ok {
+			t2, f2 := d.targets(next)[0], d.next(next)
+			if taken == t2 || taken == f2 {
+				d.emitted[next] = true
+				blocks = append(blocks, next)
+				if taken == t2 {
+					c, next = binary("||", precOr, c, d.cond), f2
+				} else {
+					c, next = binary("||", precOr, c, negate(d.cond)), t2
+				}
+				continue
+			}
+			d.state = saved
+		}
+		if saved, ok := d.conditionBlock(taken, blocks); ok {
+			t2, f2 := d.targets(taken)[0], d.next(taken)
+			if next == t2 || next == f2 {
+				d.emitted[taken] = true
+				blocks = append(blocks, taken)
+				if next == f2 {
+					c, taken = binary("&&", precAnd, c, d.cond), t2
+				} else {
+					c, taken = binary("&&", precAnd, c, negate(d.cond)), f2
+				}
+				continue
+			}
+			d.state = saved
+		}
+		return c, taken, next
+	}
+}
+
+// conditionBlock interprets a block computing a condition only, reached
+// from the given blocks only. It returns the state to restore when the
+// condition is not used.
+func (d *decompiler) conditionBlock(b int, blocks []int) (state, bool) {
+	if b < 0 || d.emitted[b] || d.loops[b] != nil || d.tryStarts(b) != nil {
+		return state{}, false
+	}
+	for _, p := range d.graph.Predecessors(b) {
+		found := false
+		for _, from := range blocks {
+			found = found || p == from
+		}
+		if !found {
+			return state{}, false
+		}
+	}
+	saved := d.state.clone()
+	if !d.execCondition(b, saved) {
+		d.state = saved
+		return state{}, false
+	}
+	return saved, true
+}
+
+// execCondition interprets a block and reports whether it computes a
+// condition only: it ends with a conditional branch, emits no statement and
+// leaves the stack as deep as it found it, the values it pushes being used
+// by the blocks it branches to otherwise
+func (d *decompiler) execCondition(b int, saved state) bool {
+	stmts := d.exec(b)
+	return len(stmts) == 0 && isConditional(d.last(b)) && len(d.stack) == len(saved.stack) && d.err == saved.err
+}
+
+// reachable returns the blocks reachable from a block without exceptions
+func (d *decompiler) reachable(b int) []bool {
+	seen := make([]bool, len(d.graph.Blocks))
+	worklist := []int{b}
+	for len(worklist) > 0 {
+		b := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+		if b < 0 || seen[b] {
+			continue
+		}
+		seen[b] = true
+		for _, e := range d.graph.Blocks[b].Succs {
+			if e.Kind != cfg.EdgeException {
+				worklist = append(worklist, e.To)
+			}
+		}
+	}
+	return seen
+}
+
+// mergeOf returns the block where the branches starting at b join: its
+// immediate post-dominator, or else the first block dominated by b reached
+// by both branches. It is -1 when the branches only join outside of the
+// enclosing loop or try.
+func (d *decompiler) mergeOf(b int, branches []int, ctx context) int {
+	merge := d.ipdom[b]
+	if merge < 0 && len(branches) == 2 && branches[0] >= 0 && branches[1] >= 0 {
+		a, c := d.reachable(branches[0]), d.reachable(branches[1])
+		for m := range a {
+			if a[m] && c[m] && m != b && cfg.Dominates(d.idom, b, m) {
+				merge = m
+				break
+			}
+		}
+	}
+	if merge < 0 {
+		return -1
+	}
+	if ctx.loop != nil && !ctx.loop.body[merge] {
+		return -1
+	}
+	if ctx.try != nil && !d.inTry(ctx.try, merge) {
+		return -1
+	}
+	return merge
+}
+
+// abrupt reports whether the statements end by leaving the code flow
+func abrupt(stmts []stmt) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	s, ok := stmts[len(stmts)-1].(*simpleStmt)
+	if !ok {
+		return false
+	}
+	for _, keyword := range []string{"return", "throw", "break", "continue"} {
+		if len(s.text) >= len(keyword) && s.text[:len(keyword)] == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// pushesValue reports whether two branches leave a different value on top
+// of the stack: a value pushed by both, or the value duplicated before the
+// branch replaced in one of them as compiled for && and ||
+func pushesValue(before, a, b []expr) bool {
+	switch top := len(a) - 1; {
+	case len(a) != len(b) || len(a) == 0:
+		return false
+	case len(a) == len(before)+1:
+		return true
+	case len(a) == len(before):
+		return a[top].text != b[top].text
+	}
+	return false
+}
+
+// emitIf structures a conditional branch ending block b, as an if
+// statement or, when both branches only push a value, as a conditional
+// expression
+func (d *decompiler) emitIf(b int, ctx context) ([]stmt, int) {
+	c, taken, next := d.combine(d.cond, d.targets(b)[0], d.next(b), []int{b})
+	merge := d.mergeOf(b, []int{taken, next}, ctx)
+
+	saved := d.state.clone()
+	then := d.region(next, merge, ctx)
+	thenStack := d.stack
+	d.restoreStacks(saved)
+	otherwise := d.region(taken, merge, ctx)
+	if len(then) == 0 && len(otherwise) == 0 && pushesValue(saved.stack, thenStack, d.stack) {
+		top := len(d.stack) - 1
+		d.stack = append(d.stack[:top:top], ternary(c, d.stack[top], thenStack[top]))
+		return nil, merge
+	}
+	if len(thenStack) > len(d.stack) {
+		d.stack = thenStack
+	}
+
+	cond := negate(c)
+	if len(then) == 0 {
+		cond, then, otherwise = c, otherwise, nil
+	}
+	if len(then) == 0 {
+		return nil, merge
+	}
+	if len(otherwise) == 0 {
+		otherwise = nil
+	}
+	if merge < 0 && otherwise != nil {
+		// one branch leaves: the other one continues after the if
+		switch {
+		case abrupt(otherwise):
+			return append([]stmt{&ifStmt{negate(cond), otherwise, nil}}, then...), -1
+		case abrupt(then):
+			return append([]stmt{&ifStmt{cond, then, nil}}, otherwise...), -1
+		}
+	}
+	return []stmt{&ifStmt{cond, then, otherwise}}, merge
+}
+
+// trimContinue removes the continue ending the body of a loop
+func trimContinue(body []stmt) []stmt {
+	if n := len(body); n > 0 {
+		if s, ok := body[n-1].(*simpleStmt); ok && s.text == "continue" {
+			return body[:n-1]
+		}
+	}
+	return body
+}
+
+// emitLoop structures a loop: a while loop when its header only computes
+// the condition, a for-in loop when that condition is hasnext2, a do-while
+// loop when its only latch computes the condition, or else an infinite loop
+// left with break
+func (d *decompiler) emitLoop(l *loop, ctx context) ([]stmt, int) {
+	inner := ctx.with(breakable{loop: l, follow: l.follow})
+	inner.loop, inner.doWhile, inner.entering = l, nil, -1
+
+	saved := d.state.clone()
+	emitted := append([]bool(nil), d.emitted...)
+	if c, taken, next, ok := d.condition(l.header); ok {
+		stay := -1
+		switch {
+		case taken >= 0 && l.body[taken] && next == l.follow:
+			stay = taken
+		case next >= 0 && l.body[next] && taken == l.follow:
+			stay, c = next, negate(c)
+		}
+		if stay >= 0 {
+			d.restoreStacks(saved)
+			if c.kind == kindHasNext {
+				return d.emitForIn(c, stay, inner), l.follow
+			}
+			body := trimContinue(d.region(stay, -1, inner))
+			return []stmt{&whileStmt{c, body}}, l.follow
+		}
+		d.emitted = emitted
+		d.state = saved
+	}
+
+	inner.entering = l.header
+	if len(l.latches) == 1 {
+		latch := l.latches[0]
+		last := d.last(latch)
+		if isConditional(last) {
+			targets := []int{d.targets(latch)[0], d.next(latch)}
+			if targets[0] == l.header && targets[1] == l.follow || targets[1] == l.header && targets[0] == l.follow {
+				inner.doWhile = l
+				body := d.region(l.header, -1, inner)
+				return []stmt{&doWhileStmt{body, d.cond}}, l.follow
+			}
+		}
+	}
+	body := trimContinue(d.region(l.header, -1, inner))
+	return []stmt{&whileStmt{primary("true"), body}}, l.follow
+}
+
+// emitForIn structures a loop on hasnext2. Its body starts by assigning the
+// next name or value to the loop variable.
+func (d *decompiler) emitForIn(c expr, start int, ctx context) []stmt {
+	body := trimContinue(d.region(start, -1, ctx))
+	f := &forInStmt{variable: "_loc_", object: primary(d.register(c.regs[0])), regs: c.regs}
+	if len(body) > 0 {
+		if s, ok := body[0].(*simpleStmt); ok && s.name != "" {
+			if i := strings.Index(s.text, " = "); i >= 0 {
+				f.variable, f.each = s.text[:i], strings.Contains(s.text, "nextvalue(")
+				body = body[1:]
+			}
+		}
+	}
+	f.body = body
+	return []stmt{f}
+}
+
+// hoistForIn removes from the statements preceding a for-in loop the
+// initialization of the registers it iterates with, the object iterated
+// becoming the object of the loop
+func hoistForIn(out []stmt, f *forInStmt) []stmt {
+	for _, r := range []uint32{f.regs[1], f.regs[0]} {
+		for i := len(out) - 1; i >= 0; i-- {
+			s, ok := out[i].(*simpleStmt)
+			if !ok || s.reg != int(r) {
+				continue
+			}
+			if r == f.regs[0] {
+				f.object = s.value
+			}
+			out = append(out[:i:i], out[i+1:]...)
+			break
+		}
+	}
+	return out
+}
+
+// caseLabels recovers the values of the cases of a switch compiled as a
+// chain of strict comparisons selecting the index of the case:
+// v === a ? 0 : v === b ? 1 : 2. It returns the value switched on and the
+// label of each index, or false.
+func caseLabels(e expr) (expr, map[int]string, int, bool) {
+	labels := map[int]string{}
+	var subject *expr
+	for e.op == "?:" {
+		if e.cond.op != "===" || (subject != nil && e.cond.left.text != subject.text) {
+			return expr{}, nil, 0, false
+		}
+		subject = e.cond.left
+		index, err := parseIndex(e.left.text)
+		if err != nil {
+			return expr{}, nil, 0, false
+		}
+		labels[index] = e.cond.right.text
+		e = *e.right
+	}
+	def, err := parseIndex(e.text)
+	if subject == nil || err != nil {
+		return expr{}, nil, 0, false
+	}
+	return *subject, labels, def, true
+}
+
+func parseIndex(s string) (int, error) {
+	var i int
+	_, err := fmt.Sscan(s, &i)
+	return i, err
+}
+
+// emitSwitch structures a lookupswitch ending block b
+func (d *decompiler) emitSwitch(b int, ctx context) (stmt, int) {
+	value := d.cond
+	targets := d.targets(b)
+	merge := d.mergeOf(b, nil, ctx)
+
+	labels := make([]string, len(targets))
+	for i := 1; i < len(targets); i++ {
+		labels[i] = fmt.Sprint(i - 1)
+	}
+	if subject, values, def, ok := caseLabels(value); ok {
+		value = subject
+		for i := 1; i < len(targets); i++ {
+			if v, ok := values[i-1]; ok {
+				labels[i] = v
+			} else if i-1 == def {
+				labels[i] = ""
+			}
+		}
+	}
+
+	// cases sharing a block, in the order of the code
+	var order []int
+	byBlock := map[int][]string{}
+	for i, t := range targets {
+		if t < 0 {
+			continue
+		}
+		if _, ok := byBlock[t]; !ok {
+			order = append(order, t)
+		}
+		byBlock[t] = append(byBlock[t], labels[i])
+	}
+	sort.Ints(order)
+	starts := map[int]bool{}
+	for _, t := range order {
+		starts[t] = true
+	}
+
+	s := &switchStmt{value: value}
+	inner := ctx.with(breakable{follow: merge, cases: starts})
+	saved := d.state.clone()
+	for _, t := range order {
+		if t == merge {
+			s.cases = append(s.cases, switchCase{byBlock[t], []stmt{newStmt("break")}})
+			continue
+		}
+		d.restoreStacks(saved)
+		inner.entering = t
+		s.cases = append(s.cases, switchCase{byBlock[t], d.region(t, -1, inner)})
+	}
+	return s, merge
+}
+
+// tryStarts returns the first unopened try group starting at a block
+func (d *decompiler) tryStarts(b int) *tryGroup {
+	for _, t := range d.tries {
+		if t.start == b && !t.opened {
+			return t
+		}
+	}
+	return nil
+}
+
+// tryAt returns the try group to open at a block, nested in the enclosing
+// one
+func (d *decompiler) tryAt(b int, ctx context) *tryGroup {
+	for _, t := range d.tries {
+		if t.start != b || t.opened {
+			continue
+		}
+		if ctx.try == nil || t.from >= ctx.try.from && t.to <= ctx.try.to {
+			return t
+		}
+	}
+	return nil
+}
+
+// emitTry structures the code protected by a try group and its handlers.
+// A handler starts with the exception alone on the stack.
+func (d *decompiler) emitTry(t *tryGroup, b int, ctx context) (stmt, int) {
+	t.opened = true
+	inner := ctx
+	inner.try, inner.entering = t, -1
+	body := d.region(b, -1, inner)
+	follow := -1
+	for _, exit := range t.exits {
+		if follow == -1 || exit < follow {
+			follow = exit
+		}
+	}
+	follow = d.skipJumps(follow)
+	after := d.stack
+	s := &tryStmt{body: body}
+	for _, e := range t.exceptions {
+		name, typ := "e", "*"
+		if e.VarName != 0 {
+			name = d.localName(e.VarName)
+		}
+		if e.ExcType != 0 {
+			typ = d.localName(e.ExcType)
+		}
+		d.stack, d.scopes = []expr{primary(name)}, nil
+		var handler []stmt
+		if h := d.blockAt(int64(e.Target)); h >= 0 {
+			handler = d.region(h, follow, ctx)
+		}
+		s.catches = append(s.catches, catchClause{name, typ, handler})
+	}
+	d.stack = after
+	return s, follow
+}
@@ -0,0 +1,83 @@
+package decompiler
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kelvyne/as3"
+)
+
+// WriteStub writes the declarations of a linked class without its code: its
+// package, imports, metadata and declaration, its fields with their types
+// and default values and the signatures of its constructor and methods,
+// whose bodies are empty
+func WriteStub(w io.Writer, abc *as3.AbcFile, c *as3.Class) error {
+	cw := newClassWriter(abc, c)
+	cw.stub = true
+	return cw.write(w)
+}
+
+// ExportStubs writes the stub of each class of the file into a directory,
+// one .as file per class in the directory of its package, such as
+// dir/com/example/Widget.as. Classes sharing a name, such as the private
+// classes of different scripts, are numbered: Widget.as, Widget$2.as, ...
+// skipping the names of the other classes, as $ may appear in names.
+func ExportStubs(dir string, abc *as3.AbcFile) error {
+	paths := make([]string, len(abc.Classes))
+	taken := map[string]bool{}
+	for i := range abc.Classes {
+		paths[i] = stubPath(dir, &abc.Classes[i])
+		taken[paths[i]] = true
+	}
+	used := map[string]bool{}
+	for i := range abc.Classes {
+		path := paths[i]
+		for n := 2; used[path]; n++ {
+			if numbered := strings.TrimSuffix(paths[i], ".as") + "$" + strconv.Itoa(n) + ".as"; !taken[numbered] {
+				path = numbered
+			}
+		}
+		used[path] = true
+		if err := writeStubFile(path, abc, &abc.Classes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stubPath returns the path of the file of a class. Separators in the names
+// are replaced so that files stay in the directory of their package.
+func stubPath(dir string, c *as3.Class) string {
+	clean := func(name string) string {
+		name = strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(name)
+		if name == "" || name == "." || name == ".." {
+			return "_"
+		}
+		return name
+	}
+	elems := []string{dir}
+	if pkg := packageName(c); pkg != "" {
+		for _, p := range strings.Split(pkg, ".") {
+			elems = append(elems, clean(p))
+		}
+	}
+	return filepath.Join(append(elems, clean(c.Name)+".as")...)
+}
+
+func writeStubFile(path string, abc *as3.AbcFile, c *as3.Class) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = WriteStub(f, abc, c); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
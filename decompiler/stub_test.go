@@ -0,0 +1,86 @@
+package decompiler
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteStub(t *testing.T) {
+	abc := linkSource(t, classSource)
+	buf := &bytes.Buffer{}
+	if err := WriteStub(buf, &abc, &abc.Classes[0]); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	want := `package com.example {
+
+    import flash.display.Sprite;
+    import flash.events.IEventDispatcher;
+
+    public class Widget extends Sprite implements IEventDispatcher {
+        public static const SIZE:int = 4;
+        private var _label:String;
+
+        public function Widget(name:String) {}
+
+        [Event(name="change", type="flash.events.Event")]
+        public function get label():String {}
+    }
+}
+`
+	if buf.String() != want {
+		t.Errorf("expected:\n%v\ngot:\n%v", want, buf.String())
+	}
+}
+
+func TestExportStubs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stubs")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	abc := linkFixture(t, "obf2")
+	if err = ExportStubs(dir, &abc); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	for _, path := range []string{
+		"StageShereManager.as",
+		"RolePleyFrame.as",
+		"com/ankamagames/jerakine/messages/Frame.as",
+		"com/ankamagames/jerakine/utils/misc/Prioritizable.as",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(path))); err != nil {
+			t.Errorf("%v: expected nil, got %v", path, err)
+		}
+	}
+}
+
+func TestExportStubs_SameName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stubs")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	abc := linkSource(t, classSource)
+	abc.Classes = append(abc.Classes, abc.Classes[0], abc.Classes[0])
+	abc.Classes[2].Name = "Widget$2"
+	if err = ExportStubs(dir, &abc); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	for path, class := range map[string]string{
+		"Widget.as":   "class Widget ",
+		"Widget$3.as": "class Widget ",
+		"Widget$2.as": "class Widget$2 ",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "com", "example", path))
+		if err != nil {
+			t.Errorf("%v: expected nil, got %v", path, err)
+		} else if !bytes.Contains(b, []byte(class)) {
+			t.Errorf("%v: expected %v, got:\n%s", path, class, b)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package export
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+// Ref is a reference to an entry of a table of the file: its raw index and
+// the name or the text the entry resolves to
+type Ref struct {
+	Index uint32 `json:"index"`
+	Name  string `json:"name"`
+}
+
+// Double is a number of the constant pool. NaN and the infinities, which
+// JSON numbers cannot represent, are written as the strings "NaN",
+// "Infinity" and "-Infinity".
+type Double float64
+
+// MarshalJSON implements json.Marshaler
+func (d Double) MarshalJSON() ([]byte, error) {
+	switch f := float64(d); {
+	case math.IsNaN(f):
+		return []byte(`"NaN"`), nil
+	case math.IsInf(f, 1):
+		return []byte(`"Infinity"`), nil
+	case math.IsInf(f, -1):
+		return []byte(`"-Infinity"`), nil
+	}
+	return []byte(strconv.FormatFloat(float64(d), 'g', -1, 64)), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, reading the numbers and the
+// strings written by MarshalJSON
+func (d *Double) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var f float64
+		if err := json.Unmarshal(b, &f); err != nil {
+			return err
+		}
+		*d = Double(f)
+		return nil
+	}
+	switch s {
+	case "NaN":
+		*d = Double(math.NaN())
+	case "Infinity":
+		*d = Double(math.Inf(1))
+	case "-Infinity":
+		*d = Double(math.Inf(-1))
+	default:
+		return fmt.Errorf("export: invalid double %q", s)
+	}
+	return nil
+}
+
+// BytecodeFile is the document exported for a bytecode.AbcFile. Each table
+// keeps the order and the indexes of the file.
+type BytecodeFile struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	MinorVersion  uint16         `json:"minorVersion"`
+	MajorVersion  uint16         `json:"majorVersion"`
+	ConstantPool  ConstantPool   `json:"constantPool"`
+	Methods       []MethodInfo   `json:"methods"`
+	Metadata      []MetadataInfo `json:"metadata"`
+	Instances     []InstanceInfo `json:"instances"`
+	Classes       []ClassInfo    `json:"classes"`
+	Scripts       []ScriptInfo   `json:"scripts"`
+	MethodBodies  []MethodBody   `json:"methodBodies"`
+}
+
+// ConstantPool holds the constants of the file. Entry 0 of each table is
+// the implicit default entry.
+type ConstantPool struct {
+	Integers   []int32     `json:"integers"`
+	UIntegers  []uint32    `json:"uintegers"`
+	Doubles    []Double    `json:"doubles"`
+	Strings    []string    `json:"strings"`
+	Namespaces []Namespace `json:"namespaces"`
+	NsSets     []NsSet     `json:"nsSets"`
+	Multinames []Multiname `json:"multinames"`
+}
+
+// Namespace is a namespace entry: its kind and its name
+type Namespace struct {
+	Kind     string `json:"kind"`
+	KindCode uint8  `json:"kindCode"`
+	Name     Ref    `json:"name"`
+}
+
+// NsSet is a namespace set entry and its text, as in {ns1, ns2}
+type NsSet struct {
+	Namespaces []Ref  `json:"namespaces"`
+	Text       string `json:"text"`
+}
+
+// Multiname is a multiname entry and its text, as in flash.display::Sprite.
+// Name, Namespace, NsSet and Params are only set for the kinds having them.
+type Multiname struct {
+	Kind      string `json:"kind"`
+	KindCode  uint8  `json:"kindCode"`
+	Text      string `json:"text"`
+	Name      *Ref   `json:"name,omitempty"`
+	Namespace *Ref   `json:"namespace,omitempty"`
+	NsSet     *Ref   `json:"nsSet,omitempty"`
+	Params    []Ref  `json:"params,omitempty"`
+}
+
+// Value is a constant value: the default of a slot or of a parameter.
+// Text is its actionscript literal.
+type Value struct {
+	Kind     string `json:"kind"`
+	KindCode uint8  `json:"kindCode"`
+	Index    uint32 `json:"index"`
+	Text     string `json:"text"`
+}
+
+// MethodInfo is a method signature
+type MethodInfo struct {
+	Name       Ref      `json:"name"`
+	ReturnType Ref      `json:"returnType"`
+	ParamTypes []Ref    `json:"paramTypes"`
+	Flags      uint8    `json:"flags"`
+	FlagNames  []string `json:"flagNames"`
+	Options    []Value  `json:"options,omitempty"`
+	ParamNames []Ref    `json:"paramNames,omitempty"`
+}
+
+// MetadataInfo is a metadata entry with its key/value items
+type MetadataInfo struct {
+	Name  Ref            `json:"name"`
+	Items []MetadataItem `json:"items"`
+}
+
+// MetadataItem is an item of a metadata entry. Keyless items have a key of
+// index 0.
+type MetadataItem struct {
+	Key   Ref `json:"key"`
+	Value Ref `json:"value"`
+}
+
+// Trait is a trait of an instance, a class, a script or a method body.
+// KindCode is the raw kind byte, attributes included. SlotID, Type and
+// Value are set for slots and constants, Class for classes, Function for
+// functions and Method for methods, getters and setters.
+type Trait struct {
+	Kind       string   `json:"kind"`
+	KindCode   uint8    `json:"kindCode"`
+	Name       Ref      `json:"name"`
+	Attributes []string `json:"attributes"`
+	SlotID     *uint32  `json:"slotId,omitempty"`
+	DispID     *uint32  `json:"dispId,omitempty"`
+	Type       *Ref     `json:"type,omitempty"`
+	Value      *Value   `json:"value,omitempty"`
+	Class      *Ref     `json:"class,omitempty"`
+	Function   *Ref     `json:"function,omitempty"`
+	Method     *Ref     `json:"method,omitempty"`
+	Metadata   []Ref    `json:"metadata,omitempty"`
+}
+
+// InstanceInfo is the instance part of a class
+type InstanceInfo struct {
+	Name        Ref      `json:"name"`
+	SuperName   Ref      `json:"superName"`
+	Flags       uint8    `json:"flags"`
+	FlagNames   []string `json:"flagNames"`
+	ProtectedNs *Ref     `json:"protectedNs,omitempty"`
+	Interfaces  []Ref    `json:"interfaces"`
+	IInit       Ref      `json:"iinit"`
+	Traits      []Trait  `json:"traits"`
+}
+
+// ClassInfo is the static part of a class
+type ClassInfo struct {
+	CInit  Ref     `json:"cinit"`
+	Traits []Trait `json:"traits"`
+}
+
+// ScriptInfo is a script with its initializer and its definitions
+type ScriptInfo struct {
+	Init   Ref     `json:"init"`
+	Traits []Trait `json:"traits"`
+}
+
+// MethodBody is the body of a method. Code is the hexadecimal bytecode and
+// Instructions, set on demand, its disassembly.
+type MethodBody struct {
+	Method         Ref           `json:"method"`
+	MaxStack       uint32        `json:"maxStack"`
+	LocalCount     uint32        `json:"localCount"`
+	InitScopeDepth uint32        `json:"initScopeDepth"`
+	MaxScopeDepth  uint32        `json:"maxScopeDepth"`
+	Code           string        `json:"code"`
+	Exceptions     []Exception   `json:"exceptions"`
+	Traits         []Trait       `json:"traits"`
+	Instructions   []Instruction `json:"instructions,omitempty"`
+}
+
+// Exception is an exception handler: the range of code it protects, the
+// offset of the handler, the type of exception caught and the name of its
+// variable
+type Exception struct {
+	From    uint32 `json:"from"`
+	To      uint32 `json:"to"`
+	Target  uint32 `json:"target"`
+	Type    Ref    `json:"type"`
+	VarName Ref    `json:"varName"`
+}
+
+// Bytecode returns the document of a parsed file. Method bodies are
+// disassembled when the options ask for the instructions.
+func Bytecode(abc *bytecode.AbcFile, opts Options) (*BytecodeFile, error) {
+	r := resolver{abc, &abc.ConstantPool}
+	c := &abc.ConstantPool
+	f := &BytecodeFile{
+		SchemaVersion: SchemaVersion,
+		MinorVersion:  abc.MinorVersion,
+		MajorVersion:  abc.MajorVersion,
+		ConstantPool: ConstantPool{
+			Integers:   append([]int32{}, c.Integers...),
+			UIntegers:  append([]uint32{}, c.UIntegers...),
+			Doubles:    make([]Double, len(c.Doubles)),
+			Strings:    append([]string{}, c.Strings...),
+			Namespaces: make([]Namespace, len(c.Namespaces)),
+			NsSets:     make([]NsSet, len(c.NsSets)),
+			Multinames: make([]Multiname, len(c.Multinames)),
+		},
+		Methods:      make([]MethodInfo, len(abc.Methods)),
+		Metadata:     make([]MetadataInfo, len(abc.Metadatas)),
+		Instances:    make([]InstanceInfo, len(abc.Instances)),
+		Classes:      make([]ClassInfo, len(abc.Classes)),
+		Scripts:      make([]ScriptInfo, len(abc.Scripts)),
+		MethodBodies: make([]MethodBody, len(abc.MethodBodies)),
+	}
+	for i, d := range c.Doubles {
+		f.ConstantPool.Doubles[i] = Double(d)
+	}
+	for i, ns := range c.Namespaces {
+		f.ConstantPool.Namespaces[i] = Namespace{kindName(bytecode.NamespaceKindNames, ns.Kind), ns.Kind, r.strRef(ns.Name)}
+	}
+	for i, set := range c.NsSets {
+		refs := []Ref{}
+		for _, ns := range set.Namespaces {
+			refs = append(refs, r.nsRef(ns))
+		}
+		f.ConstantPool.NsSets[i] = NsSet{refs, r.nsSetText(uint32(i))}
+	}
+	for i, m := range c.Multinames {
+		f.ConstantPool.Multinames[i] = r.multinameEntry(uint32(i), m)
+	}
+	for i, m := range abc.Methods {
+		f.Methods[i] = r.methodInfo(m)
+	}
+	for i, m := range abc.Metadatas {
+		items := []MetadataItem{}
+		for _, item := range m.Items {
+			items = append(items, MetadataItem{r.strRef(item.Key), r.strRef(item.Value)})
+		}
+		f.Metadata[i] = MetadataInfo{r.strRef(m.Names), items}
+	}
+	for i, inst := range abc.Instances {
+		interfaces := []Ref{}
+		for _, m := range inst.Interfaces {
+			interfaces = append(interfaces, r.multinameRef(m))
+		}
+		f.Instances[i] = InstanceInfo{
+			Name:       r.multinameRef(inst.Name),
+			SuperName:  r.multinameRef(inst.SuperName),
+			Flags:      inst.Flags,
+			FlagNames:  flagNames(inst.Flags, bytecode.InstanceFlagNames),
+			Interfaces: interfaces,
+			IInit:      r.methodRef(inst.IInit),
+			Traits:     r.traits(inst.Traits),
+		}
+		if inst.Flags&bytecode.InstanceInfoClassProtectedNs != 0 {
+			ns := r.nsRef(inst.ProtectedNs)
+			f.Instances[i].ProtectedNs = &ns
+		}
+	}
+	for i, class := range abc.Classes {
+		f.Classes[i] = ClassInfo{r.methodRef(class.CInit), r.traits(class.Traits)}
+	}
+	for i, s := range abc.Scripts {
+		f.Scripts[i] = ScriptInfo{r.methodRef(s.Init), r.traits(s.Traits)}
+	}
+	for i, body := range abc.MethodBodies {
+		b, err := r.methodBody(body, opts)
+		if err != nil {
+			return nil, err
+		}
+		f.MethodBodies[i] = b
+	}
+	return f, nil
+}
+
+// WriteBytecode writes the JSON document of a parsed file
+func WriteBytecode(w io.Writer, abc *bytecode.AbcFile, opts Options) error {
+	f, err := Bytecode(abc, opts)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, f, opts)
+}
+
+func (r resolver) strRef(i uint32) Ref {
+	return Ref{i, r.str(i)}
+}
+
+func (r resolver) nsRef(i uint32) Ref {
+	return Ref{i, r.namespace(i).URI}
+}
+
+func (r resolver) multinameRef(i uint32) Ref {
+	return Ref{i, r.multiname(i).String()}
+}
+
+func (r resolver) methodRef(i uint32) Ref {
+	return Ref{i, r.methodName(i)}
+}
+
+func (r resolver) valueOf(kind uint8, index uint32) Value {
+	name, ok := bytecode.ValueKindNames[kind]
+	if !ok {
+		name = kindName(bytecode.NamespaceKindNames, kind)
+	}
+	return Value{name, kind, index, r.value(kind, index)}
+}
+
+func (r resolver) multinameEntry(i uint32, m bytecode.MultinameInfo) Multiname {
+	e := Multiname{Kind: kindName(bytecode.MultinameKindNames, m.Kind), KindCode: m.Kind, Text: r.multiname(i).String()}
+	ref := func(ref Ref) *Ref { return &ref }
+	switch m.Kind {
+	case bytecode.MultinameKindQName, bytecode.MultinameKindQNameA:
+		e.Name, e.Namespace = ref(r.strRef(m.Name)), ref(r.nsRef(m.Namespace))
+	case bytecode.MultinameKindRTQName, bytecode.MultinameKindRTQNameA:
+		e.Name = ref(r.strRef(m.Name))
+	case bytecode.MultinameKindMultiname, bytecode.MultinameKindMultinameA:
+		e.Name, e.NsSet = ref(r.strRef(m.Name)), ref(Ref{m.NsSet, r.nsSetText(m.NsSet)})
+	case bytecode.MultinameKindMultinameL, bytecode.MultinameKindMultinameLA:
+		e.NsSet = ref(Ref{m.NsSet, r.nsSetText(m.NsSet)})
+	case bytecode.MultinameKindTypename:
+		e.Name = ref(r.multinameRef(m.Name))
+		e.Params = []Ref{}
+		for _, p := range m.Params {
+			e.Params = append(e.Params, r.multinameRef(p))
+		}
+	}
+	return e
+}
+
+func (r resolver) methodInfo(m bytecode.MethodInfo) MethodInfo {
+	info := MethodInfo{
+		Name:       r.strRef(m.Name),
+		ReturnType: r.multinameRef(m.ReturnType),
+		ParamTypes: []Ref{},
+		Flags:      m.Flags,
+		FlagNames:  flagNames(m.Flags, bytecode.MethodFlagNames),
+	}
+	for _, p := range m.ParamTypes {
+		info.ParamTypes = append(info.ParamTypes, r.multinameRef(p))
+	}
+	for _, o := range m.OptionInfo.Options {
+		info.Options = append(info.Options, r.valueOf(o.Kind, o.Value))
+	}
+	for _, name := range m.ParamInfo.ParamNames {
+		info.ParamNames = append(info.ParamNames, r.strRef(name))
+	}
+	return info
+}
+
+func (r resolver) traits(traits []bytecode.TraitsInfo) []Trait {
+	out := []Trait{}
+	for _, t := range traits {
+		out = append(out, r.trait(t))
+	}
+	return out
+}
+
+func (r resolver) trait(t bytecode.TraitsInfo) Trait {
+	e := Trait{
+		Kind:       kindName(bytecode.TraitKindNames, t.GetType()),
+		KindCode:   t.Kind,
+		Name:       r.multinameRef(t.Name),
+		Attributes: flagNames(t.Kind&0xf0, traitAttributes),
+	}
+	slotID, dispID := t.SlotID, t.DispID
+	ref := func(ref Ref) *Ref { return &ref }
+	switch t.GetType() {
+	case bytecode.TraitsInfoSlot, bytecode.TraitsInfoConst:
+		v := r.valueOf(t.VKind, t.VIndex)
+		e.SlotID, e.Type, e.Value = &slotID, ref(r.multinameRef(t.Typename)), &v
+		if t.VIndex == 0 {
+			e.Value = nil
+		}
+	case bytecode.TraitsInfoClass:
+		e.SlotID, e.Class = &slotID, ref(Ref{t.ClassI, r.className(t.ClassI)})
+	case bytecode.TraitsInfoFunction:
+		e.SlotID, e.Function = &slotID, ref(r.methodRef(t.Function))
+	case bytecode.TraitsInfoMethod, bytecode.TraitsInfoGetter, bytecode.TraitsInfoSetter:
+		e.DispID, e.Method = &dispID, ref(r.methodRef(t.Method))
+	}
+	for _, m := range t.Metadatas {
+		name := ""
+		if int(m) < len(r.abc.Metadatas) {
+			name = r.str(r.abc.Metadatas[m].Names)
+		}
+		e.Metadata = append(e.Metadata, Ref{m, name})
+	}
+	return e
+}
+
+func (r resolver) methodBody(body bytecode.MethodBodyInfo, opts Options) (MethodBody, error) {
+	b := MethodBody{
+		Method:         r.methodRef(body.Method),
+		MaxStack:       body.MaxStack,
+		LocalCount:     body.LocalCount,
+		InitScopeDepth: body.InitScopeLength,
+		MaxScopeDepth:  body.MaxScopeLength,
+		Code:           hex.EncodeToString(body.Code),
+		Exceptions:     []Exception{},
+		Traits:         r.traits(body.Traits),
+	}
+	for _, e := range body.Exceptions {
+		b.Exceptions = append(b.Exceptions, Exception{e.From, e.To, e.Target, r.multinameRef(e.ExcType), r.multinameRef(e.VarName)})
+	}
+	if opts.Instructions {
+		instrs, err := r.instructions(body)
+		if err != nil {
+			return MethodBody{}, err
+		}
+		b.Instructions = instrs
+	}
+	return b, nil
+}
+
+func writeJSON(w io.Writer, v interface{}, opts Options) error {
+	enc := json.NewEncoder(w)
+	if opts.Indent != "" {
+		enc.SetIndent("", opts.Indent)
+	}
+	return enc.Encode(v)
+}
@@ -0,0 +1,28 @@
+// Package export writes ABC files as JSON documents for tools not written
+// in Go, at two levels.
+//
+// WriteBytecode exports a parsed bytecode.AbcFile table by table, in the
+// order of the file. A reference to an entry of a table is written as a Ref
+// holding both the raw index and the name the entry resolves to:
+//
+//	{"index": 12, "name": "flash.display::Sprite"}
+//
+// Multinames refer to the constant pool, methods to the method table by
+// the name of their method_info and classes by the name of their instance.
+//
+// WriteLinked exports a linked as3.AbcFile as classes, scripts and
+// methods. Names are written as qualified names, ns::name, and the
+// methods are referred to by their index in the methods array.
+//
+// The Go types of both documents describe their schema: the json tags are
+// the field names, which are kept from a version to the next, and the doc
+// comments give their meaning. Every document starts with a schemaVersion
+// field. Options.Instructions adds the instructions of the method bodies,
+// with their offset, opcode, name, operands and branch targets:
+//
+//	{"offset": 4, "opcode": 93, "name": "findpropstrict", "operands": [
+//	    {"type": "multiname", "value": 3, "text": "trace"}]}
+//
+// Numbers JSON cannot represent, NaN and the infinities, are written as the
+// strings "NaN", "Infinity" and "-Infinity".
+package export
@@ -0,0 +1,14 @@
+package export
+
+// SchemaVersion is the version of the documents, written in their
+// schemaVersion field. It changes when fields are renamed or removed, not
+// when fields are added.
+const SchemaVersion = 1
+
+// Options configures an export.
+// Instructions adds the disassembled instructions of the method bodies and
+// Indent, when not empty, indents the JSON with it.
+type Options struct {
+	Instructions bool
+	Indent       string
+}
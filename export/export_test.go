@@ -0,0 +1,248 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kelvyne/as3"
+	"github.com/kelvyne/as3/asm"
+	"github.com/kelvyne/as3/bytecode"
+)
+
+func parseFixture(t *testing.T, name string) bytecode.AbcFile {
+	file, err := os.Open(fmt.Sprintf("../bytecode/fixtures/%v.abc", name))
+	if err != nil {
+		t.Fatalf("openFixture: %v", err)
+	}
+	defer file.Close()
+	a, err := bytecode.Parse(bytecode.NewReader(file))
+	if err != nil {
+		t.Fatalf("%v: expected nil, got %v", name, err)
+	}
+	return a
+}
+
+const source = `
+method 0
+  returns QName(PackageNamespace(""), "void")
+  param QName(PackageNamespace(""), "int")
+  optional Integer(-3)
+  flags HAS_OPTIONAL
+end
+
+method 1
+  returns null
+end
+
+method 2
+  returns null
+end
+
+class 0
+  instance QName(PackageNamespace("com.example"), "Widget")
+    extends QName(PackageNamespace("flash.display"), "Sprite")
+    flags SEALED
+    iinit 1
+    trait slot QName(PackageNamespace(""), "size") slotid 0 type QName(PackageNamespace(""), "Number") value Double(NaN)
+    trait method QName(PackageNamespace(""), "resize") dispid 0 method 0
+  end
+  cinit 2
+  end
+end
+
+body 0
+  method 0
+  maxstack 1
+  localcount 2
+  initscopedepth 0
+  maxscopedepth 1
+  code
+    getlocal_0
+    pushscope
+    findpropstrict QName(PackageNamespace(""), "trace")
+    pushstring "resize"
+    callpropvoid QName(PackageNamespace(""), "trace") 1
+    returnvoid
+  end
+end
+`
+
+func TestWriteBytecode(t *testing.T) {
+	a, err := asm.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("asm.Parse: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err = WriteBytecode(buf, &a, Options{Instructions: true}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `"doubles":[`) || !strings.Contains(buf.String(), `"NaN"]`) {
+		t.Errorf("expected the NaN double as a string, got %v", buf.String())
+	}
+
+	var doc struct {
+		SchemaVersion int `json:"schemaVersion"`
+		Methods       []MethodInfo
+		Instances     []InstanceInfo
+		MethodBodies  []MethodBody
+	}
+	if err = json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if doc.SchemaVersion != SchemaVersion {
+		t.Errorf("expected %v, got %v", SchemaVersion, doc.SchemaVersion)
+	}
+	if o := doc.Methods[0].Options[0]; o.Kind != "Integer" || o.Text != "-3" {
+		t.Errorf("expected Integer -3, got %v", o)
+	}
+	inst := doc.Instances[0]
+	if inst.Name.Name != "com.example::Widget" || inst.SuperName.Name != "flash.display::Sprite" {
+		t.Errorf("expected com.example::Widget extending flash.display::Sprite, got %v", inst)
+	}
+	if slot := inst.Traits[0]; slot.Type.Name != "Number" || slot.Value.Text != "NaN" || slot.Value.Kind != "Double" {
+		t.Errorf("expected a Number slot of value NaN, got %v", slot)
+	}
+	if m := inst.Traits[1]; m.Kind != "method" || m.Method.Index != 0 {
+		t.Errorf("expected method 0, got %v", m)
+	}
+	instrs := doc.MethodBodies[0].Instructions
+	if len(instrs) != 6 {
+		t.Fatalf("expected 6 instructions, got %v", len(instrs))
+	}
+	want := Instruction{4, 0x2c, "pushstring", []Operand{{"string", instrs[3].Operands[0].Value, `"resize"`}}, nil}
+	if got := instrs[3]; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestWriteLinked(t *testing.T) {
+	a, err := asm.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("asm.Parse: %v", err)
+	}
+	abc, err := as3.Link(&a)
+	if err != nil {
+		t.Fatalf("as3.Link: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err = WriteLinked(buf, &abc, Options{}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	var f LinkedFile
+	if err = json.Unmarshal(buf.Bytes(), &f); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	c := f.Classes[0]
+	if c.QName != "com.example::Widget" || c.Super != "flash.display::Sprite" || c.Constructor != 1 {
+		t.Errorf("expected com.example::Widget, got %v", c)
+	}
+	if s := c.InstanceTraits[0]; s.Kind != "slot" || s.Type != "Number" || s.Default == nil || s.Default.Text != "NaN" {
+		t.Errorf("expected a Number slot of value NaN, got %v", s)
+	}
+	if m := c.InstanceTraits[1]; m.Method == nil || *m.Method != 0 {
+		t.Errorf("expected method 0, got %v", m)
+	}
+	m := f.Methods[0]
+	if m.Kind != "method" || m.Signature != "function resize(param1:int = -3):void" || m.Class != "com.example::Widget" {
+		t.Errorf("expected the resize method, got %v", m)
+	}
+	if m.Body == nil || m.Body.MaxStack != 1 || m.Body.Instructions != nil {
+		t.Errorf("expected a body without instructions, got %v", m.Body)
+	}
+}
+
+func TestDouble_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{1.5, "1.5"},
+		{-2, "-2"},
+		{math.NaN(), `"NaN"`},
+		{math.Inf(1), `"Infinity"`},
+		{math.Inf(-1), `"-Infinity"`},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(Double(tt.value))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("expected %v, got %s (%v)", tt.want, got, err)
+		}
+	}
+}
+
+func TestDouble_UnmarshalJSON(t *testing.T) {
+	for _, value := range []float64{1.5, -2, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		b, err := json.Marshal(Double(value))
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+		var got Double
+		if err = json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("%s: expected nil, got %v", b, err)
+		}
+		if float64(got) != value && !(math.IsNaN(value) && math.IsNaN(float64(got))) {
+			t.Errorf("expected %v, got %v", value, got)
+		}
+	}
+	var d Double
+	if err := json.Unmarshal([]byte(`"one"`), &d); err == nil {
+		t.Errorf("expected non-nil, got %v", err)
+	}
+}
+
+func TestBytecode_RoundTrip(t *testing.T) {
+	a, err := asm.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("asm.Parse: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err = WriteBytecode(buf, &a, Options{Instructions: true}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	var got BytecodeFile
+	if err = json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	doubles := got.ConstantPool.Doubles
+	if len(doubles) == 0 || !math.IsNaN(float64(doubles[len(doubles)-1])) {
+		t.Errorf("expected a NaN double, got %v", doubles)
+	}
+	// NaN never equals itself, the documents are compared as JSON
+	again := &bytes.Buffer{}
+	if err = writeJSON(again, &got, Options{}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if again.String() != buf.String() {
+		t.Errorf("expected %v, got %v", buf, again)
+	}
+}
+
+func TestExport_Fixtures(t *testing.T) {
+	for _, name := range []string{"obf1", "obf2"} {
+		a := parseFixture(t, name)
+		if err := WriteBytecode(&bytes.Buffer{}, &a, Options{Instructions: true}); err != nil {
+			t.Errorf("%v: WriteBytecode: expected nil, got %v", name, err)
+		}
+		abc, err := as3.Link(&a)
+		if err != nil {
+			t.Fatalf("%v: as3.Link: %v", name, err)
+		}
+		buf := &bytes.Buffer{}
+		if err = WriteLinked(buf, &abc, Options{Instructions: true, Indent: "  "}); err != nil {
+			t.Errorf("%v: WriteLinked: expected nil, got %v", name, err)
+		}
+		var f LinkedFile
+		if err = json.Unmarshal(buf.Bytes(), &f); err != nil {
+			t.Fatalf("%v: json.Unmarshal: %v", name, err)
+		}
+		if len(f.Methods) != len(abc.Methods) || len(f.Classes) != len(abc.Classes) {
+			t.Errorf("%v: expected %v methods and %v classes, got %v and %v", name,
+				len(abc.Methods), len(abc.Classes), len(f.Methods), len(f.Classes))
+		}
+	}
+}
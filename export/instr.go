@@ -0,0 +1,50 @@
+package export
+
+import (
+	"github.com/kelvyne/as3/bytecode"
+)
+
+// Instruction is a disassembled instruction at an offset of the code.
+// Targets holds the offsets its branches point to.
+type Instruction struct {
+	Offset   uint32    `json:"offset"`
+	Opcode   uint8     `json:"opcode"`
+	Name     string    `json:"name"`
+	Operands []Operand `json:"operands"`
+	Targets  []int64   `json:"targets,omitempty"`
+}
+
+// Operand is an instruction operand: its type, its raw value, an index for
+// the constant pool operands, and the text it resolves to
+type Operand struct {
+	Type  string `json:"type"`
+	Value uint32 `json:"value"`
+	Text  string `json:"text"`
+}
+
+// instructions disassembles a copy of a method body
+func (r resolver) instructions(body bytecode.MethodBodyInfo) ([]Instruction, error) {
+	body.Instructions = nil
+	if err := body.Disassemble(); err != nil {
+		return nil, err
+	}
+	offsets, err := body.Offsets()
+	if err != nil {
+		return nil, err
+	}
+	instrs := make([]Instruction, len(body.Instructions))
+	for i, instr := range body.Instructions {
+		operands := []Operand{}
+		for _, o := range instr.TypedOperands() {
+			operands = append(operands, Operand{operandTypes[o.Type], o.Value, r.operand(o)})
+		}
+		instrs[i] = Instruction{
+			offsets[i],
+			instr.Model.Code,
+			instr.Model.Name,
+			operands,
+			instr.BranchTargets(offsets[i], offsets[i+1]),
+		}
+	}
+	return instrs, nil
+}
@@ -0,0 +1,318 @@
+package export
+
+import (
+	"io"
+
+	"github.com/kelvyne/as3"
+	"github.com/kelvyne/as3/bytecode"
+)
+
+// LinkedFile is the document exported for a linked as3.AbcFile. Methods
+// keep the indexes of the file, which the other entries refer to.
+type LinkedFile struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Classes       []LinkedClass  `json:"classes"`
+	Scripts       []LinkedScript `json:"scripts"`
+	Methods       []LinkedMethod `json:"methods"`
+}
+
+// LinkedNamespace is a namespace: its kind and its URI
+type LinkedNamespace struct {
+	Kind string `json:"kind"`
+	URI  string `json:"uri"`
+}
+
+// LinkedValue is a constant value and its actionscript literal
+type LinkedValue struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+}
+
+// LinkedMetadata is a metadata with its items. Keyless items have an empty
+// key.
+type LinkedMetadata struct {
+	Name  string               `json:"name"`
+	Items []LinkedMetadataItem `json:"items"`
+}
+
+// LinkedMetadataItem is a key/value item of a metadata
+type LinkedMetadataItem struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// LinkedClass is a class. Names are written as qualified names, such as
+// flash.display::Sprite. Super is empty for a class without super class.
+// Constructor and StaticInitializer are method indexes.
+type LinkedClass struct {
+	Name              string           `json:"name"`
+	Namespace         LinkedNamespace  `json:"namespace"`
+	QName             string           `json:"qname"`
+	Super             string           `json:"super"`
+	Interfaces        []string         `json:"interfaces"`
+	Flags             []string         `json:"flags"`
+	Metadata          []LinkedMetadata `json:"metadata"`
+	Constructor       int              `json:"constructor"`
+	StaticInitializer int              `json:"staticInitializer"`
+	InstanceTraits    []LinkedTrait    `json:"instanceTraits"`
+	ClassTraits       []LinkedTrait    `json:"classTraits"`
+}
+
+// LinkedTrait is a trait. Type and Default are set for slots and
+// constants, Class for classes and Method, a method index, for methods,
+// getters, setters and functions.
+type LinkedTrait struct {
+	Name      string           `json:"name"`
+	Namespace LinkedNamespace  `json:"namespace"`
+	QName     string           `json:"qname"`
+	Kind      string           `json:"kind"`
+	Final     bool             `json:"final"`
+	Override  bool             `json:"override"`
+	Type      string           `json:"type,omitempty"`
+	Default   *LinkedValue     `json:"default,omitempty"`
+	Class     string           `json:"class,omitempty"`
+	Method    *int             `json:"method,omitempty"`
+	Metadata  []LinkedMetadata `json:"metadata"`
+}
+
+// LinkedScript is a script: its initializer, a method index, its
+// definitions and the qualified names of the classes it defines
+type LinkedScript struct {
+	Init    int           `json:"init"`
+	Traits  []LinkedTrait `json:"traits"`
+	Classes []string      `json:"classes"`
+}
+
+// LinkedMethod is a method. Name is its debug name and QName the name of
+// the trait or the class it belongs to; Class is the qualified name of the
+// class owning it, if any. Signature is its actionscript declaration.
+type LinkedMethod struct {
+	Index          int              `json:"index"`
+	Name           string           `json:"name"`
+	QName          string           `json:"qname"`
+	Kind           string           `json:"kind"`
+	Signature      string           `json:"signature"`
+	ReturnType     string           `json:"returnType"`
+	Params         []LinkedParam    `json:"params"`
+	Variadic       bool             `json:"variadic"`
+	NeedsArguments bool             `json:"needsArguments"`
+	Class          string           `json:"class,omitempty"`
+	Metadata       []LinkedMetadata `json:"metadata"`
+	Body           *LinkedBody      `json:"body,omitempty"`
+}
+
+// LinkedParam is a parameter of a method, with its default value if any
+type LinkedParam struct {
+	Name    string       `json:"name"`
+	Type    string       `json:"type"`
+	Default *LinkedValue `json:"default,omitempty"`
+}
+
+// LinkedBody is the body of a method. Instructions are set on demand.
+type LinkedBody struct {
+	MaxStack       uint32            `json:"maxStack"`
+	LocalCount     uint32            `json:"localCount"`
+	InitScopeDepth uint32            `json:"initScopeDepth"`
+	MaxScopeDepth  uint32            `json:"maxScopeDepth"`
+	Exceptions     []LinkedException `json:"exceptions"`
+	Instructions   []Instruction     `json:"instructions,omitempty"`
+}
+
+// LinkedException is an exception handler with the qualified names of the
+// type it catches and of its variable
+type LinkedException struct {
+	From    uint32 `json:"from"`
+	To      uint32 `json:"to"`
+	Target  uint32 `json:"target"`
+	Type    string `json:"type"`
+	VarName string `json:"varName"`
+}
+
+// linkedExporter builds the document of a linked file
+type linkedExporter struct {
+	r       resolver
+	methods map[*as3.Method]int
+}
+
+// Linked returns the document of a linked file. Method bodies are
+// disassembled when the options ask for the instructions.
+func Linked(abc *as3.AbcFile, opts Options) (*LinkedFile, error) {
+	e := linkedExporter{resolver{abc.Source, &abc.Source.ConstantPool}, map[*as3.Method]int{}}
+	for i := range abc.Methods {
+		e.methods[&abc.Methods[i]] = i
+	}
+	f := &LinkedFile{
+		SchemaVersion: SchemaVersion,
+		Classes:       make([]LinkedClass, len(abc.Classes)),
+		Scripts:       make([]LinkedScript, len(abc.Scripts)),
+		Methods:       make([]LinkedMethod, len(abc.Methods)),
+	}
+	for i := range abc.Classes {
+		f.Classes[i] = e.class(&abc.Classes[i])
+	}
+	for i, s := range abc.Scripts {
+		script := LinkedScript{Init: -1, Traits: e.traits(s.Traits), Classes: []string{}}
+		if s.Init != nil {
+			script.Init = e.methods[s.Init]
+		}
+		for _, c := range s.Classes {
+			script.Classes = append(script.Classes, c.QName().String())
+		}
+		f.Scripts[i] = script
+	}
+	for i := range abc.Methods {
+		m, err := e.method(i, &abc.Methods[i], opts)
+		if err != nil {
+			return nil, err
+		}
+		f.Methods[i] = m
+	}
+	return f, nil
+}
+
+// WriteLinked writes the JSON document of a linked file
+func WriteLinked(w io.Writer, abc *as3.AbcFile, opts Options) error {
+	f, err := Linked(abc, opts)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, f, opts)
+}
+
+func linkedNamespace(ns as3.Namespace) LinkedNamespace {
+	return LinkedNamespace{kindName(bytecode.NamespaceKindNames, ns.Kind), ns.URI}
+}
+
+func linkedValue(v as3.Value) *LinkedValue {
+	kind, ok := bytecode.ValueKindNames[v.Kind]
+	if !ok {
+		kind = kindName(bytecode.NamespaceKindNames, v.Kind)
+	}
+	return &LinkedValue{kind, v.String()}
+}
+
+func linkedMetadata(metadatas []as3.Metadata) []LinkedMetadata {
+	out := []LinkedMetadata{}
+	for _, md := range metadatas {
+		items := []LinkedMetadataItem{}
+		for _, item := range md.Items {
+			items = append(items, LinkedMetadataItem{item.Key, item.Value})
+		}
+		out = append(out, LinkedMetadata{md.Name, items})
+	}
+	return out
+}
+
+func (e linkedExporter) class(c *as3.Class) LinkedClass {
+	lc := LinkedClass{
+		Name:              c.Name,
+		Namespace:         linkedNamespace(c.Namespace),
+		QName:             c.QName().String(),
+		Interfaces:        []string{},
+		Flags:             flagNames(c.InstanceInfo.Flags, bytecode.InstanceFlagNames),
+		Metadata:          linkedMetadata(c.Metadatas),
+		Constructor:       int(c.InstanceInfo.IInit),
+		StaticInitializer: int(c.ClassInfo.CInit),
+		InstanceTraits:    e.traits(c.InstanceTraits),
+		ClassTraits:       e.traits(c.ClassTraits),
+	}
+	if c.SuperName.Kind != 0 {
+		lc.Super = c.SuperName.String()
+	}
+	for _, m := range c.Interfaces {
+		lc.Interfaces = append(lc.Interfaces, m.String())
+	}
+	return lc
+}
+
+// traits returns the traits of an object: its slots and constants, its
+// classes, its functions then its methods
+func (e linkedExporter) traits(o as3.TraitsObject) []LinkedTrait {
+	var all []as3.Trait
+	all = append(all, o.Slots...)
+	all = append(all, o.Classes...)
+	all = append(all, o.Functions...)
+	all = append(all, o.Methods...)
+	out := []LinkedTrait{}
+	for _, t := range all {
+		out = append(out, e.trait(t))
+	}
+	return out
+}
+
+func (e linkedExporter) trait(t as3.Trait) LinkedTrait {
+	lt := LinkedTrait{
+		Name:      t.Name,
+		Namespace: linkedNamespace(t.Namespace),
+		QName:     t.QName().String(),
+		Kind:      kindName(bytecode.TraitKindNames, t.Source.GetType()),
+		Final:     t.IsFinal(),
+		Override:  t.IsOverride(),
+		Metadata:  linkedMetadata(t.Metadatas),
+	}
+	switch t.Source.GetType() {
+	case bytecode.TraitsInfoSlot, bytecode.TraitsInfoConst:
+		lt.Type = t.Typename.String()
+		if t.HasDefault {
+			lt.Default = linkedValue(t.Default)
+		}
+	}
+	if t.Class != nil {
+		lt.Class = t.Class.QName().String()
+	}
+	for _, m := range []*as3.Method{t.Method, t.Function} {
+		if i, ok := e.methods[m]; ok && m != nil {
+			lt.Method = &i
+		}
+	}
+	return lt
+}
+
+func (e linkedExporter) method(i int, m *as3.Method, opts Options) (LinkedMethod, error) {
+	lm := LinkedMethod{
+		Index:          i,
+		Name:           m.Name,
+		QName:          m.QName.String(),
+		Kind:           methodKinds[m.Kind],
+		Signature:      m.Signature(),
+		ReturnType:     m.ReturnType.String(),
+		Params:         []LinkedParam{},
+		Variadic:       m.IsVariadic(),
+		NeedsArguments: m.NeedsArguments(),
+		Metadata:       linkedMetadata(m.Metadatas),
+	}
+	for _, p := range m.Params() {
+		param := LinkedParam{Name: p.Name, Type: p.Type.String()}
+		if p.HasDefault {
+			param.Default = linkedValue(p.Default)
+		}
+		lm.Params = append(lm.Params, param)
+	}
+	if m.Class != nil {
+		lm.Class = m.Class.QName().String()
+	}
+	if !m.HasBody {
+		return lm, nil
+	}
+	body := m.BodyInfo
+	lm.Body = &LinkedBody{
+		MaxStack:       body.MaxStack,
+		LocalCount:     body.LocalCount,
+		InitScopeDepth: body.InitScopeLength,
+		MaxScopeDepth:  body.MaxScopeLength,
+		Exceptions:     []LinkedException{},
+	}
+	for _, ex := range body.Exceptions {
+		lm.Body.Exceptions = append(lm.Body.Exceptions, LinkedException{
+			ex.From, ex.To, ex.Target, e.r.multiname(ex.ExcType).String(), e.r.multiname(ex.VarName).String(),
+		})
+	}
+	if opts.Instructions {
+		instrs, err := e.r.instructions(body)
+		if err != nil {
+			return LinkedMethod{}, err
+		}
+		lm.Body.Instructions = instrs
+	}
+	return lm, nil
+}
@@ -0,0 +1,138 @@
+package export
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kelvyne/as3"
+	"github.com/kelvyne/as3/bytecode"
+)
+
+var methodKinds = map[as3.MethodKind]string{
+	as3.MethodKindFunction:          "function",
+	as3.MethodKindMethod:            "method",
+	as3.MethodKindGetter:            "getter",
+	as3.MethodKindSetter:            "setter",
+	as3.MethodKindConstructor:       "constructor",
+	as3.MethodKindStaticInitializer: "staticInitializer",
+	as3.MethodKindScriptInitializer: "scriptInitializer",
+}
+
+var operandTypes = map[bytecode.OperandType]string{
+	bytecode.OperandTypeUnsigned:  "unsigned",
+	bytecode.OperandTypeByte:      "byte",
+	bytecode.OperandTypeShort:     "short",
+	bytecode.OperandTypeArgCount:  "argCount",
+	bytecode.OperandTypeInt:       "int",
+	bytecode.OperandTypeUInt:      "uint",
+	bytecode.OperandTypeDouble:    "double",
+	bytecode.OperandTypeString:    "string",
+	bytecode.OperandTypeNamespace: "namespace",
+	bytecode.OperandTypeMultiname: "multiname",
+	bytecode.OperandTypeMethod:    "method",
+	bytecode.OperandTypeClass:     "class",
+	bytecode.OperandTypeException: "exception",
+	bytecode.OperandTypeRegister:  "register",
+	bytecode.OperandTypeSlot:      "slot",
+	bytecode.OperandTypeBranch:    "branch",
+}
+
+var traitAttributes = []bytecode.Flag{
+	{Bit: bytecode.TraitsInfoAttributeFinal, Name: "final"},
+	{Bit: bytecode.TraitsInfoAttributeOverride, Name: "override"},
+	{Bit: bytecode.TraitsInfoAttributeMetadata, Name: "metadata"},
+}
+
+// flagNames returns the names of the bits set in a flags field, never nil
+// so that it is written as a JSON array
+func flagNames(flags uint8, names []bytecode.Flag) []string {
+	return append([]string{}, bytecode.FlagNames(flags, names)...)
+}
+
+// kindName returns the name of a kind, or its code when it has none
+func kindName(names map[uint8]string, kind uint8) string {
+	if name, ok := names[kind]; ok {
+		return name
+	}
+	return fmt.Sprintf("%#x", kind)
+}
+
+// resolver resolves the indexes of a file into the names they refer to.
+// Invalid indexes resolve to empty names.
+type resolver struct {
+	abc   *bytecode.AbcFile
+	cpool *bytecode.CpoolInfo
+}
+
+func (r resolver) str(i uint32) string {
+	if i == 0 || int(i) >= len(r.cpool.Strings) {
+		return ""
+	}
+	return r.cpool.Strings[i]
+}
+
+func (r resolver) namespace(i uint32) as3.Namespace {
+	return as3.ResolveNamespace(r.abc, i)
+}
+
+func (r resolver) nsSet(i uint32) []as3.Namespace {
+	return as3.ResolveNsSet(r.abc, i)
+}
+
+func (r resolver) nsSetText(i uint32) string {
+	uris := []string{}
+	for _, ns := range r.nsSet(i) {
+		uris = append(uris, ns.URI)
+	}
+	return "{" + strings.Join(uris, ", ") + "}"
+}
+
+func (r resolver) multiname(i uint32) as3.Multiname {
+	return as3.ResolveMultiname(r.abc, i)
+}
+
+// value returns the literal of a constant value
+func (r resolver) value(kind uint8, index uint32) string {
+	return as3.ResolveValue(r.abc, kind, index).String()
+}
+
+func (r resolver) methodName(i uint32) string {
+	if int(i) >= len(r.abc.Methods) {
+		return ""
+	}
+	return r.str(r.abc.Methods[i].Name)
+}
+
+func (r resolver) className(i uint32) string {
+	if int(i) >= len(r.abc.Instances) {
+		return ""
+	}
+	return r.multiname(r.abc.Instances[i].Name).String()
+}
+
+// operand returns the text of an instruction operand: the constant or the
+// name it refers to, or its value
+func (r resolver) operand(o bytecode.Operand) string {
+	switch o.Type {
+	case bytecode.OperandTypeByte, bytecode.OperandTypeShort, bytecode.OperandTypeBranch:
+		return strconv.Itoa(int(o.Signed()))
+	case bytecode.OperandTypeInt:
+		return r.value(bytecode.SlotKindInt, o.Value)
+	case bytecode.OperandTypeUInt:
+		return r.value(bytecode.SlotKindUInt, o.Value)
+	case bytecode.OperandTypeDouble:
+		return r.value(bytecode.SlotKindDouble, o.Value)
+	case bytecode.OperandTypeString:
+		return strconv.Quote(r.str(o.Value))
+	case bytecode.OperandTypeNamespace:
+		return r.namespace(o.Value).URI
+	case bytecode.OperandTypeMultiname:
+		return r.multiname(o.Value).String()
+	case bytecode.OperandTypeMethod:
+		return r.methodName(o.Value)
+	case bytecode.OperandTypeClass:
+		return r.className(o.Value)
+	}
+	return strconv.FormatUint(uint64(o.Value), 10)
+}
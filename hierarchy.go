@@ -0,0 +1,142 @@
+package as3
+
+import "github.com/kelvyne/as3/bytecode"
+
+// Ancestors returns the super classes of the class, from its direct super
+// class up to the root of the hierarchy, which is usually the external
+// Object class. A cycle in the hierarchy ends the chain.
+func (c *Class) Ancestors() []*Class {
+	var ancestors []*Class
+	seen := map[*Class]bool{c: true}
+	for super := c.Super; super != nil && !seen[super]; super = super.Super {
+		seen[super] = true
+		ancestors = append(ancestors, super)
+	}
+	return ancestors
+}
+
+// Subclasses returns all the classes extending the class, directly or not,
+// in depth-first order
+func (c *Class) Subclasses() []*Class {
+	var subclasses []*Class
+	seen := map[*Class]bool{c: true}
+	var walk func(*Class)
+	walk = func(parent *Class) {
+		for _, child := range parent.Children {
+			if !seen[child] {
+				seen[child] = true
+				subclasses = append(subclasses, child)
+				walk(child)
+			}
+		}
+	}
+	walk(c)
+	return subclasses
+}
+
+// IsSubclassOf reports whether the class extends other, directly or not
+func (c *Class) IsSubclassOf(other *Class) bool {
+	for _, ancestor := range c.Ancestors() {
+		if ancestor == other {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOverride reports whether the trait is marked as overriding a trait of a
+// super class
+func (t Trait) IsOverride() bool {
+	return t.Source.Kind&bytecode.TraitsInfoAttributeOverride != 0
+}
+
+// IsFinal reports whether the trait is marked as final
+func (t Trait) IsFinal() bool {
+	return t.Source.Kind&bytecode.TraitsInfoAttributeFinal != 0
+}
+
+// Member is an effective instance member of a class.
+// Owner is the class defining the trait and Overridden the member of an
+// ancestor it replaces, if any. SignatureChanged reports that the return type
+// or the parameter types differ from those of the overridden member.
+type Member struct {
+	Trait            Trait
+	Owner            *Class
+	Overridden       *Member
+	SignatureChanged bool
+}
+
+// memberKey identifies the members replacing each other. Getters and
+// setters of the same name are distinct members. Protected namespaces are
+// compared by kind only, since each class has its own. Private members never
+// replace each other and have no key.
+type memberKey struct {
+	name     QName
+	accessor uint8
+}
+
+func newMemberKey(t Trait) memberKey {
+	k := memberKey{t.QName(), 0}
+	if k.name.Namespace.Kind == bytecode.NamespaceKindProtectedNamespace {
+		k.name.Namespace.URI = ""
+	}
+	switch t.Source.GetType() {
+	case bytecode.TraitsInfoGetter, bytecode.TraitsInfoSetter:
+		k.accessor = t.Source.GetType()
+	}
+	return k
+}
+
+// Members returns the instance members of the class, including those
+// inherited from its ancestors defined in the file. Members keep the
+// position of the first ancestor defining them, so inherited members come
+// first. Private members are never overridden, even when the private
+// namespaces of two classes share their URI.
+func (c *Class) Members() []Member {
+	ancestors := c.Ancestors()
+	var members []Member
+	index := map[memberKey]int{}
+	for i := len(ancestors) - 1; i >= -1; i-- {
+		owner := c
+		if i >= 0 {
+			owner = ancestors[i]
+		}
+		for _, t := range owner.InstanceTraits.traits() {
+			m := Member{t, owner, nil, false}
+			if t.Namespace.Kind == bytecode.NamespaceKindPrivateNs {
+				members = append(members, m)
+				continue
+			}
+			k := newMemberKey(t)
+			j, ok := index[k]
+			if !ok {
+				index[k] = len(members)
+				members = append(members, m)
+				continue
+			}
+			overridden := members[j]
+			m.Overridden = &overridden
+			m.SignatureChanged = !sameSignature(t, overridden.Trait)
+			members[j] = m
+		}
+	}
+	return members
+}
+
+// sameSignature reports whether two method traits have the same return type
+// and parameter types
+func sameSignature(a, b Trait) bool {
+	if a.Method == nil || b.Method == nil {
+		return a.Method == b.Method
+	}
+	if a.Method.ReturnType.String() != b.Method.ReturnType.String() ||
+		len(a.Method.ParamTypes) != len(b.Method.ParamTypes) {
+		return false
+	}
+	for i, p := range a.Method.ParamTypes {
+		if p.String() != b.Method.ParamTypes[i].String() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package as3
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+// classNames returns the qualified names of classes
+func classNames(classes []*Class) []string {
+	var names []string
+	for _, c := range classes {
+		names = append(names, c.QName().String())
+	}
+	return names
+}
+
+func TestLink_Hierarchy(t *testing.T) {
+	var a bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&a.ConstantPool)
+	global := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	display := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString("flash.display"))
+	events := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString("flash.events"))
+	lookup := b.AddNsSet(events, global)
+	a.Methods = make([]bytecode.MethodInfo, 1)
+	a.Instances = []bytecode.InstanceInfo{
+		{Name: b.AddQName(global, "IFoo"), Flags: bytecode.InstanceInfoClassInterface},
+		{Name: b.AddQName(global, "Base"), SuperName: b.AddQName(display, "Sprite")},
+		{
+			Name:      b.AddQName(global, "Derived"),
+			SuperName: b.AddMultiname(bytecode.MultinameInfo{Kind: bytecode.MultinameKindMultiname, Name: b.AddString("Base"), NsSet: lookup}),
+			Interfaces: []uint32{
+				b.AddMultiname(bytecode.MultinameInfo{Kind: bytecode.MultinameKindMultiname, Name: b.AddString("IFoo"), NsSet: lookup}),
+				b.AddQName(events, "IEventDispatcher"),
+			},
+		},
+		{Name: b.AddQName(global, "Leaf"), SuperName: b.AddQName(global, "Derived")},
+		{Name: b.AddQName(global, "Other"), SuperName: b.AddQName(display, "Sprite")},
+	}
+	a.Classes = make([]bytecode.ClassInfo, len(a.Instances))
+
+	l, err := Link(&a)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	iface, base, derived, leaf, other := &l.Classes[0], &l.Classes[1], &l.Classes[2], &l.Classes[3], &l.Classes[4]
+
+	if got := classNames(l.Externals); !reflect.DeepEqual(got, []string{"flash.display::Sprite", "flash.events::IEventDispatcher"}) {
+		t.Errorf("expected Sprite and IEventDispatcher externals, got %v", got)
+	}
+	sprite := l.Externals[0]
+	if !sprite.External || base.External {
+		t.Errorf("expected only Sprite to be external")
+	}
+	if iface.Super != nil || base.Super != sprite || other.Super != sprite || derived.Super != base {
+		t.Errorf("expected super classes to be resolved")
+	}
+	if want := []*Class{iface, l.Externals[1]}; !reflect.DeepEqual(derived.Implements, want) {
+		t.Errorf("expected %v, got %v", classNames(want), classNames(derived.Implements))
+	}
+	if want := []string{"Derived", "Base", "flash.display::Sprite"}; !reflect.DeepEqual(classNames(leaf.Ancestors()), want) {
+		t.Errorf("expected %v, got %v", want, classNames(leaf.Ancestors()))
+	}
+	if want := []string{"Base", "Derived", "Leaf", "Other"}; !reflect.DeepEqual(classNames(sprite.Subclasses()), want) {
+		t.Errorf("expected %v, got %v", want, classNames(sprite.Subclasses()))
+	}
+	if !leaf.IsSubclassOf(sprite) || leaf.IsSubclassOf(other) || base.IsSubclassOf(base) {
+		t.Errorf("expected Leaf to extend Sprite only")
+	}
+}
+
+func TestClass_Ancestors_Cycle(t *testing.T) {
+	a, b := &Class{Name: "A"}, &Class{Name: "B"}
+	a.Super, b.Super = b, a
+	a.Children, b.Children = []*Class{b}, []*Class{a}
+	if got := classNames(a.Ancestors()); !reflect.DeepEqual(got, []string{"B"}) {
+		t.Errorf("expected [B], got %v", got)
+	}
+	if got := classNames(a.Subclasses()); !reflect.DeepEqual(got, []string{"B"}) {
+		t.Errorf("expected [B], got %v", got)
+	}
+}
+
+func TestClass_Members(t *testing.T) {
+	var a bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&a.ConstantPool)
+	global := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	baseNs := b.AddNamespace(bytecode.NamespaceKindProtectedNamespace, b.AddString("Base"))
+	derivedNs := b.AddNamespace(bytecode.NamespaceKindProtectedNamespace, b.AddString("Derived"))
+	integer, str := b.AddQName(global, "int"), b.AddQName(global, "String")
+	a.Methods = []bytecode.MethodInfo{
+		{}, {ParamTypes: []uint32{integer}}, {ReturnType: integer}, {}, {ParamTypes: []uint32{integer}},
+		{ParamTypes: []uint32{str}}, {}, {},
+	}
+	foo, bar := b.AddQName(global, "foo"), b.AddQName(global, "bar")
+	override := uint8(bytecode.TraitsInfoMethod | bytecode.TraitsInfoAttributeOverride)
+	a.Instances = []bytecode.InstanceInfo{
+		{Name: b.AddQName(global, "Base"), Traits: []bytecode.TraitsInfo{
+			{Name: foo, Kind: bytecode.TraitsInfoMethod, Method: 1},
+			{Name: bar, Kind: bytecode.TraitsInfoGetter, Method: 2},
+			{Name: bar, Kind: bytecode.TraitsInfoSetter, Method: 3},
+			{Name: b.AddQName(baseNs, "baz"), Kind: bytecode.TraitsInfoMethod, Method: 4},
+		}},
+		{Name: b.AddQName(global, "Derived"), SuperName: b.AddQName(global, "Base"), Traits: []bytecode.TraitsInfo{
+			{Name: foo, Kind: override, Method: 5},
+			{Name: b.AddQName(derivedNs, "baz"), Kind: override, Method: 4},
+			{Name: b.AddQName(global, "qux"), Kind: bytecode.TraitsInfoMethod | bytecode.TraitsInfoAttributeFinal, Method: 6},
+		}},
+	}
+	a.Classes = make([]bytecode.ClassInfo, len(a.Instances))
+
+	l, err := Link(&a)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	base, derived := &l.Classes[0], &l.Classes[1]
+	members := derived.Members()
+	tests := []struct {
+		name       string
+		owner      *Class
+		overridden bool
+		changed    bool
+		final      bool
+	}{
+		{"foo", derived, true, true, false},
+		{"bar", base, false, false, false},
+		{"bar", base, false, false, false},
+		{"baz", derived, true, false, false},
+		{"qux", derived, false, false, true},
+	}
+	if len(members) != len(tests) {
+		t.Fatalf("expected %v members, got %v", len(tests), len(members))
+	}
+	for i, tt := range tests {
+		m := members[i]
+		if m.Trait.Name != tt.name || m.Owner != tt.owner {
+			t.Errorf("member %v: expected %v of %v, got %v of %v", i, tt.name, tt.owner.Name, m.Trait.Name, m.Owner.Name)
+		}
+		if (m.Overridden != nil) != tt.overridden || m.SignatureChanged != tt.changed {
+			t.Errorf("member %v: expected overridden %v and changed %v, got %v and %v", i, tt.overridden, tt.changed, m.Overridden != nil, m.SignatureChanged)
+		}
+		if m.Trait.IsFinal() != tt.final || m.Trait.IsOverride() != tt.overridden {
+			t.Errorf("member %v: wrong attributes %x", i, m.Trait.Source.Kind)
+		}
+	}
+	if members[0].Overridden.Owner != base {
+		t.Errorf("expected foo to override Base.foo")
+	}
+	if got := len(base.Members()); got != 4 {
+		t.Errorf("expected 4, got %v", got)
+	}
+}
+
+func TestClass_Members_Private(t *testing.T) {
+	var a bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&a.ConstantPool)
+	global := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	private := b.AddNamespace(bytecode.NamespaceKindPrivateNs, b.AddString(""))
+	a.Methods = make([]bytecode.MethodInfo, 2)
+	a.Instances = []bytecode.InstanceInfo{
+		{Name: b.AddQName(global, "Base"), Traits: []bytecode.TraitsInfo{
+			{Name: b.AddQName(private, "secret"), Kind: bytecode.TraitsInfoMethod, Method: 0},
+		}},
+		{Name: b.AddQName(global, "Derived"), SuperName: b.AddQName(global, "Base"), Traits: []bytecode.TraitsInfo{
+			{Name: b.AddQName(private, "secret"), Kind: bytecode.TraitsInfoMethod, Method: 1},
+		}},
+	}
+	a.Classes = make([]bytecode.ClassInfo, len(a.Instances))
+
+	l, err := Link(&a)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	base, derived := &l.Classes[0], &l.Classes[1]
+	members := derived.Members()
+	if len(members) != 2 {
+		t.Fatalf("expected 2 members, got %v", len(members))
+	}
+	for i, owner := range []*Class{base, derived} {
+		if members[i].Owner != owner || members[i].Overridden != nil {
+			t.Errorf("member %v: expected %v not overridden, got %v overriding %v", i, owner.Name, members[i].Owner.Name, members[i].Overridden)
+		}
+	}
+}
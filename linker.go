@@ -1,78 +1,218 @@
 package as3
 
-import "github.com/kelvyne/as3/bytecode"
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kelvyne/as3/bytecode"
+)
 
 // ErrLinkerUnknownTrait means that an unknown trait_info kind was found when
-// build a TraitsObject
+// building a TraitsObject
 var ErrLinkerUnknownTrait = errors.New("linker unknown trait")
 
+// ErrLinkerUnknownValueKind means that a slot, a constant or an optional
+// parameter has a value of an unknown kind
+var ErrLinkerUnknownValueKind = errors.New("linker unknown value kind")
+
+// ErrLinkerRecursiveTypename means that the parameters of a TypeName are
+// nested too deeply, usually because the TypeName refers to itself
+var ErrLinkerRecursiveTypename = errors.New("linker recursive typename")
+
+// ErrLinkerInvalidIndex means that an entry of the file refers to a method,
+// a class, a metadata or a constant pool entry that does not exist
+var ErrLinkerInvalidIndex = errors.New("linker invalid index")
+
+// LinkError is an error found by the linker, located by the entry of the
+// file holding the bad reference: its kind ("class", "method",
+// "method body", "script" or "metadata") and its index, the index of the
+// trait within the entry, or -1 outside of traits, and the reference itself,
+// such as "multiname 42"
+type LinkError struct {
+	Entry string
+	Index int
+	Trait int
+	Ref   string
+	Err   error
+}
+
+func (e *LinkError) Error() string {
+	s := fmt.Sprintf("%v %v", e.Entry, e.Index)
+	if e.Trait >= 0 {
+		s += fmt.Sprintf(": trait %v", e.Trait)
+	}
+	if e.Ref != "" {
+		s += ": " + e.Ref
+	}
+	return s + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error, such as ErrLinkerInvalidIndex
+func (e *LinkError) Unwrap() error {
+	return e.Err
+}
+
 type linker struct {
-	abc *bytecode.AbcFile
+	abc       *bytecode.AbcFile
+	classes   []Class
+	methods   []Method
+	metadatas []Metadata
+	defined   map[QName]*Class
+	externals []*Class
+	lenient   bool
+	errs      []*LinkError
+	refs      []LinkError
 }
 
 // Link produces a linked version of the AbcFile provided. It :
 // - Links instance_info and class_info and resolve informations about each class
-// - Resolve method names, parameters and return types
+// - Resolve method names, parameter types, names and default values and
+// return types
 // and link method_body_info when the method has a body
+// - Links script_info with their init method and the classes they define
+// - Resolves metadata and attaches them to traits, classes and methods
+// - Links traits to their methods and classes, and methods back to the class
+// and the script owning them
+// - Links classes to their super class and interfaces, defined in the file or
+// external
+// Every index is checked: the first bad one stops the linking with a
+// *LinkError.
 func Link(abcFile *bytecode.AbcFile) (AbcFile, error) {
-	l := linker{abcFile}
+	l := linker{abc: abcFile}
 	return l.Link()
 }
 
+// LinkLenient links the AbcFile like Link, but records the problems found
+// and keeps going. Bad references are replaced by zero values: an empty
+// string, the any name, or a nil method or class, and traits of an unknown
+// kind are dropped.
+func LinkLenient(abcFile *bytecode.AbcFile) (AbcFile, []*LinkError) {
+	l := linker{abc: abcFile, lenient: true}
+	f, _ := l.Link()
+	return f, l.errs
+}
+
 func (l *linker) Link() (AbcFile, error) {
-	classes, err := l.LinkClasses()
+	metadatas, err := l.LinkMetadatas()
 	if err != nil {
 		return AbcFile{}, err
 	}
+	l.metadatas = metadatas
 	methods, err := l.LinkMethods()
 	if err != nil {
 		return AbcFile{}, err
 	}
-	return AbcFile{l.abc, classes, methods}, nil
+	l.methods = methods
+	classes, err := l.LinkClasses()
+	if err != nil {
+		return AbcFile{}, err
+	}
+	scripts, err := l.LinkScripts()
+	if err != nil {
+		return AbcFile{}, err
+	}
+	l.linkOwners(scripts)
+	l.linkHierarchy()
+	return AbcFile{l.abc, classes, methods, scripts, l.externals}, nil
+}
+
+// fail reports an error. It is returned in strict mode and recorded in
+// lenient mode, where linking goes on.
+func (l *linker) fail(e *LinkError) error {
+	if !l.lenient {
+		return e
+	}
+	l.errs = append(l.errs, e)
+	return nil
+}
+
+// badRef records a bad reference found while resolving an entry, to be
+// reported by check once the location of the entry is known
+func (l *linker) badRef(err error, format string, args ...interface{}) {
+	l.refs = append(l.refs, LinkError{Ref: fmt.Sprintf(format, args...), Err: err})
+}
+
+// check reports the bad references recorded since the last check, located
+// at the given entry and trait
+func (l *linker) check(entry string, index, trait int) error {
+	refs := l.refs
+	l.refs = nil
+	for _, ref := range refs {
+		if err := l.fail(&LinkError{entry, index, trait, ref.Ref, ref.Err}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// methodRef returns the method of the given index, or nil after recording a
+// bad reference
+func (l *linker) methodRef(i uint32) *Method {
+	if int(i) >= len(l.methods) {
+		l.badRef(ErrLinkerInvalidIndex, "method %v", i)
+		return nil
+	}
+	return &l.methods[i]
+}
+
+// LinkClasses links the classes once the methods are linked, so that traits
+// can refer to them
 func (l *linker) LinkClasses() ([]Class, error) {
-	classes := make([]Class, len(l.abc.Classes))
-	for i := range classes {
+	l.classes = make([]Class, len(l.abc.Classes))
+	if len(l.abc.Instances) != len(l.abc.Classes) {
+		err := l.fail(&LinkError{"class", len(l.abc.Instances), -1, "instance_info", ErrLinkerInvalidIndex})
+		if err != nil {
+			return nil, err
+		}
+		if len(l.abc.Instances) < len(l.classes) {
+			l.classes = l.classes[:len(l.abc.Instances)]
+		}
+	}
+	for i := range l.classes {
 		class, err := l.LinkClass(i)
 		if err != nil {
 			return nil, err
 		}
-		classes[i] = class
+		l.classes[i] = class
 	}
-	return classes, nil
+	return l.classes, nil
 }
 
 func (l *linker) LinkClass(index int) (c Class, err error) {
 	c.InstanceInfo = l.abc.Instances[index]
 	c.ClassInfo = l.abc.Classes[index]
+	l.methodRef(c.InstanceInfo.IInit)
+	l.methodRef(c.ClassInfo.CInit)
 
-	name := l.abc.ConstantPool.Multinames[c.InstanceInfo.Name]
-	ns := l.abc.ConstantPool.Namespaces[name.Namespace]
-	c.Name = l.abc.ConstantPool.Strings[name.Name]
-	c.Namespace = l.abc.ConstantPool.Strings[ns.Name]
-	c.SuperName = l.abc.ConstantPool.MultinameString(c.InstanceInfo.SuperName)
-	c.Interfaces = make([]string, len(c.InstanceInfo.Interfaces))
+	name := l.multiname(c.InstanceInfo.Name)
+	c.Name = name.Name
+	if q, ok := name.QName(); ok {
+		c.Namespace = q.Namespace
+	}
+	c.SuperName = l.multiname(c.InstanceInfo.SuperName)
+	c.Interfaces = make([]Multiname, len(c.InstanceInfo.Interfaces))
 	for i := range c.Interfaces {
-		c.Interfaces[i] = l.abc.ConstantPool.MultinameString(c.InstanceInfo.Interfaces[i])
+		c.Interfaces[i] = l.multiname(c.InstanceInfo.Interfaces[i])
+	}
+	if err = l.check("class", index, -1); err != nil {
+		return Class{}, err
 	}
-	instanceTraits, err := l.BuildTraits(c.InstanceInfo.Traits)
+	instanceTraits, err := l.BuildTraits(c.InstanceInfo.Traits, "class", index)
 	if err != nil {
-		c = Class{}
-		return
+		return Class{}, err
 	}
 	c.InstanceTraits = instanceTraits
-	classTraits, err := l.BuildTraits(c.ClassInfo.Traits)
+	classTraits, err := l.BuildTraits(c.ClassInfo.Traits, "class", index)
 	if err != nil {
-		c = Class{}
-		return
+		return Class{}, err
 	}
 	c.ClassTraits = classTraits
 	return
 }
 
-func (l *linker) BuildTraits(info []bytecode.TraitsInfo) (TraitsObject, error) {
+// BuildTraits links the traits of an entry, locating errors with the kind
+// and the index of the entry
+func (l *linker) BuildTraits(info []bytecode.TraitsInfo, entry string, index int) (TraitsObject, error) {
 	o := TraitsObject{}
 	mapping := map[uint8]*[]Trait{
 		bytecode.TraitsInfoSlot:     &o.Slots,
@@ -87,39 +227,410 @@ func (l *linker) BuildTraits(info []bytecode.TraitsInfo) (TraitsObject, error) {
 		t := info[i].GetType()
 		arrayPtr, ok := mapping[t]
 		if !ok {
-			return TraitsObject{}, ErrLinkerUnknownTrait
+			ref := fmt.Sprintf("kind %#x", t)
+			if err := l.fail(&LinkError{entry, index, i, ref, ErrLinkerUnknownTrait}); err != nil {
+				return TraitsObject{}, err
+			}
+			continue
 		}
-		name := l.abc.ConstantPool.MultinameString(info[i].Name)
-		var typename string
+		name := l.multiname(info[i].Name)
+		var ns Namespace
+		if q, ok := name.QName(); ok {
+			ns = q.Namespace
+		}
+		var typename Multiname
+		var value Value
+		hasValue := false
 		if t == bytecode.TraitsInfoSlot || t == bytecode.TraitsInfoConst {
-			typename = l.abc.ConstantPool.MultinameString(info[i].Typename)
+			typename = l.multiname(info[i].Typename)
+			if info[i].VIndex != 0 {
+				value, hasValue = l.value(info[i].VKind, info[i].VIndex), true
+			}
+		}
+		var metadatas []Metadata
+		for _, m := range info[i].Metadatas {
+			if int(m) >= len(l.metadatas) {
+				l.badRef(ErrLinkerInvalidIndex, "metadata %v", m)
+				continue
+			}
+			metadatas = append(metadatas, l.metadatas[m])
+		}
+		trait := Trait{info[i], name.Name, ns, typename, hasValue, value, nil, nil, nil, metadatas}
+		switch t {
+		case bytecode.TraitsInfoMethod, bytecode.TraitsInfoGetter, bytecode.TraitsInfoSetter:
+			trait.Method = l.methodRef(info[i].Method)
+		case bytecode.TraitsInfoFunction:
+			trait.Function = l.methodRef(info[i].Function)
+		case bytecode.TraitsInfoClass:
+			if int(info[i].ClassI) >= len(l.classes) {
+				l.badRef(ErrLinkerInvalidIndex, "class %v", info[i].ClassI)
+				break
+			}
+			trait.Class = &l.classes[info[i].ClassI]
+		}
+		if err := l.check(entry, index, i); err != nil {
+			return TraitsObject{}, err
 		}
-		*arrayPtr = append(*arrayPtr, Trait{info[i], name, typename})
+		*arrayPtr = append(*arrayPtr, trait)
 	}
 	return o, nil
 }
 
 func (l *linker) LinkMethods() ([]Method, error) {
 	methods := make([]Method, len(l.abc.Methods))
-	cpool := &l.abc.ConstantPool
 	for i := range methods {
 		info := l.abc.Methods[i]
-		name := cpool.MultinameString(info.Name)
-		returnType := cpool.MultinameString(info.ReturnType)
-		paramTypes := make([]string, len(info.ParamTypes))
+		name := l.str(info.Name)
+		returnType := l.multiname(info.ReturnType)
+		paramTypes := make([]Multiname, len(info.ParamTypes))
 		for iParam := range paramTypes {
-			paramTypes[iParam] = cpool.MultinameString(info.ParamTypes[iParam])
+			paramTypes[iParam] = l.multiname(info.ParamTypes[iParam])
+		}
+		var defaults []Value
+		for _, option := range info.OptionInfo.Options {
+			defaults = append(defaults, l.value(option.Kind, option.Value))
+		}
+		var paramNames []string
+		if info.Flags&bytecode.MethodHasParamNames != 0 {
+			paramNames = make([]string, len(info.ParamInfo.ParamNames))
+			for iParam, n := range info.ParamInfo.ParamNames {
+				paramNames[iParam] = l.str(n)
+			}
+		}
+		if err := l.check("method", i, -1); err != nil {
+			return nil, err
 		}
 		methods[i] = Method{
 			info, bytecode.MethodBodyInfo{}, false,
-			name, returnType, paramTypes,
+			name, returnType, paramTypes, defaults, paramNames,
+			MethodKindFunction, nil, nil, QName{}, nil,
 		}
 	}
 
 	for i := range l.abc.MethodBodies {
 		info := l.abc.MethodBodies[i]
+		if int(info.Method) >= len(methods) {
+			ref := fmt.Sprintf("method %v", info.Method)
+			if err := l.fail(&LinkError{"method body", i, -1, ref, ErrLinkerInvalidIndex}); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		methods[info.Method].HasBody = true
 		methods[info.Method].BodyInfo = info
 	}
 	return methods, nil
 }
+
+// value resolves a constant value of the given kind from the constant pool
+func (l *linker) value(kind uint8, index uint32) Value {
+	cpool := &l.abc.ConstantPool
+	v := Value{Kind: kind}
+	inRange := func(table string, n int) bool {
+		if int(index) >= n {
+			l.badRef(ErrLinkerInvalidIndex, "%v %v", table, index)
+			return false
+		}
+		return true
+	}
+	switch kind {
+	case bytecode.SlotKindInt:
+		if inRange("integer", len(cpool.Integers)) {
+			v.Value = cpool.Integers[index]
+		}
+	case bytecode.SlotKindUInt:
+		if inRange("uinteger", len(cpool.UIntegers)) {
+			v.Value = cpool.UIntegers[index]
+		}
+	case bytecode.SlotKindDouble:
+		if inRange("double", len(cpool.Doubles)) {
+			v.Value = cpool.Doubles[index]
+		}
+	case bytecode.SlotKindUtf8:
+		if inRange("string", len(cpool.Strings)) {
+			v.Value = cpool.Strings[index]
+		}
+	case bytecode.SlotKindTrue:
+		v.Value = true
+	case bytecode.SlotKindFalse:
+		v.Value = false
+	case bytecode.SlotKindNull, bytecode.SlotKindUndefined:
+	case bytecode.SlotKindNamespace, bytecode.SlotKindPackageNamespace, bytecode.SlotKindPackageInternalNs,
+		bytecode.SlotKindProtectedNamespace, bytecode.SlotKindExplicitNamespace,
+		bytecode.SlotKindStaticProtectedNs, bytecode.SlotKindPrivateNs:
+		if inRange("namespace", len(cpool.Namespaces)) {
+			v.Value = l.namespace(index)
+		}
+	default:
+		l.badRef(ErrLinkerUnknownValueKind, "value kind %#x", kind)
+		return Value{}
+	}
+	return v
+}
+
+// LinkMetadatas resolves the names, keys and values of the metadata_info
+func (l *linker) LinkMetadatas() ([]Metadata, error) {
+	metadatas := make([]Metadata, len(l.abc.Metadatas))
+	for i, info := range l.abc.Metadatas {
+		items := make([]MetadataItem, len(info.Items))
+		for j, item := range info.Items {
+			items[j] = MetadataItem{l.str(item.Key), l.str(item.Value)}
+		}
+		metadatas[i] = Metadata{l.str(info.Names), items}
+		if err := l.check("metadata", i, -1); err != nil {
+			return nil, err
+		}
+	}
+	return metadatas, nil
+}
+
+// maxTypenameDepth bounds the nesting of TypeName parameters, so that a
+// TypeName referring to itself does not loop forever
+const maxTypenameDepth = 16
+
+// str returns a string of the constant pool, or an empty string for an
+// invalid index. Like the other resolvers below, it records bad references
+// for the next check.
+func (l *linker) str(i uint32) string {
+	if i == 0 {
+		return ""
+	}
+	if int(i) >= len(l.abc.ConstantPool.Strings) {
+		l.badRef(ErrLinkerInvalidIndex, "string %v", i)
+		return ""
+	}
+	return l.abc.ConstantPool.Strings[i]
+}
+
+func (l *linker) namespace(i uint32) Namespace {
+	if i == 0 {
+		return Namespace{}
+	}
+	if int(i) >= len(l.abc.ConstantPool.Namespaces) {
+		l.badRef(ErrLinkerInvalidIndex, "namespace %v", i)
+		return Namespace{}
+	}
+	info := l.abc.ConstantPool.Namespaces[i]
+	return Namespace{info.Kind, l.str(info.Name)}
+}
+
+func (l *linker) nsSet(i uint32) []Namespace {
+	if i == 0 {
+		return nil
+	}
+	if int(i) >= len(l.abc.ConstantPool.NsSets) {
+		l.badRef(ErrLinkerInvalidIndex, "ns_set %v", i)
+		return nil
+	}
+	var namespaces []Namespace
+	for _, ns := range l.abc.ConstantPool.NsSets[i].Namespaces {
+		namespaces = append(namespaces, l.namespace(ns))
+	}
+	return namespaces
+}
+
+// multiname resolves a multiname of the constant pool. Index 0 and invalid
+// indexes give the any name.
+func (l *linker) multiname(i uint32) Multiname {
+	return l.multinameDepth(i, 0)
+}
+
+func (l *linker) multinameDepth(i uint32, depth int) Multiname {
+	if i == 0 {
+		return Multiname{}
+	}
+	if int(i) >= len(l.abc.ConstantPool.Multinames) {
+		l.badRef(ErrLinkerInvalidIndex, "multiname %v", i)
+		return Multiname{}
+	}
+	info := l.abc.ConstantPool.Multinames[i]
+	m := Multiname{Kind: info.Kind}
+	switch info.Kind {
+	case bytecode.MultinameKindQName, bytecode.MultinameKindQNameA:
+		m.Name = l.str(info.Name)
+		m.Namespaces = []Namespace{l.namespace(info.Namespace)}
+	case bytecode.MultinameKindRTQName, bytecode.MultinameKindRTQNameA:
+		m.Name = l.str(info.Name)
+	case bytecode.MultinameKindMultiname, bytecode.MultinameKindMultinameA:
+		m.Name = l.str(info.Name)
+		m.Namespaces = l.nsSet(info.NsSet)
+	case bytecode.MultinameKindMultinameL, bytecode.MultinameKindMultinameLA:
+		m.Namespaces = l.nsSet(info.NsSet)
+	case bytecode.MultinameKindTypename:
+		if depth >= maxTypenameDepth {
+			l.badRef(ErrLinkerRecursiveTypename, "multiname %v", i)
+			return Multiname{}
+		}
+		generic := l.multinameDepth(info.Name, depth+1)
+		m.Name, m.Namespaces = generic.Name, generic.Namespaces
+		m.Params = make([]Multiname, len(info.Params))
+		for j, p := range info.Params {
+			m.Params[j] = l.multinameDepth(p, depth+1)
+		}
+	}
+	return m
+}
+
+// ResolveNamespace resolves a namespace of the constant pool of a file the
+// way Link does. Index 0 and invalid indexes give the zero Namespace.
+func ResolveNamespace(abc *bytecode.AbcFile, i uint32) Namespace {
+	l := linker{abc: abc, lenient: true}
+	return l.namespace(i)
+}
+
+// ResolveNsSet resolves a namespace set of the constant pool of a file the
+// way Link does. Index 0 and invalid indexes give no namespaces.
+func ResolveNsSet(abc *bytecode.AbcFile, i uint32) []Namespace {
+	l := linker{abc: abc, lenient: true}
+	return l.nsSet(i)
+}
+
+// ResolveMultiname resolves a multiname of the constant pool of a file the
+// way Link does. Index 0 and invalid indexes give the any name.
+func ResolveMultiname(abc *bytecode.AbcFile, i uint32) Multiname {
+	l := linker{abc: abc, lenient: true}
+	return l.multiname(i)
+}
+
+// ResolveValue resolves a constant value of the given kind the way Link
+// does. Invalid indexes give a value of the kind without content, and
+// unknown kinds the zero Value.
+func ResolveValue(abc *bytecode.AbcFile, kind uint8, index uint32) Value {
+	l := linker{abc: abc, lenient: true}
+	return l.value(kind, index)
+}
+
+func (l *linker) LinkScripts() ([]Script, error) {
+	scripts := make([]Script, len(l.abc.Scripts))
+	for i, info := range l.abc.Scripts {
+		init := l.methodRef(info.Init)
+		if err := l.check("script", i, -1); err != nil {
+			return nil, err
+		}
+		traits, err := l.BuildTraits(info.Traits, "script", i)
+		if err != nil {
+			return nil, err
+		}
+		var defined []*Class
+		for _, t := range traits.Classes {
+			if t.Class != nil {
+				defined = append(defined, t.Class)
+			}
+		}
+		scripts[i] = Script{info, init, traits, defined}
+	}
+	return scripts, nil
+}
+
+// linkHierarchy resolves the super class and the interfaces of every class.
+// When several classes share a QName, the first one is used.
+func (l *linker) linkHierarchy() {
+	l.defined = map[QName]*Class{}
+	for i := range l.classes {
+		c := &l.classes[i]
+		if _, ok := l.defined[c.QName()]; !ok {
+			l.defined[c.QName()] = c
+		}
+	}
+	for i := range l.classes {
+		c := &l.classes[i]
+		if super := l.resolveClass(c.SuperName); super != nil {
+			c.Super = super
+			super.Children = append(super.Children, c)
+		}
+		for _, m := range c.Interfaces {
+			if iface := l.resolveClass(m); iface != nil {
+				c.Implements = append(c.Implements, iface)
+			}
+		}
+	}
+}
+
+// resolveClass finds the class a multiname refers to, looking in each of its
+// namespaces in turn. Unknown names resolve to an external class, qualified
+// by the namespace of a QName or by no namespace at all otherwise.
+// The any name and late bound names resolve to nil.
+func (l *linker) resolveClass(m Multiname) *Class {
+	if m.Kind == 0 || m.Name == "" {
+		return nil
+	}
+	for _, ns := range m.Namespaces {
+		if c, ok := l.defined[QName{ns, m.Name}]; ok {
+			return c
+		}
+	}
+	q, ok := m.QName()
+	if !ok {
+		q = QName{Name: m.Name}
+	}
+	if c, ok := l.defined[q]; ok {
+		return c
+	}
+	c := &Class{Name: q.Name, Namespace: q.Namespace, External: true}
+	l.defined[q] = c
+	l.externals = append(l.externals, c)
+	return c
+}
+
+// traitMethodKinds maps a method trait type to the kind of its method
+var traitMethodKinds = map[uint8]MethodKind{
+	bytecode.TraitsInfoMethod: MethodKindMethod,
+	bytecode.TraitsInfoGetter: MethodKindGetter,
+	bytecode.TraitsInfoSetter: MethodKindSetter,
+}
+
+func (l *linker) ownTraits(o TraitsObject, c *Class, s *Script) {
+	for _, t := range o.Methods {
+		if t.Method == nil {
+			continue
+		}
+		t.Method.Kind = traitMethodKinds[t.Source.GetType()]
+		t.Method.Class, t.Method.Script, t.Method.QName = c, s, t.QName()
+		t.Method.Metadatas = t.Metadatas
+	}
+	for _, t := range o.Functions {
+		if t.Function == nil {
+			continue
+		}
+		t.Function.Kind = MethodKindFunction
+		t.Function.Class, t.Function.Script, t.Function.QName = c, s, t.QName()
+		t.Function.Metadatas = t.Metadatas
+	}
+	for _, t := range o.Classes {
+		if t.Class != nil {
+			t.Class.Metadatas = t.Metadatas
+		}
+	}
+}
+
+func (l *linker) ownClass(c *Class, s *Script) {
+	if int(c.InstanceInfo.IInit) < len(l.methods) {
+		iinit := &l.methods[c.InstanceInfo.IInit]
+		iinit.Kind, iinit.Class, iinit.Script, iinit.QName = MethodKindConstructor, c, s, c.QName()
+	}
+	if int(c.ClassInfo.CInit) < len(l.methods) {
+		cinit := &l.methods[c.ClassInfo.CInit]
+		cinit.Kind, cinit.Class, cinit.Script, cinit.QName = MethodKindStaticInitializer, c, s, c.QName()
+	}
+	l.ownTraits(c.InstanceTraits, c, s)
+	l.ownTraits(c.ClassTraits, c, s)
+}
+
+// linkOwners links the methods back to the class and the script owning them.
+// It runs once every class is linked, so that the metadata of class traits
+// are attached to the classes they define.
+func (l *linker) linkOwners(scripts []Script) {
+	for i := range l.classes {
+		l.ownClass(&l.classes[i], nil)
+	}
+	for i := range scripts {
+		s := &scripts[i]
+		if s.Init != nil {
+			s.Init.Kind, s.Init.Script = MethodKindScriptInitializer, s
+		}
+		l.ownTraits(s.Traits, nil, s)
+		for _, c := range s.Classes {
+			l.ownClass(c, s)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package as3
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/kelvyne/as3/bytecode"
@@ -42,3 +44,286 @@ func TestLink(t *testing.T) {
 		t.Errorf("expected %v, got %v", len(a.Methods), len(l.Methods))
 	}
 }
+
+func TestLink_CompactCpool(t *testing.T) {
+	for _, name := range []string{"obf1", "obf2"} {
+		file := openFixture(t, name)
+		a, err := bytecode.Parse(bytecode.NewReader(file))
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if err = file.Close(); err != nil {
+			t.Errorf("file.close: %v", err)
+		}
+		before, err := Link(&a)
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		var names []string
+		for _, c := range before.Classes {
+			names = append(names, c.QName().String())
+		}
+
+		if _, err = a.CompactCpool(); err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		after, err := Link(&a)
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		for i, c := range after.Classes {
+			if got := c.QName().String(); got != names[i] {
+				t.Errorf("%v: expected %v, got %v", name, names[i], got)
+			}
+		}
+	}
+}
+
+func TestLink_Scripts(t *testing.T) {
+	for _, name := range []string{"obf1", "obf2"} {
+		file := openFixture(t, name)
+		a, err := bytecode.Parse(bytecode.NewReader(file))
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if err = file.Close(); err != nil {
+			t.Errorf("file.close: %v", err)
+		}
+		l, err := Link(&a)
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if len(l.Scripts) != len(a.Scripts) {
+			t.Errorf("%v: expected %v, got %v", name, len(a.Scripts), len(l.Scripts))
+		}
+		defined := map[*Class]int{}
+		for i, s := range l.Scripts {
+			if s.Init != &l.Methods[a.Scripts[i].Init] {
+				t.Errorf("%v: script %v: wrong init method", name, i)
+			}
+			for _, c := range s.Classes {
+				defined[c]++
+			}
+		}
+		for i := range l.Classes {
+			if defined[&l.Classes[i]] != 1 {
+				t.Errorf("%v: class %v defined %v times", name, l.Classes[i].Name, defined[&l.Classes[i]])
+			}
+		}
+	}
+}
+
+func TestLink_Owners(t *testing.T) {
+	var a bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&a.ConstantPool)
+	ns := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	a.Methods = make([]bytecode.MethodInfo, 6)
+	a.Instances = []bytecode.InstanceInfo{{
+		Name:   b.AddQName(ns, "Foo"),
+		IInit:  0,
+		Traits: []bytecode.TraitsInfo{{Name: b.AddQName(ns, "bar"), Kind: bytecode.TraitsInfoGetter, Method: 2}},
+	}}
+	a.Classes = []bytecode.ClassInfo{{CInit: 1}}
+	a.Scripts = []bytecode.ScriptInfo{{Init: 3, Traits: []bytecode.TraitsInfo{
+		{Name: b.AddQName(ns, "Foo"), Kind: bytecode.TraitsInfoClass, ClassI: 0},
+		{Name: b.AddQName(ns, "baz"), Kind: bytecode.TraitsInfoFunction, Function: 4},
+	}}}
+
+	l, err := Link(&a)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	class, script := &l.Classes[0], &l.Scripts[0]
+	if got := l.Classes[0].InstanceTraits.Methods[0].Method; got != &l.Methods[2] {
+		t.Errorf("expected getter trait to point to method 2, got %v", got)
+	}
+	if got := script.Traits.Classes[0].Class; got != class {
+		t.Errorf("expected class trait to point to class 0, got %v", got)
+	}
+	if got := script.Traits.Functions[0].Function; got != &l.Methods[4] {
+		t.Errorf("expected function trait to point to method 4, got %v", got)
+	}
+
+	tests := []struct {
+		method int
+		kind   MethodKind
+		class  *Class
+		script *Script
+	}{
+		{0, MethodKindConstructor, class, script},
+		{1, MethodKindStaticInitializer, class, script},
+		{2, MethodKindGetter, class, script},
+		{3, MethodKindScriptInitializer, nil, script},
+		{4, MethodKindFunction, nil, script},
+		{5, MethodKindFunction, nil, nil},
+	}
+	for _, tt := range tests {
+		m := l.Methods[tt.method]
+		if m.Kind != tt.kind || m.Class != tt.class || m.Script != tt.script {
+			t.Errorf("method %v: expected %v %p %p, got %v %p %p", tt.method, tt.kind, tt.class, tt.script, m.Kind, m.Class, m.Script)
+		}
+	}
+
+	a.Scripts[0].Traits[1].Function = 6
+	if _, err = Link(&a); !errors.Is(err, ErrLinkerInvalidIndex) {
+		t.Errorf("expected %v, got %v", ErrLinkerInvalidIndex, err)
+	}
+}
+
+func TestLink_Errors(t *testing.T) {
+	build := func(edit func(a *bytecode.AbcFile)) *bytecode.AbcFile {
+		var a bytecode.AbcFile
+		b := bytecode.NewCpoolBuilder(&a.ConstantPool)
+		ns := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+		a.Methods = make([]bytecode.MethodInfo, 3)
+		a.Instances = []bytecode.InstanceInfo{{
+			Name:   b.AddQName(ns, "Foo"),
+			Traits: []bytecode.TraitsInfo{{Name: b.AddQName(ns, "bar"), Kind: bytecode.TraitsInfoMethod, Method: 1}},
+		}}
+		a.Classes = []bytecode.ClassInfo{{CInit: 1}}
+		a.Scripts = []bytecode.ScriptInfo{{Init: 2, Traits: []bytecode.TraitsInfo{
+			{Name: b.AddQName(ns, "Foo"), Kind: bytecode.TraitsInfoClass, ClassI: 0},
+		}}}
+		edit(&a)
+		return &a
+	}
+	tests := []struct {
+		name string
+		edit func(a *bytecode.AbcFile)
+		want string
+		err  error
+	}{
+		{
+			"class name",
+			func(a *bytecode.AbcFile) { a.Instances[0].Name = 99 },
+			"class 0: multiname 99: linker invalid index",
+			ErrLinkerInvalidIndex,
+		},
+		{
+			"class trait name",
+			func(a *bytecode.AbcFile) { a.Instances[0].Traits[0].Name = 99 },
+			"class 0: trait 0: multiname 99: linker invalid index",
+			ErrLinkerInvalidIndex,
+		},
+		{
+			"unknown trait",
+			func(a *bytecode.AbcFile) { a.Scripts[0].Traits[0].Kind = 0x0f },
+			"script 0: trait 0: kind 0xf: linker unknown trait",
+			ErrLinkerUnknownTrait,
+		},
+		{
+			"method name",
+			func(a *bytecode.AbcFile) { a.Methods[1].Name = 99 },
+			"method 1: string 99: linker invalid index",
+			ErrLinkerInvalidIndex,
+		},
+		{
+			"method return type",
+			func(a *bytecode.AbcFile) { a.Methods[2].ReturnType = 99 },
+			"method 2: multiname 99: linker invalid index",
+			ErrLinkerInvalidIndex,
+		},
+		{
+			"method body",
+			func(a *bytecode.AbcFile) { a.MethodBodies = []bytecode.MethodBodyInfo{{Method: 9}} },
+			"method body 0: method 9: linker invalid index",
+			ErrLinkerInvalidIndex,
+		},
+		{
+			"script init",
+			func(a *bytecode.AbcFile) { a.Scripts[0].Init = 9 },
+			"script 0: method 9: linker invalid index",
+			ErrLinkerInvalidIndex,
+		},
+		{
+			"namespace",
+			func(a *bytecode.AbcFile) { a.ConstantPool.Multinames[1].Namespace = 42 },
+			"class 0: namespace 42: linker invalid index",
+			ErrLinkerInvalidIndex,
+		},
+		{
+			"recursive typename",
+			func(a *bytecode.AbcFile) {
+				a.ConstantPool.Multinames = append(a.ConstantPool.Multinames, bytecode.MultinameInfo{
+					Kind: bytecode.MultinameKindTypename, Name: 1, Params: []uint32{uint32(len(a.ConstantPool.Multinames))},
+				})
+				a.Methods[0].ReturnType = uint32(len(a.ConstantPool.Multinames) - 1)
+			},
+			"method 0: multiname 3: linker recursive typename",
+			ErrLinkerRecursiveTypename,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Link(build(tt.edit))
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestLink_EmptyStringPool(t *testing.T) {
+	a := bytecode.AbcFile{
+		Methods: []bytecode.MethodInfo{{Name: 0}},
+		Scripts: []bytecode.ScriptInfo{{Init: 0}},
+	}
+	abc, err := Link(&a)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if abc.Methods[0].Name != "" {
+		t.Errorf("expected no name, got %v", abc.Methods[0].Name)
+	}
+}
+
+func TestLinkLenient(t *testing.T) {
+	var a bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&a.ConstantPool)
+	ns := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	a.Methods = []bytecode.MethodInfo{{}, {}, {ReturnType: 99}}
+	a.Instances = []bytecode.InstanceInfo{{
+		Name:  b.AddQName(ns, "Foo"),
+		IInit: 9,
+		Traits: []bytecode.TraitsInfo{
+			{Name: b.AddQName(ns, "bad"), Kind: bytecode.TraitsInfoMethod, Method: 9},
+			{Name: b.AddQName(ns, "unknown"), Kind: 0x0f},
+			{Name: b.AddQName(ns, "good"), Kind: bytecode.TraitsInfoMethod, Method: 2},
+		},
+	}}
+	a.Classes = []bytecode.ClassInfo{{CInit: 1}}
+	a.Scripts = []bytecode.ScriptInfo{{Init: 9, Traits: []bytecode.TraitsInfo{
+		{Name: b.AddQName(ns, "Foo"), Kind: bytecode.TraitsInfoClass, ClassI: 0},
+		{Name: b.AddQName(ns, "Bar"), Kind: bytecode.TraitsInfoClass, ClassI: 5},
+	}}}
+
+	l, errs := LinkLenient(&a)
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"method 2: multiname 99: linker invalid index",
+		"class 0: method 9: linker invalid index",
+		"class 0: trait 0: method 9: linker invalid index",
+		"class 0: trait 1: kind 0xf: linker unknown trait",
+		"script 0: method 9: linker invalid index",
+		"script 0: trait 1: class 5: linker invalid index",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	methods := l.Classes[0].InstanceTraits.Methods
+	if len(methods) != 2 || methods[0].Method != nil || methods[1].Method != &l.Methods[2] {
+		t.Errorf("expected the bad and the good methods, got %v", methods)
+	}
+	if l.Scripts[0].Init != nil || len(l.Scripts[0].Classes) != 1 {
+		t.Errorf("expected a script without init defining Foo, got %v", l.Scripts[0])
+	}
+	if l.Methods[2].QName.Name != "good" {
+		t.Errorf("expected method 2 to be owned by good, got %v", l.Methods[2].QName)
+	}
+}
@@ -0,0 +1,59 @@
+package as3
+
+// Value returns the value of the first item with the given key. An empty
+// key looks for a keyless item.
+func (m Metadata) Value(key string) (string, bool) {
+	for _, item := range m.Items {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return "", false
+}
+
+// findMetadata returns the first metadata of the given name
+func findMetadata(metadatas []Metadata, name string) (Metadata, bool) {
+	for _, m := range metadatas {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Metadata{}, false
+}
+
+// FindMetadata returns the first metadata of the class with the given name,
+// such as "Event" for [Event(name="change")]
+func (c Class) FindMetadata(name string) (Metadata, bool) {
+	return findMetadata(c.Metadatas, name)
+}
+
+// FindMetadata returns the first metadata of the trait with the given name
+func (t Trait) FindMetadata(name string) (Metadata, bool) {
+	return findMetadata(t.Metadatas, name)
+}
+
+// FindMetadata returns the first metadata of the method with the given name
+func (m Method) FindMetadata(name string) (Metadata, bool) {
+	return findMetadata(m.Metadatas, name)
+}
+
+// TraitsWithMetadata returns the traits of the scripts and the classes
+// carrying a metadata with the given name, such as every [Embed] slot
+func (f AbcFile) TraitsWithMetadata(name string) []Trait {
+	var traits []Trait
+	collect := func(o TraitsObject) {
+		for _, t := range o.traits() {
+			if _, ok := t.FindMetadata(name); ok {
+				traits = append(traits, t)
+			}
+		}
+	}
+	for _, s := range f.Scripts {
+		collect(s.Traits)
+	}
+	for _, c := range f.Classes {
+		collect(c.InstanceTraits)
+		collect(c.ClassTraits)
+	}
+	return traits
+}
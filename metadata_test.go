@@ -0,0 +1,106 @@
+package as3
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+func TestLink_Metadatas(t *testing.T) {
+	var a bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&a.ConstantPool)
+	ns := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	a.Metadatas = []bytecode.MetadataInfo{
+		{Names: b.AddString("Event"), Items: []bytecode.ItemInfo{
+			{Key: b.AddString("name"), Value: b.AddString("change")},
+			{Key: b.AddString("type"), Value: b.AddString("flash.events.Event")},
+		}},
+		{Names: b.AddString("Embed"), Items: []bytecode.ItemInfo{{Key: 0, Value: b.AddString("image.png")}}},
+		{Names: b.AddString("Transient")},
+	}
+	withMetadata := uint8(bytecode.TraitsInfoAttributeMetadata)
+	a.Methods = make([]bytecode.MethodInfo, 4)
+	a.Instances = []bytecode.InstanceInfo{{
+		Name: b.AddQName(ns, "Foo"),
+		Traits: []bytecode.TraitsInfo{
+			{Name: b.AddQName(ns, "image"), Kind: bytecode.TraitsInfoSlot | withMetadata, Metadatas: []uint32{1}},
+			{Name: b.AddQName(ns, "save"), Kind: bytecode.TraitsInfoMethod | withMetadata, Method: 3, Metadatas: []uint32{2}},
+		},
+	}}
+	a.Classes = []bytecode.ClassInfo{{CInit: 1}}
+	a.Scripts = []bytecode.ScriptInfo{{Init: 2, Traits: []bytecode.TraitsInfo{
+		{Name: b.AddQName(ns, "Foo"), Kind: bytecode.TraitsInfoClass | withMetadata, ClassI: 0, Metadatas: []uint32{0, 2}},
+	}}}
+
+	l, err := Link(&a)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	class := l.Classes[0]
+	event, ok := class.FindMetadata("Event")
+	if !ok {
+		t.Fatalf("expected class Foo to have [Event]")
+	}
+	if v, ok := event.Value("type"); !ok || v != "flash.events.Event" {
+		t.Errorf("expected flash.events.Event, got %v", v)
+	}
+	if _, ok := event.Value(""); ok {
+		t.Errorf("expected no keyless item")
+	}
+	if _, ok := class.FindMetadata("Transient"); !ok {
+		t.Errorf("expected class Foo to have [Transient]")
+	}
+
+	embed, ok := class.InstanceTraits.Slots[0].FindMetadata("Embed")
+	if v, _ := embed.Value(""); !ok || v != "image.png" {
+		t.Errorf("expected image.png, got %v", v)
+	}
+	if _, ok := l.Methods[3].FindMetadata("Transient"); !ok {
+		t.Errorf("expected method save to have [Transient]")
+	}
+
+	var names []string
+	for _, t := range l.TraitsWithMetadata("Transient") {
+		names = append(names, t.Name)
+	}
+	if want := []string{"Foo", "save"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+
+	a.Scripts[0].Traits[0].Metadatas = []uint32{3}
+	if _, err := Link(&a); !errors.Is(err, ErrLinkerInvalidIndex) {
+		t.Errorf("expected %v, got %v", ErrLinkerInvalidIndex, err)
+	}
+}
+
+func TestLink_ClassMetadatas(t *testing.T) {
+	var a bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&a.ConstantPool)
+	ns := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	a.Metadatas = []bytecode.MetadataInfo{{Names: b.AddString("Transient")}}
+	withMetadata := uint8(bytecode.TraitsInfoAttributeMetadata)
+	a.Methods = make([]bytecode.MethodInfo, 5)
+	a.Instances = []bytecode.InstanceInfo{{Name: b.AddQName(ns, "Foo")}, {Name: b.AddQName(ns, "Bar"), IInit: 2}}
+	a.Classes = []bytecode.ClassInfo{
+		{CInit: 1, Traits: []bytecode.TraitsInfo{
+			{Name: b.AddQName(ns, "Bar"), Kind: bytecode.TraitsInfoClass | withMetadata, ClassI: 1, Metadatas: []uint32{0}},
+		}},
+		{CInit: 3},
+	}
+	a.Scripts = []bytecode.ScriptInfo{{Init: 4, Traits: []bytecode.TraitsInfo{
+		{Name: b.AddQName(ns, "Foo"), Kind: bytecode.TraitsInfoClass, ClassI: 0},
+	}}}
+
+	l, err := Link(&a)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if _, ok := l.Classes[1].FindMetadata("Transient"); !ok {
+		t.Errorf("expected class Bar to have [Transient]")
+	}
+	if _, ok := l.Classes[0].FindMetadata("Transient"); ok {
+		t.Errorf("expected class Foo not to have [Transient]")
+	}
+}
@@ -0,0 +1,78 @@
+package as3
+
+import (
+	"strings"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+// String returns the URI of the namespace
+func (n Namespace) String() string {
+	return n.URI
+}
+
+// String returns the name prefixed by its namespace URI and "::", or the
+// name alone when the URI is empty
+func (q QName) String() string {
+	if q.Namespace.URI == "" {
+		return q.Name
+	}
+	return q.Namespace.URI + "::" + q.Name
+}
+
+// QName returns the multiname as a QName when it names a single namespace:
+// a QName or a Multiname whose namespace set holds one namespace
+func (m Multiname) QName() (QName, bool) {
+	switch m.Kind {
+	case bytecode.MultinameKindQName, bytecode.MultinameKindQNameA,
+		bytecode.MultinameKindMultiname, bytecode.MultinameKindMultinameA:
+		if len(m.Namespaces) == 1 {
+			return QName{m.Namespaces[0], m.Name}, true
+		}
+	}
+	return QName{}, false
+}
+
+// String formats the multiname like actionscript does: * for the any name,
+// ns::name for a QName, {ns1, ns2}::name for a namespace set, *::name for
+// runtime qualified names and __AS3__.vec::Vector.<int> for a TypeName
+func (m Multiname) String() string {
+	if q, ok := m.QName(); ok {
+		return q.String()
+	}
+	name := m.Name
+	switch m.Kind {
+	case 0:
+		return "*"
+	case bytecode.MultinameKindRTQNameL, bytecode.MultinameKindRTQNameLA,
+		bytecode.MultinameKindMultinameL, bytecode.MultinameKindMultinameLA:
+		name = "[]"
+	case bytecode.MultinameKindTypename:
+		params := make([]string, len(m.Params))
+		for i, p := range m.Params {
+			params[i] = p.String()
+		}
+		generic := Multiname{bytecode.MultinameKindQName, m.Name, m.Namespaces, nil}
+		return generic.String() + ".<" + strings.Join(params, ", ") + ">"
+	}
+	switch m.Kind {
+	case bytecode.MultinameKindRTQName, bytecode.MultinameKindRTQNameA,
+		bytecode.MultinameKindRTQNameL, bytecode.MultinameKindRTQNameLA:
+		return "*::" + name
+	}
+	uris := make([]string, len(m.Namespaces))
+	for i, ns := range m.Namespaces {
+		uris[i] = ns.URI
+	}
+	return "{" + strings.Join(uris, ", ") + "}::" + name
+}
+
+// QName returns the qualified name of the class
+func (c Class) QName() QName {
+	return QName{c.Namespace, c.Name}
+}
+
+// QName returns the qualified name of the trait
+func (t Trait) QName() QName {
+	return QName{t.Namespace, t.Name}
+}
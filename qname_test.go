@@ -0,0 +1,135 @@
+package as3
+
+import (
+	"testing"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+func TestMultiname_String(t *testing.T) {
+	pkg := Namespace{bytecode.NamespaceKindPackageNamespace, "flash.display"}
+	global := Namespace{bytecode.NamespaceKindPackageNamespace, ""}
+	vector := Namespace{bytecode.NamespaceKindPackageNamespace, "__AS3__.vec"}
+	integer := Multiname{bytecode.MultinameKindQName, "int", []Namespace{global}, nil}
+	tests := []struct {
+		name string
+		m    Multiname
+		want string
+	}{
+		{"any", Multiname{}, "*"},
+		{"qname", Multiname{bytecode.MultinameKindQName, "Sprite", []Namespace{pkg}, nil}, "flash.display::Sprite"},
+		{"global", integer, "int"},
+		{"rtqname", Multiname{bytecode.MultinameKindRTQName, "foo", nil, nil}, "*::foo"},
+		{"rtqnamel", Multiname{bytecode.MultinameKindRTQNameL, "", nil, nil}, "*::[]"},
+		{"single ns set", Multiname{bytecode.MultinameKindMultiname, "Sprite", []Namespace{pkg}, nil}, "flash.display::Sprite"},
+		{"ns set", Multiname{bytecode.MultinameKindMultiname, "x", []Namespace{global, pkg}, nil}, "{, flash.display}::x"},
+		{"multinamel", Multiname{bytecode.MultinameKindMultinameL, "", []Namespace{pkg}, nil}, "{flash.display}::[]"},
+		{
+			"typename",
+			Multiname{bytecode.MultinameKindTypename, "Vector", []Namespace{vector}, []Multiname{integer}},
+			"__AS3__.vec::Vector.<int>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.String(); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLink_QNames(t *testing.T) {
+	var abc bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&abc.ConstantPool)
+	global := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	private := b.AddNamespace(bytecode.NamespaceKindPrivateNs, b.AddString(""))
+	protected := b.AddNamespace(bytecode.NamespaceKindProtectedNamespace, b.AddString("Foo"))
+	vector := b.AddQName(b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString("__AS3__.vec")), "Vector")
+	abc.Methods = []bytecode.MethodInfo{
+		{}, {}, {}, {},
+		{ReturnType: b.AddTypename(vector, b.AddQName(global, "int")), Name: b.AddString("debug")},
+	}
+	abc.Instances = []bytecode.InstanceInfo{{
+		Name:  b.AddQName(global, "Foo"),
+		IInit: 0,
+		Traits: []bytecode.TraitsInfo{
+			{Name: b.AddQName(private, "x"), Kind: bytecode.TraitsInfoMethod, Method: 3},
+			{Name: b.AddQName(protected, "x"), Kind: bytecode.TraitsInfoMethod, Method: 4},
+			{
+				Name:     b.AddMultiname(bytecode.MultinameInfo{Kind: bytecode.MultinameKindRTQName, Name: b.AddString("y")}),
+				Kind:     bytecode.TraitsInfoSlot,
+				Typename: b.AddMultiname(bytecode.MultinameInfo{Kind: bytecode.MultinameKindMultiname, Name: b.AddString("z"), NsSet: b.AddNsSet(global, private)}),
+			},
+		},
+	}}
+	abc.Classes = []bytecode.ClassInfo{{CInit: 1}}
+	abc.Scripts = []bytecode.ScriptInfo{{Init: 2, Traits: []bytecode.TraitsInfo{
+		{Name: b.AddQName(global, "Foo"), Kind: bytecode.TraitsInfoClass, ClassI: 0},
+	}}}
+	l, err := Link(&abc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	traits := l.Classes[0].InstanceTraits
+	privateX := QName{Namespace{bytecode.NamespaceKindPrivateNs, ""}, "x"}
+	protectedX := QName{Namespace{bytecode.NamespaceKindProtectedNamespace, "Foo"}, "x"}
+	if got := traits.Methods[0].QName(); got != privateX {
+		t.Errorf("expected %v, got %v", privateX, got)
+	}
+	if got := l.Methods[4].QName; got != protectedX {
+		t.Errorf("expected %v, got %v", protectedX, got)
+	}
+	if got := l.Methods[4].Name; got != "debug" {
+		t.Errorf("expected debug, got %v", got)
+	}
+	if got := l.Methods[4].ReturnType.String(); got != "__AS3__.vec::Vector.<int>" {
+		t.Errorf("expected __AS3__.vec::Vector.<int>, got %v", got)
+	}
+	slot := traits.Slots[0]
+	if slot.Name != "y" || slot.Namespace != (Namespace{}) {
+		t.Errorf("expected y without namespace, got %v", slot.QName())
+	}
+	if got := slot.Typename.String(); got != "{, }::z" {
+		t.Errorf("expected {, }::z, got %v", got)
+	}
+
+	foo := QName{Namespace{bytecode.NamespaceKindPackageNamespace, ""}, "Foo"}
+	if c, ok := l.GetClassByQName(foo); !ok || c.Name != "Foo" {
+		t.Errorf("expected Foo, got %v, %v", c.Name, ok)
+	}
+	if _, ok := l.GetClassByQName(QName{Namespace{bytecode.NamespaceKindPrivateNs, ""}, "Foo"}); ok {
+		t.Errorf("expected a private Foo not to be found")
+	}
+	if _, _, ok := l.GetDefinitionByQName(foo); !ok {
+		t.Errorf("expected definition %v to be found", foo)
+	}
+	if got := l.Methods[0].QName; got != foo {
+		t.Errorf("expected constructor %v, got %v", foo, got)
+	}
+}
+
+func TestResolveMultiname(t *testing.T) {
+	var abc bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&abc.ConstantPool)
+	global := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	vector := b.AddQName(b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString("__AS3__.vec")), "Vector")
+	typename := b.AddTypename(vector, b.AddQName(global, "int"))
+	tests := []struct {
+		index uint32
+		want  string
+	}{
+		{typename, "__AS3__.vec::Vector.<int>"},
+		{0, "*"},
+		{typename + 1, "*"},
+	}
+	for _, tt := range tests {
+		if got := ResolveMultiname(&abc, tt.index).String(); got != tt.want {
+			t.Errorf("%v: expected %v, got %v", tt.index, tt.want, got)
+		}
+	}
+	if got := ResolveValue(&abc, bytecode.SlotKindUtf8, b.AddString("Vector")).String(); got != `"Vector"` {
+		t.Errorf("expected \"Vector\", got %v", got)
+	}
+}
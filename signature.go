@@ -0,0 +1,91 @@
+package as3
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+// Params returns the parameters of the method with their types, names and
+// default values. Parameters without a name are named param1, param2, ...
+func (m Method) Params() []Param {
+	params := make([]Param, len(m.ParamTypes))
+	firstDefault := len(params) - len(m.Defaults)
+	for i := range params {
+		params[i].Type = m.ParamTypes[i]
+		if i < len(m.ParamNames) && m.ParamNames[i] != "" {
+			params[i].Name = m.ParamNames[i]
+		} else {
+			params[i].Name = "param" + strconv.Itoa(i+1)
+		}
+		if i >= firstDefault {
+			params[i].HasDefault, params[i].Default = true, m.Defaults[i-firstDefault]
+		}
+	}
+	return params
+}
+
+// IsVariadic reports whether the method takes a rest parameter
+func (m Method) IsVariadic() bool {
+	return m.Info.Flags&bytecode.MethodNeedRest != 0
+}
+
+// NeedsArguments reports whether the method uses the arguments object
+func (m Method) NeedsArguments() bool {
+	return m.Info.Flags&bytecode.MethodNeedArguments != 0
+}
+
+// TypeString formats the multiname as a type annotation: the local name of a
+// qualified name, * for the any type and Vector.<T> for a TypeName
+func (m Multiname) TypeString() string {
+	switch m.Kind {
+	case 0:
+		return "*"
+	case bytecode.MultinameKindTypename:
+		params := make([]string, len(m.Params))
+		for i, p := range m.Params {
+			params[i] = p.TypeString()
+		}
+		return m.Name + ".<" + strings.Join(params, ", ") + ">"
+	}
+	if m.Name == "" {
+		return m.String()
+	}
+	return m.Name
+}
+
+// Signature formats the method as an actionscript declaration, such as
+// function foo(a:int, b:String = "x", ...rest):void. Constructors have no
+// return type and getters and setters are prefixed by get and set.
+func (m Method) Signature() string {
+	name := m.QName.Name
+	if name == "" {
+		name = m.Name
+	}
+	params := m.Params()
+	args := make([]string, 0, len(params)+1)
+	for _, p := range params {
+		arg := p.Name + ":" + p.Type.TypeString()
+		if p.HasDefault {
+			arg += " = " + p.Default.String()
+		}
+		args = append(args, arg)
+	}
+	if m.IsVariadic() {
+		args = append(args, "...rest")
+	}
+
+	s := "function "
+	switch m.Kind {
+	case MethodKindGetter:
+		s += "get "
+	case MethodKindSetter:
+		s += "set "
+	}
+	s += name + "(" + strings.Join(args, ", ") + ")"
+	if m.Kind != MethodKindConstructor {
+		s += ":" + m.ReturnType.TypeString()
+	}
+	return s
+}
@@ -0,0 +1,69 @@
+package as3
+
+import (
+	"testing"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+func TestMethod_Signature(t *testing.T) {
+	var a bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&a.ConstantPool)
+	global := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	vec := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString("__AS3__.vec"))
+	integer, str, void := b.AddQName(global, "int"), b.AddQName(global, "String"), b.AddQName(global, "void")
+	vector := b.AddTypename(b.AddQName(vec, "Vector"), integer)
+	a.Methods = []bytecode.MethodInfo{
+		{}, {}, {},
+		{
+			ParamTypes: []uint32{integer, str},
+			ReturnType: void,
+			Flags:      bytecode.MethodHasOptional | bytecode.MethodHasParamNames | bytecode.MethodNeedRest,
+			OptionInfo: bytecode.OptionInfo{Options: []bytecode.OptionDetail{{Value: b.AddString("x"), Kind: bytecode.SlotKindUtf8}}},
+			ParamInfo:  bytecode.ParamInfo{ParamNames: []uint32{b.AddString("a"), b.AddString("b")}},
+		},
+		{ReturnType: vector},
+		{ParamTypes: []uint32{0}, ReturnType: void},
+		{Name: b.AddString("closure")},
+	}
+	a.Instances = []bytecode.InstanceInfo{{
+		Name:  b.AddQName(global, "Foo"),
+		IInit: 0,
+		Traits: []bytecode.TraitsInfo{
+			{Name: b.AddQName(global, "foo"), Kind: bytecode.TraitsInfoMethod, Method: 3},
+			{Name: b.AddQName(global, "items"), Kind: bytecode.TraitsInfoGetter, Method: 4},
+			{Name: b.AddQName(global, "items"), Kind: bytecode.TraitsInfoSetter, Method: 5},
+		},
+	}}
+	a.Classes = []bytecode.ClassInfo{{CInit: 1}}
+	a.Scripts = []bytecode.ScriptInfo{{Init: 2}}
+
+	l, err := Link(&a)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	tests := []struct {
+		method int
+		want   string
+	}{
+		{0, "function Foo()"},
+		{3, `function foo(a:int, b:String = "x", ...rest):void`},
+		{4, "function get items():Vector.<int>"},
+		{5, "function set items(param1:*):void"},
+		{6, "function closure():*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := l.Methods[tt.method].Signature(); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+	params := l.Methods[3].Params()
+	if params[0].HasDefault || !params[1].HasDefault || params[1].Name != "b" {
+		t.Errorf("expected b to be the only optional parameter, got %v", params)
+	}
+	if !l.Methods[3].IsVariadic() || l.Methods[4].IsVariadic() {
+		t.Errorf("expected only foo to be variadic")
+	}
+}
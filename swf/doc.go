@@ -0,0 +1,8 @@
+// Package swf provides utilities to read the tags of a Shockwave Flash file
+// and extract the ActionScript 3 bytecode embedded in DoABC tags
+// (see https://www.adobe.com/content/dam/acom/en/devnet/pdf/swf-file-format-spec.pdf)
+//
+// LZMA compressed files are read and written with the
+// github.com/ulikunitz/xz/lzma package, the only dependency of the
+// repository outside of the standard library.
+package swf
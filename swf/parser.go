@@ -0,0 +1,202 @@
+package swf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/kelvyne/as3/bytecode"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+// ErrInvalidSignature means that the file does not start with FWS, CWS or ZWS
+var ErrInvalidSignature = errors.New("invalid swf signature")
+
+// ErrMalformedTag means that a tag header announces more bytes than
+// the file contains
+var ErrMalformedTag = errors.New("malformed tag")
+
+// ErrInvalidFileLength means that the header announces a file shorter than
+// the header itself
+var ErrInvalidFileLength = errors.New("invalid swf file length")
+
+// ErrNotDoABC means that a tag other than DoABC or DoABC2 was used as
+// a DoABC tag
+var ErrNotDoABC = errors.New("tag is not a DoABC tag")
+
+type parser struct {
+	r io.Reader
+}
+
+// Parse parses a SWF file and decompresses it if needed
+func Parse(r io.Reader) (File, error) {
+	p := parser{r}
+	return p.Parse()
+}
+
+func (p *parser) Parse() (File, error) {
+	raw := &bytes.Buffer{}
+	p.r = io.TeeReader(p.r, raw)
+	var h Header
+	body, err := p.ParseSignature(&h)
+	if err != nil {
+		return File{}, err
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return File{}, err
+	}
+	b := bytes.NewReader(data)
+	if err = p.ParseHeader(b, &h); err != nil {
+		return File{}, err
+	}
+	tags, err := p.ParseTags(b)
+	if err != nil {
+		return File{}, err
+	}
+	return File{h, tags, source{h, raw.Bytes(), data}}, nil
+}
+
+// ParseSignature reads the uncompressed part of the header and returns a
+// reader over the decompressed rest of the file
+func (p *parser) ParseSignature(h *Header) (io.Reader, error) {
+	var sig [8]byte
+	if _, err := io.ReadFull(p.r, sig[:]); err != nil {
+		return nil, err
+	}
+	if sig[1] != 'W' || sig[2] != 'S' {
+		return nil, ErrInvalidSignature
+	}
+	h.Compression = sig[0]
+	h.Version = sig[3]
+	h.FileLength = binary.LittleEndian.Uint32(sig[4:])
+
+	switch h.Compression {
+	case CompressionNone:
+		return p.r, nil
+	case CompressionZlib:
+		return zlib.NewReader(p.r)
+	case CompressionLZMA:
+		return p.parseLZMA(h.FileLength)
+	}
+	return nil, ErrInvalidSignature
+}
+
+// parseLZMA rebuilds the classic 13 bytes LZMA header from the SWF one:
+// the SWF stores the compressed length before the 5 bytes of properties
+// and omits the uncompressed size, which is derived from the file length.
+func (p *parser) parseLZMA(fileLength uint32) (io.Reader, error) {
+	if fileLength < 8 {
+		return nil, ErrInvalidFileLength
+	}
+	var h [9]byte
+	if _, err := io.ReadFull(p.r, h[:]); err != nil {
+		return nil, err
+	}
+	var header [13]byte
+	copy(header[:5], h[4:])
+	binary.LittleEndian.PutUint64(header[5:], uint64(fileLength-8))
+	return lzma.NewReader(io.MultiReader(bytes.NewReader(header[:]), p.r))
+}
+
+func (p *parser) ParseHeader(r *bytes.Reader, h *Header) error {
+	first, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	nBits := uint(first >> 3)
+	rect := make([]byte, (5+4*nBits+7)/8)
+	rect[0] = first
+	if _, err = io.ReadFull(r, rect[1:]); err != nil {
+		return err
+	}
+	h.FrameSize = rect
+	if err = binary.Read(r, binary.LittleEndian, &h.FrameRate); err != nil {
+		return err
+	}
+	return binary.Read(r, binary.LittleEndian, &h.FrameCount)
+}
+
+func (p *parser) ParseTag(r *bytes.Reader) (Tag, error) {
+	var codeAndLength uint16
+	if err := binary.Read(r, binary.LittleEndian, &codeAndLength); err != nil {
+		return Tag{}, err
+	}
+	t := Tag{Code: codeAndLength >> 6}
+	length := uint32(codeAndLength & 0x3f)
+	if length == 0x3f {
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return Tag{}, err
+		}
+		t.LongHeader = true
+	}
+	if int64(length) > int64(r.Len()) {
+		return Tag{}, ErrMalformedTag
+	}
+	t.Data = make([]byte, length)
+	if _, err := io.ReadFull(r, t.Data); err != nil {
+		return Tag{}, err
+	}
+	return t, nil
+}
+
+func (p *parser) ParseTags(r *bytes.Reader) ([]Tag, error) {
+	var tags []Tag
+	for r.Len() > 0 {
+		tag, err := p.ParseTag(r)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// ParseDoABC parses the content of a DoABC or DoABC2 tag
+func ParseDoABC(t Tag) (DoABC, error) {
+	var d DoABC
+	data := t.Data
+	switch t.Code {
+	default:
+		return DoABC{}, ErrNotDoABC
+	case TagDoABC:
+	case TagDoABC2:
+		if len(data) < 4 {
+			return DoABC{}, ErrMalformedTag
+		}
+		d.Flags = binary.LittleEndian.Uint32(data)
+		data = data[4:]
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			return DoABC{}, ErrMalformedTag
+		}
+		d.Name = string(data[:end])
+		data = data[end+1:]
+	}
+	abc, err := bytecode.Parse(bytecode.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return DoABC{}, err
+	}
+	d.Abc = abc
+	return d, nil
+}
+
+// DoABCs parses every DoABC and DoABC2 tag of the file, in file order
+func (f File) DoABCs() ([]DoABC, error) {
+	var abcs []DoABC
+	for i, t := range f.Tags {
+		if t.Code != TagDoABC && t.Code != TagDoABC2 {
+			continue
+		}
+		d, err := ParseDoABC(t)
+		if err != nil {
+			return nil, err
+		}
+		d.Tag = i
+		abcs = append(abcs, d)
+	}
+	return abcs, nil
+}
@@ -0,0 +1,151 @@
+package swf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	b, err := ioutil.ReadFile(fmt.Sprintf("../bytecode/fixtures/%v.abc", name))
+	if err != nil {
+		t.Fatalf("readFixture: %v", err)
+	}
+	return b
+}
+
+func appendTag(b []byte, code uint16, long bool, data []byte) []byte {
+	var header [6]byte
+	if !long && len(data) < 0x3f {
+		binary.LittleEndian.PutUint16(header[:], code<<6|uint16(len(data)))
+		return append(append(b, header[:2]...), data...)
+	}
+	binary.LittleEndian.PutUint16(header[:], code<<6|0x3f)
+	binary.LittleEndian.PutUint32(header[2:], uint32(len(data)))
+	return append(append(b, header[:]...), data...)
+}
+
+// buildSWF wraps the obf1 and obf2 fixtures in a minimal SWF file, the first
+// one in a DoABC2 tag and the second one in a DoABC tag
+func buildSWF(t *testing.T, compression byte) []byte {
+	// RECT with nBits = 15, 0 0 11000 8000 twips
+	body := []byte{0x78, 0x00, 0x05, 0x5f, 0x00, 0x00, 0x0f, 0xa0, 0x00}
+	body = append(body, 0x00, 0x18, 0x01, 0x00)
+	body = appendTag(body, 69, false, []byte{0x08, 0x00, 0x00, 0x00})
+	doABC2 := []byte{DoABCLazyInitialize, 0, 0, 0}
+	doABC2 = append(doABC2, "frame1"...)
+	doABC2 = append(doABC2, 0)
+	doABC2 = append(doABC2, readFixture(t, "obf1")...)
+	body = appendTag(body, TagDoABC2, true, doABC2)
+	body = appendTag(body, TagDoABC, true, readFixture(t, "obf2"))
+	body = appendTag(body, 1, false, nil)
+	body = appendTag(body, 0, false, nil)
+
+	header := []byte{compression, 'W', 'S', 10, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(body)+8))
+	out := bytes.NewBuffer(header)
+	switch compression {
+	case CompressionNone:
+		out.Write(body)
+	case CompressionZlib:
+		w := zlib.NewWriter(out)
+		w.Write(body)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	case CompressionLZMA:
+		var compressed bytes.Buffer
+		w, err := lzma.WriterConfig{SizeInHeader: true, Size: int64(len(body))}.NewWriter(&compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(body)
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		c := compressed.Bytes()
+		var length [4]byte
+		binary.LittleEndian.PutUint32(length[:], uint32(len(c)-13))
+		out.Write(length[:])
+		out.Write(c[:5])
+		out.Write(c[13:])
+	}
+	return out.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression byte
+	}{
+		{"FWS", CompressionNone},
+		{"CWS", CompressionZlib},
+		{"ZWS", CompressionLZMA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(bytes.NewReader(buildSWF(t, tt.compression)))
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if f.Header.Compression != tt.compression {
+				t.Errorf("expected %c, got %c", tt.compression, f.Header.Compression)
+			}
+			if f.Header.Version != 10 {
+				t.Errorf("expected 10, got %v", f.Header.Version)
+			}
+			if f.Header.FrameRate != 0x1800 || f.Header.FrameCount != 1 {
+				t.Errorf("expected 0x1800/1, got %#x/%v", f.Header.FrameRate, f.Header.FrameCount)
+			}
+			if len(f.Tags) != 5 {
+				t.Fatalf("expected 5, got %v", len(f.Tags))
+			}
+
+			abcs, err := f.DoABCs()
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if len(abcs) != 2 {
+				t.Fatalf("expected 2, got %v", len(abcs))
+			}
+			if abcs[0].Tag != 1 || abcs[0].Flags != DoABCLazyInitialize || abcs[0].Name != "frame1" {
+				t.Errorf("unexpected DoABC2 %v %v %q", abcs[0].Tag, abcs[0].Flags, abcs[0].Name)
+			}
+			if len(abcs[0].Abc.Methods) != 36 {
+				t.Errorf("expected 36, got %v", len(abcs[0].Abc.Methods))
+			}
+			if abcs[1].Tag != 2 || abcs[1].Flags != 0 || abcs[1].Name != "" {
+				t.Errorf("unexpected DoABC %v %v %q", abcs[1].Tag, abcs[1].Flags, abcs[1].Name)
+			}
+			if len(abcs[1].Abc.Methods) != 33 {
+				t.Errorf("expected 33, got %v", len(abcs[1].Abc.Methods))
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"empty", nil, nil},
+		{"signature", []byte("GIF89a\x00\x00"), ErrInvalidSignature},
+		{"truncated tag", append(buildSWF(t, CompressionNone)[:21], 0xbf, 0x14, 0xff, 0xff), nil},
+		{"lzma file length", append([]byte("ZWS\x0a\x04\x00\x00\x00"), make([]byte, 9)...), ErrInvalidFileLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(bytes.NewReader(tt.data))
+			if err == nil || (tt.err != nil && err != tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
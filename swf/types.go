@@ -0,0 +1,66 @@
+package swf
+
+import "github.com/kelvyne/as3/bytecode"
+
+// These constants are the possible compressions of a SWF file, stored as the
+// first byte of its signature
+const (
+	CompressionNone = 'F'
+	CompressionZlib = 'C'
+	CompressionLZMA = 'Z'
+)
+
+// These constants are the codes of the tags holding ActionScript 3 bytecode
+const (
+	TagDoABC  = 72
+	TagDoABC2 = 82
+)
+
+// These constants are possible flags of the flags field of a DoABC2 tag
+const (
+	DoABCLazyInitialize = 1
+)
+
+// File represents a decompressed SWF file
+type File struct {
+	Header Header
+	Tags   []Tag
+
+	source source
+}
+
+// source keeps the file as it was parsed so that an untouched file can be
+// written back byte for byte, whatever compressor produced it
+type source struct {
+	header Header
+	raw    []byte
+	body   []byte
+}
+
+// Header represents the header of a SWF file.
+// FrameSize is kept as its raw bit-packed RECT record.
+type Header struct {
+	Compression byte
+	Version     uint8
+	FileLength  uint32
+	FrameSize   []byte
+	FrameRate   uint16
+	FrameCount  uint16
+}
+
+// Tag represents a single SWF tag.
+// LongHeader records whether the tag length was stored on 32 bits.
+type Tag struct {
+	Code       uint16
+	LongHeader bool
+	Data       []byte
+}
+
+// DoABC represents the content of a DoABC or DoABC2 tag.
+// Tag is the index of the tag in the File Tags.
+type DoABC struct {
+	Tag   int
+	Flags uint32
+	Name  string
+	Abc   bytecode.AbcFile
+}
@@ -0,0 +1,138 @@
+package swf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/kelvyne/as3/bytecode"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+// ErrUnknownCompression means that the header compression is not one of
+// CompressionNone, CompressionZlib or CompressionLZMA
+var ErrUnknownCompression = errors.New("unknown swf compression")
+
+// SetDoABC serializes d.Abc and stores it in the tag d.Tag, keeping
+// the flags and name of DoABC2 tags
+func (f *File) SetDoABC(d DoABC) error {
+	if d.Tag < 0 || d.Tag >= len(f.Tags) {
+		return ErrNotDoABC
+	}
+	t := &f.Tags[d.Tag]
+	buf := &bytes.Buffer{}
+	switch t.Code {
+	default:
+		return ErrNotDoABC
+	case TagDoABC:
+	case TagDoABC2:
+		var flags [4]byte
+		binary.LittleEndian.PutUint32(flags[:], d.Flags)
+		buf.Write(flags[:])
+		buf.WriteString(d.Name)
+		buf.WriteByte(0)
+	}
+	if err := bytecode.Extract(buf, d.Abc); err != nil {
+		return err
+	}
+	t.Data = buf.Bytes()
+	return nil
+}
+
+func writeTag(w *bytes.Buffer, t Tag) {
+	var header [6]byte
+	length := len(t.Data)
+	if !t.LongHeader && length < 0x3f {
+		binary.LittleEndian.PutUint16(header[:], t.Code<<6|uint16(length))
+		w.Write(header[:2])
+	} else {
+		binary.LittleEndian.PutUint16(header[:], t.Code<<6|0x3f)
+		binary.LittleEndian.PutUint32(header[2:], uint32(length))
+		w.Write(header[:])
+	}
+	w.Write(t.Data)
+}
+
+func (f *File) body() []byte {
+	b := &bytes.Buffer{}
+	b.Write(f.Header.FrameSize)
+	binary.Write(b, binary.LittleEndian, f.Header.FrameRate)
+	binary.Write(b, binary.LittleEndian, f.Header.FrameCount)
+	for _, t := range f.Tags {
+		writeTag(b, t)
+	}
+	return b.Bytes()
+}
+
+// Write serializes a SWF file using the compression of its header.
+// The tag lengths and the header file length are recomputed.
+// When neither the header nor the tags were modified since Parse, the
+// original bytes are written back untouched.
+func Write(w io.Writer, f File) error {
+	body := f.body()
+	if f.untouched(body) {
+		_, err := w.Write(f.source.raw)
+		return err
+	}
+
+	header := [8]byte{f.Header.Compression, 'W', 'S', f.Header.Version}
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(body)+len(header)))
+	out := bytes.NewBuffer(header[:])
+	switch f.Header.Compression {
+	default:
+		return ErrUnknownCompression
+	case CompressionNone:
+		out.Write(body)
+	case CompressionZlib:
+		zw := zlib.NewWriter(out)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	case CompressionLZMA:
+		if err := writeLZMA(out, body); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// untouched reports whether the header and the body of a file are the ones
+// it was parsed with. The frame fields of the header are part of the body.
+func (f *File) untouched(body []byte) bool {
+	h := f.source.header
+	return f.source.raw != nil &&
+		f.Header.Compression == h.Compression &&
+		f.Header.Version == h.Version &&
+		f.Header.FileLength == h.FileLength &&
+		bytes.Equal(body, f.source.body)
+}
+
+// writeLZMA compresses body and converts the classic 13 bytes LZMA header
+// to the SWF one (see parseLZMA)
+func writeLZMA(out *bytes.Buffer, body []byte) error {
+	compressed := &bytes.Buffer{}
+	config := lzma.WriterConfig{SizeInHeader: true, Size: int64(len(body))}
+	lw, err := config.NewWriter(compressed)
+	if err != nil {
+		return err
+	}
+	if _, err = lw.Write(body); err != nil {
+		return err
+	}
+	if err = lw.Close(); err != nil {
+		return err
+	}
+	c := compressed.Bytes()
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(c)-13))
+	out.Write(length[:])
+	out.Write(c[:5])
+	out.Write(c[13:])
+	return nil
+}
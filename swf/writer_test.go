@@ -0,0 +1,124 @@
+package swf
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWrite_Untouched(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression byte
+	}{
+		{"FWS", CompressionNone},
+		{"CWS", CompressionZlib},
+		{"ZWS", CompressionLZMA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := buildSWF(t, tt.compression)
+			f, err := Parse(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			buf := &bytes.Buffer{}
+			if err = Write(buf, f); err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), b) {
+				t.Errorf("buffer are not equal (required len: %v, got %v)", len(b), buf.Len())
+			}
+		})
+	}
+}
+
+func TestWrite_Header(t *testing.T) {
+	f, err := Parse(bytes.NewReader(buildSWF(t, CompressionZlib)))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	f.Header.Version++
+	buf := &bytes.Buffer{}
+	if err = Write(buf, f); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	got, err := Parse(buf)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if got.Header.Version != f.Header.Version {
+		t.Errorf("expected %v, got %v", f.Header.Version, got.Header.Version)
+	}
+}
+
+func TestWrite_Compression(t *testing.T) {
+	original, err := Parse(bytes.NewReader(buildSWF(t, CompressionZlib)))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	for _, compression := range []byte{CompressionNone, CompressionZlib, CompressionLZMA} {
+		f := original
+		f.Header.Compression = compression
+		buf := &bytes.Buffer{}
+		if err = Write(buf, f); err != nil {
+			t.Fatalf("%c: expected nil, got %v", compression, err)
+		}
+		if buf.Bytes()[0] != compression {
+			t.Errorf("expected %c, got %c", compression, buf.Bytes()[0])
+		}
+		got, err := Parse(buf)
+		if err != nil {
+			t.Fatalf("%c: expected nil, got %v", compression, err)
+		}
+		if !reflect.DeepEqual(got.Tags, original.Tags) {
+			t.Errorf("%c: tags are not equal", compression)
+		}
+	}
+
+	f := original
+	f.Header.Compression = 'X'
+	if err = Write(&bytes.Buffer{}, f); err != ErrUnknownCompression {
+		t.Errorf("expected %v, got %v", ErrUnknownCompression, err)
+	}
+}
+
+func TestFile_SetDoABC(t *testing.T) {
+	f, err := Parse(bytes.NewReader(buildSWF(t, CompressionNone)))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	abcs, err := f.DoABCs()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	for i := range abcs {
+		abcs[i].Abc.ConstantPool.Strings = append(abcs[i].Abc.ConstantPool.Strings, "a patched string")
+		if err = f.SetDoABC(abcs[i]); err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	}
+	if err = f.SetDoABC(DoABC{Tag: 0}); err != ErrNotDoABC {
+		t.Errorf("expected %v, got %v", ErrNotDoABC, err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = Write(buf, f); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	got, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if int(got.Header.FileLength) != buf.Len() {
+		t.Errorf("expected %v, got %v", buf.Len(), got.Header.FileLength)
+	}
+	gotAbcs, err := got.DoABCs()
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if !reflect.DeepEqual(gotAbcs, abcs) {
+		t.Errorf("DoABC tags are not equal")
+	}
+}
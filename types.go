@@ -4,23 +4,62 @@ import (
 	"github.com/kelvyne/as3/bytecode"
 )
 
-// AbcFile represents a linked AbcFile
+// AbcFile represents a linked AbcFile.
+// Externals holds the classes referred to as a super class or an interface
+// but not defined in the file, such as flash.display.Sprite.
 type AbcFile struct {
-	Source  *bytecode.AbcFile
-	Classes []Class
-	Methods []Method
+	Source    *bytecode.AbcFile
+	Classes   []Class
+	Methods   []Method
+	Scripts   []Script
+	Externals []*Class
 }
 
-// Class represents an actionscript Class
+// Namespace represents a namespace: its kind, one of the
+// bytecode.NamespaceKind constants, and its URI
+type Namespace struct {
+	Kind uint8
+	URI  string
+}
+
+// QName represents a name qualified by a single namespace
+type QName struct {
+	Namespace Namespace
+	Name      string
+}
+
+// Multiname represents a reference to a name. Kind is one of the
+// bytecode.MultinameKind constants, or zero for the any name (*).
+// Namespaces holds the namespace of a QName or the namespace set of a
+// Multiname, and is empty for runtime qualified names. Params holds the
+// parameters of a TypeName, whose Name and Namespaces are those of the
+// generic type.
+type Multiname struct {
+	Kind       uint8
+	Name       string
+	Namespaces []Namespace
+	Params     []Multiname
+}
+
+// Class represents an actionscript Class.
+// Metadatas are those of the trait defining the class.
+// Super is the super class, nil for a class without one, and Children the
+// classes extending it. Implements holds the resolved Interfaces.
+// An External class is not defined in the file: only its name is known.
 type Class struct {
 	InstanceInfo   bytecode.InstanceInfo
 	ClassInfo      bytecode.ClassInfo
 	Name           string
-	Namespace      string
-	SuperName      string
-	Interfaces     []string
+	Namespace      Namespace
+	SuperName      Multiname
+	Interfaces     []Multiname
 	InstanceTraits TraitsObject
 	ClassTraits    TraitsObject
+	Super          *Class
+	Children       []*Class
+	Implements     []*Class
+	External       bool
+	Metadatas      []Metadata
 }
 
 // TraitsObject represents an object that has traits
@@ -31,19 +70,98 @@ type TraitsObject struct {
 	Methods   []Trait
 }
 
-// Trait represents a single trait from a TraitsObject
+// Trait represents a single trait from a TraitsObject.
+// Default is the value of a slot or a constant trait, when HasDefault is set.
+// Method is set for method, getter and setter traits, Class for class traits
+// and Function for function traits.
 type Trait struct {
-	Source   bytecode.TraitsInfo
-	Name     string
-	Typename string
+	Source     bytecode.TraitsInfo
+	Name       string
+	Namespace  Namespace
+	Typename   Multiname
+	HasDefault bool
+	Default    Value
+	Method     *Method
+	Class      *Class
+	Function   *Method
+	Metadatas  []Metadata
+}
+
+// Value represents a constant value: the default value of a slot, a constant
+// or an optional parameter. Kind is one of the bytecode.SlotKind constants
+// and Value holds an int32, a uint32, a float64, a string, a bool or a
+// Namespace, or nil for null and undefined.
+type Value struct {
+	Kind  uint8
+	Value interface{}
+}
+
+// Metadata represents a linked metadata_info, such as
+// [Event(name="change", type="flash.events.Event")]
+type Metadata struct {
+	Name  string
+	Items []MetadataItem
+}
+
+// MetadataItem is a key/value pair of a metadata. Keyless items, as in
+// [Embed("image.png")], have an empty Key.
+type MetadataItem struct {
+	Key   string
+	Value string
 }
 
-// Method represents a linked method
+// MethodKind is the role of a method, given by what refers to it
+type MethodKind uint8
+
+// These are possible kinds of methods
+const (
+	MethodKindFunction          = MethodKind(iota) // function trait or closure
+	MethodKindMethod                               // method trait
+	MethodKindGetter                               // getter trait
+	MethodKindSetter                               // setter trait
+	MethodKindConstructor                          // instance initializer
+	MethodKindStaticInitializer                    // class initializer
+	MethodKindScriptInitializer                    // script initializer
+)
+
+// Method represents a linked method.
+// Name is the debug name of the method_info while QName is the name of the
+// trait or the class the method belongs to, if any.
+// Class is the class owning the method, if any, and Script the script
+// owning the method or its class, if any.
+// Metadatas are those of the trait referring to the method.
+// Defaults holds the default values of the last len(Defaults) parameters and
+// ParamNames the names of the parameters, when the method has them.
 type Method struct {
 	Info       bytecode.MethodInfo
 	BodyInfo   bytecode.MethodBodyInfo
 	HasBody    bool
 	Name       string
-	ReturnType string
-	ParamTypes []string
+	ReturnType Multiname
+	ParamTypes []Multiname
+	Defaults   []Value
+	ParamNames []string
+	Kind       MethodKind
+	Class      *Class
+	Script     *Script
+	QName      QName
+	Metadatas  []Metadata
+}
+
+// Param represents a parameter of a linked method.
+// Default is its default value when HasDefault is set.
+type Param struct {
+	Name       string
+	Type       Multiname
+	HasDefault bool
+	Default    Value
+}
+
+// Script represents a linked script_info: the package-level definitions of
+// the file and the method initializing them
+type Script struct {
+	Info    bytecode.ScriptInfo
+	Init    *Method
+	Traits  TraitsObject
+	Classes []*Class
 }
@@ -0,0 +1,37 @@
+package as3
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+// String formats the value as an actionscript literal: numbers, quoted
+// strings, true, false, null, undefined, and namespaces as their quoted URI
+func (v Value) String() string {
+	switch v.Kind {
+	case bytecode.SlotKindNull:
+		return "null"
+	case bytecode.SlotKindUndefined:
+		return "undefined"
+	}
+	switch value := v.Value.(type) {
+	case string:
+		return strconv.Quote(value)
+	case Namespace:
+		return strconv.Quote(value.URI)
+	case float64:
+		switch {
+		case math.IsNaN(value):
+			return "NaN"
+		case math.IsInf(value, 1):
+			return "Infinity"
+		case math.IsInf(value, -1):
+			return "-Infinity"
+		}
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	return fmt.Sprint(v.Value)
+}
@@ -0,0 +1,95 @@
+package as3
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+func TestValue_String(t *testing.T) {
+	tests := []struct {
+		v    Value
+		want string
+	}{
+		{Value{bytecode.SlotKindInt, int32(-3)}, "-3"},
+		{Value{bytecode.SlotKindUInt, uint32(7)}, "7"},
+		{Value{bytecode.SlotKindDouble, 0.5}, "0.5"},
+		{Value{bytecode.SlotKindDouble, math.Inf(-1)}, "-Infinity"},
+		{Value{bytecode.SlotKindUtf8, "a\"b"}, `"a\"b"`},
+		{Value{bytecode.SlotKindTrue, true}, "true"},
+		{Value{bytecode.SlotKindNull, nil}, "null"},
+		{Value{bytecode.SlotKindUndefined, nil}, "undefined"},
+		{Value{bytecode.SlotKindNamespace, Namespace{bytecode.NamespaceKindNamespace, "http://x"}}, `"http://x"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.v.String(); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLink_Values(t *testing.T) {
+	var a bytecode.AbcFile
+	b := bytecode.NewCpoolBuilder(&a.ConstantPool)
+	ns := b.AddNamespace(bytecode.NamespaceKindPackageNamespace, b.AddString(""))
+	custom := b.AddNamespace(bytecode.NamespaceKindNamespace, b.AddString("http://x"))
+	a.Methods = []bytecode.MethodInfo{{}, {}, {
+		ParamTypes: []uint32{0, 0, 0},
+		Flags:      bytecode.MethodHasOptional,
+		OptionInfo: bytecode.OptionInfo{Options: []bytecode.OptionDetail{
+			{Value: b.AddUInt(42), Kind: bytecode.SlotKindUInt},
+			{Kind: bytecode.SlotKindNull},
+		}},
+	}}
+	slot := func(name string, kind uint8, index uint32) bytecode.TraitsInfo {
+		return bytecode.TraitsInfo{Name: b.AddQName(ns, name), Kind: bytecode.TraitsInfoConst, VKind: kind, VIndex: index}
+	}
+	a.Scripts = []bytecode.ScriptInfo{{Traits: []bytecode.TraitsInfo{
+		slot("PROTOCOL_ID", bytecode.SlotKindInt, b.AddInt(1337)),
+		slot("RATIO", bytecode.SlotKindDouble, b.AddDouble(0.25)),
+		slot("NAME", bytecode.SlotKindUtf8, b.AddString("hello")),
+		slot("ENABLED", bytecode.SlotKindTrue, bytecode.SlotKindTrue),
+		slot("NS", bytecode.SlotKindNamespace, custom),
+		slot("NONE", 0, 0),
+		{Name: b.AddQName(ns, "f"), Kind: bytecode.TraitsInfoFunction, Function: 2},
+	}}}
+
+	l, err := Link(&a)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	want := []Value{
+		{bytecode.SlotKindInt, int32(1337)},
+		{bytecode.SlotKindDouble, 0.25},
+		{bytecode.SlotKindUtf8, "hello"},
+		{bytecode.SlotKindTrue, true},
+		{bytecode.SlotKindNamespace, Namespace{bytecode.NamespaceKindNamespace, "http://x"}},
+	}
+	slots := l.Scripts[0].Traits.Slots
+	for i, w := range want {
+		if !slots[i].HasDefault || !reflect.DeepEqual(slots[i].Default, w) {
+			t.Errorf("%v: expected %v, got %v", slots[i].Name, w, slots[i].Default)
+		}
+	}
+	if slots[5].HasDefault {
+		t.Errorf("expected NONE to have no default value, got %v", slots[5].Default)
+	}
+	wantDefaults := []Value{{bytecode.SlotKindUInt, uint32(42)}, {bytecode.SlotKindNull, nil}}
+	if got := l.Methods[2].Defaults; !reflect.DeepEqual(got, wantDefaults) {
+		t.Errorf("expected %v, got %v", wantDefaults, got)
+	}
+
+	a.Scripts[0].Traits[0].VIndex = 100
+	if _, err = Link(&a); !errors.Is(err, ErrLinkerInvalidIndex) {
+		t.Errorf("expected %v, got %v", ErrLinkerInvalidIndex, err)
+	}
+	a.Scripts[0].Traits[0].VKind = 0x42
+	if _, err = Link(&a); !errors.Is(err, ErrLinkerUnknownValueKind) {
+		t.Errorf("expected %v, got %v", ErrLinkerUnknownValueKind, err)
+	}
+}
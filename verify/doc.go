@@ -0,0 +1,4 @@
+// Package verify checks method bodies the way the AVM2 verifier does: it
+// interprets the instructions of a body along its control flow to track the
+// depth of the operand and scope stacks and the registers in use
+package verify
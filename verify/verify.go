@@ -0,0 +1,121 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/kelvyne/as3/bytecode"
+	"github.com/kelvyne/as3/cfg"
+)
+
+// These errors are the problems found while interpreting the code, shared
+// with bytecode.MethodBodyInfo.AnalyzeDepths
+var (
+	ErrStackUnderflow = bytecode.ErrStackUnderflow
+	ErrScopeUnderflow = bytecode.ErrScopeUnderflow
+	ErrStackMismatch  = bytecode.ErrStackMismatch
+	ErrScopeMismatch  = bytecode.ErrScopeMismatch
+	ErrFallOffCode    = bytecode.ErrFallOffCode
+)
+
+// ErrStackOverflow means that the operand stack grows past MaxStack
+var ErrStackOverflow = errors.New("stack overflow")
+
+// ErrScopeOverflow means that the scope stack grows past
+// MaxScopeLength - InitScopeLength
+var ErrScopeOverflow = errors.New("scope overflow")
+
+// ErrRegisterOutOfRange means that an instruction uses a register past
+// LocalCount
+var ErrRegisterOutOfRange = errors.New("register out of range")
+
+// Problem is a problem found at an instruction of a method body
+type Problem struct {
+	Instr  int
+	Offset uint32
+	Err    error
+}
+
+func (p Problem) Error() string {
+	return fmt.Sprintf("instruction %v at %#x: %v", p.Instr, p.Offset, p.Err)
+}
+
+// Analysis is the result of the abstract interpretation of a method body.
+// Stack and Scope hold the depth of the operand and scope stacks before
+// each instruction, or -1 for unreachable instructions. MaxRegister is the
+// highest register used, or -1 when the code uses none.
+type Analysis struct {
+	Graph       *cfg.Graph
+	Stack       []int
+	Scope       []int
+	MaxStack    int
+	MaxScope    int
+	MaxRegister int
+	Problems    []Problem
+}
+
+// Analyze interprets a disassembled method body with
+// bytecode.MethodBodyInfo.AnalyzeDepths. The operand and scope stacks are
+// empty when the body starts, and an exception handler starts with the
+// exception alone on the operand stack and an empty scope stack.
+// Errors are returned when the control-flow graph cannot be built; problems
+// of the code itself are listed in the Analysis.
+func Analyze(c *bytecode.CpoolInfo, body *bytecode.MethodBodyInfo) (*Analysis, error) {
+	g, err := cfg.Build(body)
+	if err != nil {
+		return nil, err
+	}
+	d, err := body.AnalyzeDepths(c)
+	if err != nil {
+		return nil, err
+	}
+	a := &Analysis{g, d.Stack, d.Scope, d.MaxStack, d.MaxScope, d.MaxRegister, nil}
+	for _, p := range d.Problems {
+		a.Problems = append(a.Problems, Problem{p.Instr, g.Offsets[p.Instr], p.Err})
+	}
+	return a, nil
+}
+
+// Verify analyzes a disassembled method body and checks it against its
+// declared limits: MaxStack, MaxScopeLength - InitScopeLength and
+// LocalCount. Stack overflows are reported once, at the first instruction
+// exceeding the limit.
+func Verify(c *bytecode.CpoolInfo, body *bytecode.MethodBodyInfo) ([]Problem, error) {
+	a, err := Analyze(c, body)
+	if err != nil {
+		return nil, err
+	}
+	problems := a.Problems
+	report := func(i int, err error) {
+		problems = append(problems, Problem{i, a.Graph.Offsets[i], err})
+	}
+	maxScope := int(body.MaxScopeLength) - int(body.InitScopeLength)
+	stackReported, scopeReported := false, false
+	for i, instr := range body.Instructions {
+		if a.Stack[i] < 0 {
+			continue
+		}
+		// the depths after the instruction, underflows being already reported
+		pop, push, _ := instr.StackEffect(c)
+		stack := a.Stack[i] + push - pop
+		pop, push = instr.ScopeEffect()
+		scope := a.Scope[i] + push - pop
+		if stack > int(body.MaxStack) && !stackReported {
+			stackReported = true
+			report(i, ErrStackOverflow)
+		}
+		if scope > maxScope && !scopeReported {
+			scopeReported = true
+			report(i, ErrScopeOverflow)
+		}
+		for _, r := range instr.Registers() {
+			if r >= body.LocalCount {
+				report(i, ErrRegisterOutOfRange)
+				break
+			}
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Instr < problems[j].Instr })
+	return problems, nil
+}
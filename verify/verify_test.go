@@ -0,0 +1,155 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/kelvyne/as3/bytecode"
+)
+
+func openFixture(t *testing.T, name string) *os.File {
+	file, err := os.Open(fmt.Sprintf("../bytecode/fixtures/%v.abc", name))
+	if err != nil {
+		t.Fatalf("openFixture: %v", err)
+	}
+	return file
+}
+
+func TestVerify_Fixtures(t *testing.T) {
+	// obf2 holds junk bodies, never executed, that underflow the stack
+	flagged := map[string]int{"obf1": 0, "obf2": 7}
+	for _, name := range []string{"obf1", "obf2"} {
+		file := openFixture(t, name)
+		a, err := bytecode.Parse(bytecode.NewReader(file))
+		if err != nil {
+			t.Fatalf("%v: expected nil, got %v", name, err)
+		}
+		if err = file.Close(); err != nil {
+			t.Errorf("file.close: %v", err)
+		}
+		count := 0
+		for i := range a.MethodBodies {
+			body := &a.MethodBodies[i]
+			if err = body.Disassemble(); err != nil {
+				t.Fatalf("%v: method_body %v: %v", name, i, err)
+			}
+			problems, err := Verify(&a.ConstantPool, body)
+			if err != nil {
+				t.Fatalf("%v: method_body %v: %v", name, i, err)
+			}
+			if len(problems) != 0 {
+				count++
+			}
+		}
+		if count != flagged[name] {
+			t.Errorf("%v: expected %v bodies with problems, got %v", name, flagged[name], count)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	cpool := &bytecode.CpoolInfo{
+		Strings:    []string{"", "foo"},
+		Namespaces: []bytecode.NamespaceInfo{{}, {Kind: bytecode.NamespaceKindPackageNamespace, Name: 1}},
+		Multinames: []bytecode.MultinameInfo{
+			{},
+			{Kind: bytecode.MultinameKindQName, Namespace: 1, Name: 1},
+			{Kind: bytecode.MultinameKindRTQNameL},
+		},
+	}
+	tests := []struct {
+		name       string
+		code       []byte
+		exceptions []bytecode.ExceptionInfo
+		maxStack   uint32
+		maxScope   uint32
+		locals     uint32
+		want       []Problem
+	}{
+		{
+			"valid",
+			// getlocal_0; pushscope; pushnull; pushnull; callproperty foo 1; returnvalue
+			[]byte{0xd0, 0x30, 0x20, 0x20, 0x46, 0x01, 0x01, 0x48},
+			nil, 2, 1, 1, nil,
+		},
+		{
+			"runtime multiname",
+			// pushnull; pushnull; pushnull; getproperty [ns::name]; returnvalue
+			[]byte{0x20, 0x20, 0x20, 0x66, 0x02, 0x48},
+			nil, 3, 0, 0, nil,
+		},
+		{
+			"underflow",
+			// pushnull; callproperty foo 1; popscope; returnvoid
+			[]byte{0x20, 0x46, 0x01, 0x01, 0x1d, 0x47},
+			nil, 1, 0, 0,
+			[]Problem{{1, 1, ErrStackUnderflow}, {2, 4, ErrScopeUnderflow}},
+		},
+		{
+			"merge",
+			// pushtrue; iffalse L; pushnull; L: returnvoid
+			[]byte{0x26, 0x12, 0x01, 0x00, 0x00, 0x20, 0x47},
+			nil, 1, 0, 0,
+			[]Problem{{3, 6, ErrStackMismatch}},
+		},
+		{
+			"limits",
+			// getlocal_0; pushscope; pushnull; pushnull; setlocal 3; pop; returnvoid
+			[]byte{0xd0, 0x30, 0x20, 0x20, 0x63, 0x03, 0x29, 0x47},
+			nil, 1, 0, 1,
+			[]Problem{{1, 1, ErrScopeOverflow}, {3, 3, ErrStackOverflow}, {4, 4, ErrRegisterOutOfRange}},
+		},
+		{
+			"exception handler",
+			// pushnull; pop; returnvoid; handler: pop; returnvoid
+			[]byte{0x20, 0x29, 0x47, 0x29, 0x47},
+			[]bytecode.ExceptionInfo{{From: 0, To: 2, Target: 3}},
+			1, 0, 0, nil,
+		},
+		{
+			"fall off",
+			// pushnull; pop
+			[]byte{0x20, 0x29},
+			nil, 1, 0, 0,
+			[]Problem{{1, 1, ErrFallOffCode}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &bytecode.MethodBodyInfo{
+				Code: tt.code, Exceptions: tt.exceptions,
+				MaxStack: tt.maxStack, MaxScopeLength: tt.maxScope, LocalCount: tt.locals,
+			}
+			if err := body.Disassemble(); err != nil {
+				t.Fatalf("Disassemble: %v", err)
+			}
+			got, err := Verify(cpool, body)
+			if err != nil {
+				t.Fatalf("expected nil, got %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	// getlocal_0; pushscope; pushnull; dup; pop; pop; popscope; returnvoid
+	body := &bytecode.MethodBodyInfo{Code: []byte{0xd0, 0x30, 0x20, 0x2a, 0x29, 0x29, 0x1d, 0x47}}
+	if err := body.Disassemble(); err != nil {
+		t.Fatalf("Disassemble: %v", err)
+	}
+	a, err := Analyze(&bytecode.CpoolInfo{}, body)
+	if err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if want := []int{0, 1, 0, 1, 2, 1, 0, 0}; !reflect.DeepEqual(a.Stack, want) {
+		t.Errorf("expected %v, got %v", want, a.Stack)
+	}
+	if a.MaxStack != 2 || a.MaxScope != 1 || a.MaxRegister != 0 {
+		t.Errorf("expected 2, 1 and 0, got %v, %v and %v", a.MaxStack, a.MaxScope, a.MaxRegister)
+	}
+}